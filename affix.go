@@ -0,0 +1,52 @@
+package logfmtr
+
+import "bytes"
+
+// lineAffixes holds the text written at the start and end of each line of an entry.
+type lineAffixes struct {
+	prefix   string
+	prefixFn func(Entry) string
+	suffix   string
+	suffixFn func(Entry) string
+}
+
+// newLineAffixes returns the line affixes set by the options, or nil if there are none.
+func newLineAffixes(o Options) *lineAffixes {
+	if o.LinePrefix == "" && o.LinePrefixFunc == nil && o.LineSuffix == "" && o.LineSuffixFunc == nil {
+		return nil
+	}
+	return &lineAffixes{
+		prefix:   o.LinePrefix,
+		prefixFn: o.LinePrefixFunc,
+		suffix:   o.LineSuffix,
+		suffixFn: o.LineSuffixFunc,
+	}
+}
+
+// appendLines appends each line of the encoded entry src to b, adding the prefix at the start of the line
+// and the suffix before its line terminator. The prefix and suffix functions are called once per entry.
+func (a *lineAffixes) appendLines(b []byte, src []byte, e Entry) []byte {
+	prefix, suffix := a.prefix, a.suffix
+	if a.prefixFn != nil {
+		prefix += a.prefixFn(e)
+	}
+	if a.suffixFn != nil {
+		suffix += a.suffixFn(e)
+	}
+	for len(src) > 0 {
+		line := src
+		eol := false
+		if i := bytes.IndexByte(src, '\n'); i >= 0 {
+			line, src, eol = src[:i], src[i+1:], true
+		} else {
+			src = nil
+		}
+		b = append(b, prefix...)
+		b = append(b, line...)
+		b = append(b, suffix...)
+		if eol {
+			b = append(b, '\n')
+		}
+	}
+	return b
+}
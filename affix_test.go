@@ -0,0 +1,93 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestLineAffixes(t *testing.T) {
+	priority := func(e logfmtr.Entry) string {
+		if e.IsError {
+			return "<11>"
+		}
+		return "<14>"
+	}
+
+	testCases := []struct {
+		name string
+		fn   func(*logfmtr.Options)
+		want string
+	}{
+		{
+			name: "static",
+			fn: func(o *logfmtr.Options) {
+				o.LinePrefix = "[route=audit] "
+				o.LineSuffix = " ;"
+			},
+			want: "[route=audit] level=0 msg=hello ;\n[route=audit] level=0 msg=goodbye error=<nil> ;\n",
+		},
+		{
+			name: "callback",
+			fn: func(o *logfmtr.Options) {
+				o.LinePrefixFunc = priority
+				o.LineSuffixFunc = func(e logfmtr.Entry) string { return " len=" + strconv.Itoa(len(e.Message)) }
+			},
+			want: "<14>level=0 msg=hello len=5\n<11>level=0 msg=goodbye error=<nil> len=7\n",
+		},
+		{
+			name: "static and callback",
+			fn: func(o *logfmtr.Options) {
+				o.LinePrefix = "app "
+				o.LinePrefixFunc = priority
+			},
+			want: "app <14>level=0 msg=hello\napp <11>level=0 msg=goodbye error=<nil>\n",
+		},
+		{
+			name: "multiline template",
+			fn: func(o *logfmtr.Options) {
+				o.LinePrefix = "> "
+				o.Template = "{{.Msg}}\nsecond line"
+			},
+			want: "> hello\n> second line\n> goodbye\n> second line\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			tc.fn(&opts)
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("hello")
+			logger.Error(nil, "goodbye")
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLineAffixesOutputs(t *testing.T) {
+	var main, extra bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&main),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithOutput(&extra, &logfmtr.JSONEncoder{}),
+		logfmtr.WithLinePrefix("token "),
+	)
+	logger.Info("hello")
+
+	if got, want := main.String(), "token level=0 msg=hello\n"; got != want {
+		t.Errorf("got main %q, wanted %q", got, want)
+	}
+	if got, want := extra.String(), "token {\"level\":0,\"msg\":\"hello\"}\n"; got != want {
+		t.Errorf("got output %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,189 @@
+package logfmtr
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWriterClosed is returned when writing to a writer that has been closed.
+var ErrWriterClosed = errors.New("writer closed")
+
+// DropPolicy determines what an AsyncWriter does with an entry when its queue is full.
+type DropPolicy int32
+
+const (
+	// DropNewest discards the entry being written when the queue is full.
+	DropNewest DropPolicy = iota
+
+	// DropOldest discards the oldest queued entry to make room for the entry being written.
+	DropOldest
+
+	// Block waits until there is room in the queue. No entries are dropped.
+	Block
+)
+
+// asyncItem is an entry to be written or, if flushed is not nil, a marker that is signalled
+// when all preceding entries have been written.
+type asyncItem struct {
+	entry   []byte
+	flushed chan struct{}
+}
+
+// AsyncWriter is a writer that queues writes and performs them on a background goroutine so that
+// a slow underlying writer does not block logging. Each write is expected to be a complete log entry.
+type AsyncWriter struct {
+	w       io.Writer
+	queue   chan asyncItem
+	policy  int32  // accessed atomically
+	dropped uint64 // accessed atomically
+	done    chan struct{}
+
+	mu     sync.RWMutex // guards closed and prevents sends on a closed queue
+	closed bool
+
+	markersMu sync.Mutex
+	markers   []chan struct{} // flush markers removed from the queue to make room, signalled by run
+
+	unregister func() // removes the writer from those flushed by Flush and Close
+}
+
+// NewAsyncWriter returns an AsyncWriter that writes to w from a background goroutine, queueing up to
+// queueLen entries. A queueLen less than 1 is treated as 1. By default entries are dropped when the
+// queue is full; use SetDropPolicy to change this. Close must be called to flush the queue and stop
+// the background goroutine.
+func NewAsyncWriter(w io.Writer, queueLen int) *AsyncWriter {
+	if queueLen < 1 {
+		queueLen = 1
+	}
+	a := &AsyncWriter{
+		w:     w,
+		queue: make(chan asyncItem, queueLen),
+		done:  make(chan struct{}),
+	}
+	a.unregister = RegisterWriter(a)
+	go a.run()
+	return a
+}
+
+// SetDropPolicy sets the policy used when the queue is full.
+func (a *AsyncWriter) SetDropPolicy(p DropPolicy) {
+	atomic.StoreInt32(&a.policy, int32(p))
+}
+
+// Dropped returns the number of entries that have been dropped because the queue was full.
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// Write queues a copy of p to be written to the underlying writer. It does not report errors from the
+// underlying writer and reports success even if the entry is dropped.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return 0, ErrWriterClosed
+	}
+
+	entry := make([]byte, len(p))
+	copy(entry, p)
+	item := asyncItem{entry: entry}
+
+	switch DropPolicy(atomic.LoadInt32(&a.policy)) {
+	case Block:
+		a.queue <- item
+	case DropOldest:
+		for {
+			select {
+			case a.queue <- item:
+				return len(p), nil
+			default:
+			}
+			select {
+			case old := <-a.queue:
+				if old.flushed != nil {
+					// never drop a flush marker, but let the background goroutine signal it once
+					// it has finished writing the entry it may be writing now
+					a.markersMu.Lock()
+					a.markers = append(a.markers, old.flushed)
+					a.markersMu.Unlock()
+				} else {
+					atomic.AddUint64(&a.dropped, 1)
+				}
+			default:
+			}
+		}
+	default:
+		select {
+		case a.queue <- item:
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush waits until all entries queued before the call have been written. If the underlying
+// writer has a Flush method it is then called.
+func (a *AsyncWriter) Flush() error {
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	a.queue <- asyncItem{flushed: flushed}
+	a.mu.RUnlock()
+
+	<-flushed
+	if f, ok := a.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close stops accepting writes and waits for all queued entries to be written. If the underlying
+// writer implements io.Closer it is then closed, unless it is os.Stdout or os.Stderr.
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+	a.unregister()
+
+	<-a.done
+	if c, ok := a.w.(io.Closer); ok && !isStdStream(a.w) {
+		return c.Close()
+	}
+	return nil
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+	for item := range a.queue {
+		a.signalMarkers()
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+		_, _ = a.w.Write(item.entry)
+	}
+	a.signalMarkers()
+}
+
+// signalMarkers signals flush markers that were removed from the queue by Write. It is called by run
+// between entries, so every entry queued before such a marker has been written or dropped.
+func (a *AsyncWriter) signalMarkers() {
+	a.markersMu.Lock()
+	markers := a.markers
+	a.markers = nil
+	a.markersMu.Unlock()
+	for _, m := range markers {
+		close(m)
+	}
+}
@@ -0,0 +1,142 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+// blockingWriter blocks all writes until released.
+type blockingWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriterDropsWhenFull(t *testing.T) {
+	bw := &blockingWriter{release: make(chan struct{})}
+	w := logfmtr.NewAsyncWriter(bw, 2)
+
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("line\n")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	close(bw.release)
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	written := strings.Count(bw.buf.String(), "line\n")
+	if written+int(w.Dropped()) != 10 {
+		t.Errorf("got %d written and %d dropped, wanted a total of 10", written, w.Dropped())
+	}
+	if w.Dropped() == 0 {
+		t.Errorf("no entries were dropped")
+	}
+
+	if _, err := w.Write([]byte("line\n")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	var buf bytes.Buffer
+	w := logfmtr.NewAsyncWriter(&buf, 1)
+	w.SetDropPolicy(logfmtr.Block)
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+	for i := 0; i < 100; i++ {
+		logger.Info("hello")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	if got := strings.Count(buf.String(), "level=0 msg=hello\n"); got != 100 {
+		t.Errorf("got %d entries, wanted 100", got)
+	}
+	if w.Dropped() != 0 {
+		t.Errorf("got %d dropped entries, wanted 0", w.Dropped())
+	}
+}
+
+func TestAsyncWriterQueueLen(t *testing.T) {
+	for _, queueLen := range []int{0, -1} {
+		var buf bytes.Buffer
+		w := logfmtr.NewAsyncWriter(&buf, queueLen)
+		w.SetDropPolicy(logfmtr.DropOldest)
+
+		for i := 0; i < 10; i++ {
+			if _, err := w.Write([]byte("line\n")); err != nil {
+				t.Fatalf("queue length %d: unexpected error writing: %v", queueLen, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("queue length %d: unexpected error closing: %v", queueLen, err)
+		}
+
+		if got := uint64(strings.Count(buf.String(), "line\n")) + w.Dropped(); got != 10 {
+			t.Errorf("queue length %d: got %d entries written or dropped, wanted 10", queueLen, got)
+		}
+	}
+}
+
+// startedWriter reports when a write has started and blocks it until released.
+type startedWriter struct {
+	blockingWriter
+	started chan struct{}
+}
+
+func (w *startedWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	return w.blockingWriter.Write(p)
+}
+
+func TestAsyncWriterDropOldestFlush(t *testing.T) {
+	sw := &startedWriter{blockingWriter: blockingWriter{release: make(chan struct{})}, started: make(chan struct{}, 10)}
+	w := logfmtr.NewAsyncWriter(sw, 1)
+	w.SetDropPolicy(logfmtr.DropOldest)
+	defer w.Close()
+
+	w.Write([]byte("one\n"))
+	<-sw.started
+
+	flushed := make(chan struct{})
+	go func() {
+		w.Flush()
+		close(flushed)
+	}()
+	// wait for the flush marker to fill the queue, then push it out with another entry
+	time.Sleep(20 * time.Millisecond)
+	w.Write([]byte("two\n"))
+
+	select {
+	case <-flushed:
+		close(sw.release)
+		t.Fatalf("flush returned while the first entry was still being written")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(sw.release)
+	<-flushed
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if got := sw.buf.String(); !strings.HasPrefix(got, "one\n") {
+		t.Errorf("got %q after flush, wanted the first entry to be written", got)
+	}
+}
@@ -74,6 +74,16 @@ func doWithValues(b *testing.B, log logr.Logger) {
 	}
 }
 
+//go:noinline
+func doInfoWithValues(b *testing.B, log logr.Logger) {
+	log = log.WithValues("k1", "v1", "k2", 2)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		log.Info("this is", "a", "string")
+	}
+}
+
 //go:noinline
 func doWithName(b *testing.B, log logr.Logger) {
 	b.ReportAllocs()
@@ -122,6 +132,10 @@ func BenchmarkLogfmtrWithValues(b *testing.B) {
 	doWithValues(b, newLogger())
 }
 
+func BenchmarkLogfmtrInfoWithValues(b *testing.B) {
+	doInfoWithValues(b, newLogger())
+}
+
 func BenchmarkLogfmtrWithName(b *testing.B) {
 	doWithName(b, newLogger())
 }
@@ -0,0 +1,36 @@
+package logfmtr
+
+import "sync"
+
+// maxPooledBufferSize is the largest buffer capacity that will be returned to the pool. Larger
+// buffers are left for the garbage collector so that an occasional large entry does not pin memory.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &buffer{b: make([]byte, 0, 1024)}
+	},
+}
+
+// buffer is a reusable byte slice that entries are encoded into before being written.
+type buffer struct {
+	b []byte
+}
+
+// Write appends p to the buffer. It always returns len(p) and a nil error.
+func (b *buffer) Write(p []byte) (int, error) {
+	b.b = append(b.b, p...)
+	return len(p), nil
+}
+
+func getBuffer() *buffer {
+	return bufferPool.Get().(*buffer)
+}
+
+func putBuffer(b *buffer) {
+	if cap(b.b) > maxPooledBufferSize {
+		return
+	}
+	b.b = b.b[:0]
+	bufferPool.Put(b)
+}
@@ -0,0 +1,56 @@
+package logfmtr
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+// BytesFormat selects how []byte values of key/value pairs are written.
+type BytesFormat int
+
+const (
+	// BytesDefault writes []byte values as a list of decimal bytes, such as [104 105].
+	BytesDefault BytesFormat = iota
+
+	// BytesHex writes []byte values as lower case hexadecimal, such as 6869. This suits digests and keys.
+	BytesHex
+
+	// BytesBase64 writes []byte values using standard base64 encoding with padding, such as aGk=.
+	BytesBase64
+
+	// BytesLength writes only the length of []byte values, such as len=2, so that payloads are not
+	// written to the log.
+	BytesLength
+)
+
+// String returns the name of the format.
+func (f BytesFormat) String() string {
+	switch f {
+	case BytesDefault:
+		return "default"
+	case BytesHex:
+		return "hex"
+	case BytesBase64:
+		return "base64"
+	case BytesLength:
+		return "length"
+	default:
+		return fmt.Sprintf("BytesFormat(%d)", int(f))
+	}
+}
+
+// format returns b written in the format.
+func (f BytesFormat) format(b []byte) string {
+	switch f {
+	case BytesHex:
+		return hex.EncodeToString(b)
+	case BytesBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case BytesLength:
+		return "len=" + strconv.Itoa(len(b))
+	default:
+		return fmt.Sprint(b)
+	}
+}
@@ -0,0 +1,53 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestBytesFormat(t *testing.T) {
+	testCases := []struct {
+		format logfmtr.BytesFormat
+		want   string
+	}{
+		{
+			format: logfmtr.BytesDefault,
+			want:   `level=0 msg=hello key="[1 171 255]" digest="[104 105]" name=hi` + "\n",
+		},
+		{
+			format: logfmtr.BytesHex,
+			want:   `level=0 msg=hello key=01abff digest=6869 name=hi` + "\n",
+		},
+		{
+			format: logfmtr.BytesBase64,
+			want:   `level=0 msg=hello key=Aav/ digest="aGk=" name=hi` + "\n",
+		},
+		{
+			format: logfmtr.BytesLength,
+			want:   `level=0 msg=hello key="len=3" digest="len=2" name=hi` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.BytesFormat = tc.format
+			logger := logfmtr.NewWithOptions(opts)
+
+			key := []byte{0x01, 0xab, 0xff}
+			logger.WithValues("key", key).Info("hello", "digest", []byte("hi"), "name", "hi")
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+			if key[0] != 0x01 {
+				t.Errorf("value was modified")
+			}
+		})
+	}
+}
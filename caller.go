@@ -0,0 +1,110 @@
+package logfmtr
+
+import (
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CallerFormat selects how the file of the caller is written when AddCaller is set.
+type CallerFormat int
+
+const (
+	// CallerShort writes the base name of the file and the line number, for example client.go:42.
+	CallerShort CallerFormat = iota
+
+	// CallerPackage writes the name of the directory containing the file along with the base name of the
+	// file and the line number, for example http/client.go:42.
+	CallerPackage
+
+	// CallerFull writes the full path of the file and the line number, for example
+	// /home/user/src/app/http/client.go:42.
+	CallerFull
+)
+
+// maxHelperDepth is the maximum number of frames searched for a caller that is not a helper.
+const maxHelperDepth = 32
+
+var (
+	helpers   sync.Map // names of functions marked by MarkHelper
+	anyHelper int32    // an atomicly accessed variable that is set to 1 if any function has been marked as a helper
+)
+
+// MarkHelper marks the calling function as a logging helper. When AddCaller is set, helper functions
+// are skipped when determining the caller so that the caller of the helper is written instead. Like
+// testing.T.Helper, it may be called from multiple goroutines and marking the same function more than
+// once has no additional effect. Unlike CallerSkip, a helper is skipped wherever it appears in the call
+// stack, so helpers may call one another or be called directly. A single level of wrapping can also be
+// handled with logr.Logger.WithCallStackHelper or logr.Logger.WithCallDepth.
+func MarkHelper() {
+	var pcs [1]uintptr
+	// skip runtime.Callers and this function
+	if runtime.Callers(2, pcs[:]) == 0 {
+		return
+	}
+	f, _ := runtime.CallersFrames(pcs[:]).Next()
+	if f.Function == "" {
+		return
+	}
+	helpers.Store(f.Function, true)
+	atomic.StoreInt32(&anyHelper, 1)
+}
+
+// caller returns the location of the caller of the logger, skipping any helper functions. skip is the
+// number of frames between the caller of this function and the logr package.
+func (c *core) caller(skip int) string {
+	var pcs [maxHelperDepth]uintptr
+	size := 2
+	skipHelpers := atomic.LoadInt32(&anyHelper) != 0
+	if skipHelpers {
+		size = len(pcs)
+	}
+	// skip runtime.Callers and this function in addition to the requested frames
+	n := runtime.Callers(c.runtimeInfo.CallDepth+skip+c.callerSkip+c.callDepth+2, pcs[:size])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if f.File != "" && f.File != "<autogenerated>" {
+			if !skipHelpers {
+				return c.formatCaller(f)
+			}
+			if _, ok := helpers.Load(f.Function); !ok {
+				return c.formatCaller(f)
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	return "unknown"
+}
+
+// formatCaller formats the location of a frame according to the core's caller options.
+func (c *core) formatCaller(f runtime.Frame) string {
+	var file string
+	switch c.callerFormat {
+	case CallerFull:
+		file = f.File
+	case CallerPackage:
+		file = path.Join(path.Base(path.Dir(f.File)), path.Base(f.File))
+	default:
+		file = path.Base(f.File)
+	}
+	loc := file + ":" + strconv.Itoa(f.Line)
+	if c.callerFunc && f.Function != "" {
+		return shortFuncName(f.Function) + " " + loc
+	}
+	return loc
+}
+
+// shortFuncName removes the import path from a fully qualified function name, leaving the package name.
+// For example github.com/iand/logfmtr.(*core).write becomes logfmtr.(*core).write.
+func shortFuncName(fn string) string {
+	if i := strings.LastIndex(fn, "/"); i >= 0 {
+		return fn[i+1:]
+	}
+	return fn
+}
@@ -0,0 +1,18 @@
+package logfmtr
+
+import "errors"
+
+// causeSuffix is appended to the key of an error to form the key of the error it wraps.
+const causeSuffix = ".cause"
+
+// maxCauses limits the number of wrapped errors that are expanded, guarding against cyclic chains.
+const maxCauses = 16
+
+// causes returns the chain of errors wrapped by err, outermost first.
+func causes(err error) []error {
+	var chain []error
+	for err = errors.Unwrap(err); err != nil && len(chain) < maxCauses; err = errors.Unwrap(err) {
+		chain = append(chain, err)
+	}
+	return chain
+}
@@ -0,0 +1,261 @@
+// Command logfmtr reads log lines written in logfmt style or as JSON objects and writes them in the
+// human friendly format used by logfmtr loggers with the Humanize option. Lines are read from the files
+// named on the command line, or from standard input if none are named. Lines that cannot be decoded
+// are written unchanged, as are lines longer than 1MiB. When writing to a terminal, lines wider than the
+// terminal are truncated unless -no-truncate is given. With -color=auto, output is colored when written to
+// a terminal unless the NO_COLOR environment variable is set or TERM is dumb.
+//
+// Usage:
+//
+//	logfmtr [-color=auto|always|never] [-time-format layout] [-local] [-width n] [-no-truncate] [file ...]
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtparse"
+)
+
+// maxLineSize is the maximum length of a line that will be reformatted.
+const maxLineSize = 1 << 20
+
+func main() {
+	color := flag.String("color", "auto", "colorize output: auto, always or never")
+	timeFormat := flag.String("time-format", logfmtr.DefaultHumanTimestampFormat, "layout used to show timestamps")
+	local := flag.Bool("local", false, "show timestamps in the local time zone instead of UTC")
+	width := flag.Int("width", 0, "truncate lines wider than this many columns; when 0 the terminal width is used")
+	noTruncate := flag.Bool("no-truncate", false, "do not truncate long lines")
+	flag.Parse()
+
+	enc := &logfmtr.HumanEncoder{TimestampFormat: *timeFormat}
+	switch *color {
+	case "always":
+		enc.Colorize = true
+	case "never":
+	case "auto":
+		enc.Colorize = logfmtr.ColorTerminal(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "logfmtr: invalid value %q for -color\n", *color)
+		os.Exit(2)
+	}
+	if *local {
+		enc.Location = time.Local
+	}
+	if !*noTruncate {
+		enc.MaxWidth = *width
+		if *width == 0 {
+			enc.MaxWidth = logfmtr.TerminalWidth(os.Stdout)
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	if flag.NArg() == 0 {
+		if err := humanize(out, os.Stdin, enc); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		err = humanize(out, f, enc)
+		f.Close()
+		if err != nil {
+			fatal(err)
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "logfmtr: %v\n", err)
+	os.Exit(1)
+}
+
+// humanize reads lines from r and writes them to w using enc. Lines that cannot be decoded, or that are
+// longer than maxLineSize, are written unchanged.
+func humanize(w io.Writer, r io.Reader, enc *logfmtr.HumanEncoder) error {
+	br := bufio.NewReaderSize(r, maxLineSize)
+	for {
+		b, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			if err := copyLine(w, br, b); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(b) == 0 {
+			return nil
+		}
+		if err := humanizeLine(w, string(b), enc); err != nil {
+			return err
+		}
+	}
+}
+
+// copyLine writes the rest of a line that is too long to reformat, starting with the part already read
+// into b, to w unchanged.
+func copyLine(w io.Writer, br *bufio.Reader, b []byte) error {
+	for {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		if len(b) > 0 && b[len(b)-1] == '\n' {
+			return nil
+		}
+		var err error
+		b, err = br.ReadSlice('\n')
+		if err == io.EOF {
+			if len(b) > 0 {
+				if _, err := w.Write(b); err != nil {
+					return err
+				}
+			}
+			_, err := io.WriteString(w, "\n")
+			return err
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+}
+
+// humanizeLine writes a single line using enc, or unchanged if it cannot be decoded.
+func humanizeLine(w io.Writer, line string, enc *logfmtr.HumanEncoder) error {
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	pairs, err := decodeLine(line)
+	if err != nil || len(pairs) == 0 {
+		_, err := io.WriteString(w, line+"\n")
+		return err
+	}
+	e, levelName := entryFromPairs(pairs)
+	if levelName != "" {
+		le := *enc
+		le.LevelNames = map[int]string{e.Level: levelName}
+		enc = &le
+	}
+	return enc.EncodeEntry(w, e)
+}
+
+// decodeLine decodes a line written in logfmt style or as a JSON object.
+func decodeLine(line string) ([]logfmtparse.Pair, error) {
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return decodeJSON(line)
+	}
+	return logfmtparse.DecodePairs(line)
+}
+
+// decodeJSON decodes the members of a JSON object, preserving their order. String values are
+// unquoted and other values are kept in their JSON form.
+func decodeJSON(line string) ([]logfmtparse.Pair, error) {
+	d := json.NewDecoder(strings.NewReader(line))
+	d.UseNumber()
+	if tok, err := d.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+	var pairs []logfmtparse.Pair
+	for d.More() {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, errors.New("invalid JSON object key")
+		}
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err != nil {
+			return nil, err
+		}
+		value := string(raw)
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			value = s
+		}
+		pairs = append(pairs, logfmtparse.Pair{Key: key, Value: value})
+	}
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// entryFromPairs converts decoded pairs to an entry using the default field names. Pairs that are not
+// built-in fields become the entry's values. When the level is a name rather than a number the name is
+// returned so that it can be shown in place of the default label.
+func entryFromPairs(pairs []logfmtparse.Pair) (logfmtr.Entry, string) {
+	var e logfmtr.Entry
+	var levelName string
+	for _, p := range pairs {
+		switch p.Key {
+		case "level":
+			if n, err := strconv.Atoi(p.Value); err == nil {
+				e.Level = n
+			} else if p.Value == "error" {
+				e.IsError = true
+			} else {
+				levelName = p.Value
+			}
+		case "logger":
+			e.Name = p.Value
+		case "ts":
+			if t, ok := parseTime(p.Value); ok {
+				e.Time = t
+			} else {
+				e.Values = append(e.Values, p.Key, p.Value)
+			}
+		case "msg":
+			e.Message = p.Value
+		case "caller":
+			e.Caller = p.Value
+		case "error":
+			e.IsError = true
+			e.Error = errors.New(p.Value)
+		case "stacktrace":
+			e.Stacktrace = p.Value
+		default:
+			e.Values = append(e.Values, p.Key, p.Value)
+		}
+	}
+	return e, levelName
+}
+
+// parseTime parses a timestamp written in RFC 3339 format or as a number of seconds, milliseconds or
+// nanoseconds since the Unix epoch.
+func parseTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) || f < 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case f < 1e11:
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	case f < 1e14:
+		return time.Unix(0, int64(f*1e6)), true
+	default:
+		return time.Unix(0, int64(f)), true
+	}
+}
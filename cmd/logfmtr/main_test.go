@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestHumanize(t *testing.T) {
+	input := "level=1 logger=europa ts=2024-01-02T03:04:05.123456789Z msg=\"hello world\" caller=main.go:12 val=1\n" +
+		"not logfmt at \"all\n" +
+		"{\"level\":\"debug\",\"ts\":1704164645.5,\"msg\":\"from json\",\"user\":\"you\"}\n" +
+		"level=0 ts=1704164645123 msg=goodbye error=\"uh oh\"\n"
+
+	var buf bytes.Buffer
+	if err := humanize(&buf, strings.NewReader(input), &logfmtr.HumanEncoder{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "1 info  | 03:04:05.123456 | hello world                    logger=europa caller=main.go:12 val=1\n" +
+		"not logfmt at \"all\n" +
+		"0 debug | 03:04:05.500000 | from json                      user=you\n" +
+		"0 error | 03:04:05.123000 | goodbye                        error=\"uh oh\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestHumanizeLongLine(t *testing.T) {
+	long := "level=0 msg=" + strings.Repeat("x", maxLineSize+10)
+	input := long + "\nlevel=0 msg=after\n" + long
+
+	var buf bytes.Buffer
+	if err := humanize(&buf, strings.NewReader(input), &logfmtr.HumanEncoder{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := long + "\n" +
+		"0 info  | 00:00:00.000000 | after                         \n" +
+		long + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %d bytes, wanted %d with long lines unchanged", len(got), len(want))
+	}
+}
@@ -0,0 +1,134 @@
+package logfmtr
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Coalescer collapses runs of consecutive identical entries. The first entry of a run is written
+// immediately and later identical entries are held back. When a different entry is written, or the
+// timeout passes, the last held entry is written with an additional repeated field holding the number
+// of entries that were held back. Entries are identical if they have the same level, logger name,
+// message, error and key/value pairs. Lazy values are not computed to compare entries, so entries whose
+// pairs differ only in Lazy values are identical.
+type Coalescer struct {
+	timeout    time.Duration
+	unregister func() // removes the coalescer from those flushed by Flush and Close
+
+	mu       sync.Mutex
+	closed   bool
+	lastKey  string
+	last     Entry // last entry held back
+	lastCore *core // core that wrote the last entry held back
+	repeated int
+	timer    *time.Timer
+}
+
+// NewCoalescer returns a Coalescer that writes held back entries when a different entry is written or
+// after timeout has passed since the first entry was held back. The coalescer is registered so that
+// held back entries are written by Flush and Close until the coalescer is closed.
+func NewCoalescer(timeout time.Duration) *Coalescer {
+	co := &Coalescer{timeout: timeout}
+	co.unregister = register(&pending, co)
+	return co
+}
+
+// Flush writes any entry that is being held back.
+func (co *Coalescer) Flush() error {
+	co.mu.Lock()
+	c, e, ok := co.take()
+	co.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return c.encode(e)
+}
+
+// Close writes any entry that is being held back and removes the coalescer's registration. Entries
+// written after Close are not coalesced.
+func (co *Coalescer) Close() error {
+	co.mu.Lock()
+	co.closed = true
+	c, e, ok := co.take()
+	co.mu.Unlock()
+	co.unregister()
+	if !ok {
+		return nil
+	}
+	return c.encode(e)
+}
+
+// add records an entry written by c and reports whether it should be written. Any entry held back
+// that is not identical is written before add returns.
+func (co *Coalescer) add(c *core, e Entry) bool {
+	key := coalesceKey(e)
+
+	co.mu.Lock()
+	if co.closed {
+		co.mu.Unlock()
+		return true
+	}
+	if key == co.lastKey {
+		co.last = e
+		co.lastCore = c
+		co.repeated++
+		if co.timer == nil {
+			co.timer = time.AfterFunc(co.timeout, func() {
+				_ = co.Flush()
+			})
+		}
+		co.mu.Unlock()
+		return false
+	}
+
+	hc, he, ok := co.take()
+	co.lastKey = key
+	co.mu.Unlock()
+	if ok {
+		_ = hc.encode(he)
+	}
+	return true
+}
+
+// take removes the entry being held back, annotated with the number of entries it stands for, and
+// returns it with the core that wrote it. It reports false if no entry is held back. co.mu must be
+// held; the entry is written by the caller once co.mu is released so that a slow writer does not
+// block other loggers using the coalescer.
+func (co *Coalescer) take() (*core, Entry, bool) {
+	if co.timer != nil {
+		co.timer.Stop()
+		co.timer = nil
+	}
+	if co.repeated == 0 {
+		return nil, Entry{}, false
+	}
+
+	e := co.last
+	e.Values = append(e.Values[:len(e.Values):len(e.Values)], "repeated", co.repeated)
+	c := co.lastCore
+	co.last = Entry{}
+	co.lastCore = nil
+	co.repeated = 0
+	return c, e, true
+}
+
+// coalesceKey returns a string that is equal for identical entries, ignoring the time and caller. Lazy
+// values are replaced by a placeholder so that they are not computed.
+func coalesceKey(e Entry) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	b := buf.b
+	b = strconv.AppendInt(b, int64(e.Level), 10)
+	b = strconv.AppendBool(b, e.IsError)
+	b = appendQuoted(b, e.Name)
+	b = append(b, ' ')
+	b = appendQuoted(b, e.Message)
+	if e.IsError {
+		b = appendKV(b, "error", e.Error, KeyReplace, nil)
+	}
+	b = appendKVs(b, mapValues(e.Context, placeholdLazy), KeyReplace, nil)
+	b = appendKVs(b, mapValues(e.Values, placeholdLazy), KeyReplace, nil)
+	buf.b = b
+	return string(b)
+}
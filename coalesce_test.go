@@ -0,0 +1,104 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestCoalescer(t *testing.T) {
+	var buf syncBuffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Coalescer = logfmtr.NewCoalescer(time.Hour)
+	defer opts.Coalescer.Close()
+	logger := logfmtr.NewWithOptions(opts)
+
+	for i := 0; i < 4; i++ {
+		logger.Error(nil, "retry failed", "attempt", 1)
+	}
+	logger.Info("giving up")
+	logger.Info("giving up", "attempt", 2)
+
+	want := "level=0 msg=\"retry failed\" error=<nil> attempt=1\n" +
+		"level=0 msg=\"retry failed\" error=<nil> attempt=1 repeated=3\n" +
+		"level=0 msg=\"giving up\"\n" +
+		"level=0 msg=\"giving up\" attempt=2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestCoalescerTimeout(t *testing.T) {
+	var buf syncBuffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Coalescer = logfmtr.NewCoalescer(10 * time.Millisecond)
+	defer opts.Coalescer.Close()
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("tick")
+	logger.Info("tick")
+
+	want := "level=0 msg=tick\n" +
+		"level=0 msg=tick repeated=1\n"
+	deadline := time.Now().Add(time.Second)
+	for buf.String() != want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestCoalescerClose(t *testing.T) {
+	var buf syncBuffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Coalescer = logfmtr.NewCoalescer(time.Hour)
+	logger := logfmtr.NewWithOptions(opts)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("tick")
+	}
+	if err := opts.Coalescer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		logger.Info("tick")
+	}
+	if err := logfmtr.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "level=0 msg=tick\n" +
+		"level=0 msg=tick repeated=2\n" +
+		"level=0 msg=tick\n" +
+		"level=0 msg=tick\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package logfmtr
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorMode selects when human friendly output is colored.
+type ColorMode int
+
+const (
+	// ColorDefault colors output when the Colorize option is set.
+	ColorDefault ColorMode = iota
+
+	// ColorAuto colors output only when it is written to a terminal and neither the NO_COLOR
+	// environment variable is set nor TERM is dumb.
+	ColorAuto
+
+	// ColorAlways colors output regardless of where it is written.
+	ColorAlways
+
+	// ColorNever never colors output.
+	ColorNever
+)
+
+// String returns the name of the color mode.
+func (m ColorMode) String() string {
+	switch m {
+	case ColorDefault:
+		return "default"
+	case ColorAuto:
+		return "auto"
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	default:
+		return fmt.Sprintf("ColorMode(%d)", int(m))
+	}
+}
+
+// parseColorMode returns the color mode with the given name.
+func parseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	case "default", "":
+		return ColorDefault, nil
+	default:
+		return ColorDefault, fmt.Errorf("unknown color mode %q", s)
+	}
+}
+
+// colorize reports whether output written to w should be colored under these options.
+func (o Options) colorize(w io.Writer) bool {
+	switch o.ColorMode {
+	case ColorAuto:
+		return ColorTerminal(w)
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	default:
+		return o.Colorize
+	}
+}
+
+// ColorTerminal reports whether w is a terminal that accepts color, respecting the NO_COLOR convention
+// described at https://no-color.org/ and treating a TERM of dumb as not accepting color. It is the test
+// used by ColorAuto.
+func ColorTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w appears to be a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// TerminalWidth returns the width in columns of the terminal that w writes to. If the width cannot be
+// found from the terminal the COLUMNS environment variable is used. It returns zero if w is not a
+// terminal or its width is unknown.
+func TerminalWidth(w io.Writer) int {
+	if !isTerminal(w) {
+		return 0
+	}
+	if n := fileTerminalWidth(w.(*os.File)); n > 0 {
+		return n
+	}
+	n, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,68 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestColorMode(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mode      logfmtr.ColorMode
+		colorize  bool
+		wantColor bool
+	}{
+		{name: "default", mode: logfmtr.ColorDefault},
+		{name: "default colorize", mode: logfmtr.ColorDefault, colorize: true, wantColor: true},
+		{name: "auto", mode: logfmtr.ColorAuto},
+		{name: "always", mode: logfmtr.ColorAlways, wantColor: true},
+		{name: "never", mode: logfmtr.ColorNever, colorize: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.Humanize = true
+			opts.Colorize = tc.colorize
+			opts.ColorMode = tc.mode
+			logfmtr.NewWithOptions(opts).Info("hello")
+
+			if got := strings.Contains(buf.String(), "\x1b["); got != tc.wantColor {
+				t.Errorf("got color %v, wanted %v: %q", got, tc.wantColor, buf.String())
+			}
+		})
+	}
+}
+
+func TestColorAutoFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+	// the file is registered with the logger's writers and closed by Close
+	defer logfmtr.Close()
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Humanize = true
+	opts.ColorMode = logfmtr.ColorAuto
+	opts.Outputs = []logfmtr.Output{{Writer: f}}
+	logfmtr.NewWithOptions(opts).Info("hello")
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading file: %v", err)
+	}
+	if !strings.Contains(string(b), "hello") || strings.Contains(string(b), "\x1b[") {
+		t.Errorf("got %q written to file, wanted uncolored output", b)
+	}
+}
@@ -0,0 +1,119 @@
+package logfmtr
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// Config is the runtime logging configuration read and written by ConfigHandler.
+type Config struct {
+	// Verbosity is the global log level, as set by SetVerbosity.
+	Verbosity *int `json:"verbosity"`
+
+	// LoggerVerbosity maps logger names to log levels that override the global level, as set by
+	// SetLoggerVerbosity.
+	LoggerVerbosity map[string]int `json:"logger_verbosity"`
+
+	// DisabledLoggers lists the names and patterns of loggers disabled by DisableLogger.
+	DisabledLoggers []string `json:"disabled_loggers"`
+
+	// DisabledLoggerTrees lists the names of loggers disabled along with their descendants by
+	// DisableLoggerTree.
+	DisabledLoggerTrees []string `json:"disabled_logger_trees"`
+}
+
+// CurrentConfig returns the current runtime logging configuration.
+func CurrentConfig() Config {
+	v := Verbosity()
+	cfg := Config{
+		Verbosity:           &v,
+		LoggerVerbosity:     map[string]int{},
+		DisabledLoggers:     []string{},
+		DisabledLoggerTrees: []string{},
+	}
+	for name, lv := range loggerVerbosity.Load().(map[string]int) {
+		cfg.LoggerVerbosity[name] = lv
+	}
+	disabled := disabledLoggers.Load().(*loggerSet)
+	for name := range disabled.names {
+		cfg.DisabledLoggers = append(cfg.DisabledLoggers, name)
+	}
+	cfg.DisabledLoggers = append(cfg.DisabledLoggers, disabled.patterns...)
+	sort.Strings(cfg.DisabledLoggers)
+	cfg.DisabledLoggerTrees = append(cfg.DisabledLoggerTrees, disabled.trees...)
+	sort.Strings(cfg.DisabledLoggerTrees)
+	return cfg
+}
+
+// ApplyConfig changes the runtime logging configuration. Only the fields of cfg that are set are
+// applied: a non-nil LoggerVerbosity replaces all per-logger levels, a non-nil DisabledLoggers replaces
+// all loggers disabled by DisableLogger and a non-nil DisabledLoggerTrees replaces all trees disabled by
+// DisableLoggerTree.
+func ApplyConfig(cfg Config) {
+	if cfg.Verbosity != nil {
+		SetVerbosity(*cfg.Verbosity)
+	}
+	if cfg.LoggerVerbosity != nil {
+		next := make(map[string]int, len(cfg.LoggerVerbosity))
+		for name, lv := range cfg.LoggerVerbosity {
+			next[name] = lv
+		}
+		loggerVerbosityMu.Lock()
+		storeLoggerVerbosity(next)
+		loggerVerbosityMu.Unlock()
+	}
+	if cfg.DisabledLoggers != nil || cfg.DisabledLoggerTrees != nil {
+		disabledLoggersMu.Lock()
+		next := disabledLoggers.Load().(*loggerSet).clone()
+		if cfg.DisabledLoggers != nil {
+			next.names = map[string]bool{}
+			next.patterns = nil
+			for _, name := range cfg.DisabledLoggers {
+				next = next.with(name)
+			}
+		}
+		if cfg.DisabledLoggerTrees != nil {
+			next.trees = append([]string(nil), cfg.DisabledLoggerTrees...)
+		}
+		storeDisabledLoggers(next)
+		disabledLoggersMu.Unlock()
+	}
+}
+
+// ConfigHandler returns an http.Handler that reads and changes the runtime logging configuration. A GET
+// request responds with the current Config as JSON. A PUT request applies a Config sent as JSON in the
+// request body, as described by ApplyConfig, and responds with the resulting configuration. Request
+// bodies larger than 64KiB are rejected. For example:
+//
+//	curl -X PUT -d '{"verbosity":2,"disabled_loggers":["kafka.*"]}' http://localhost:6060/debug/logging
+//
+// The handler does not perform any authentication so it should only be served on an internal address.
+func ConfigHandler() http.Handler {
+	return http.HandlerFunc(serveConfig)
+}
+
+// maxConfigBody is the largest request body accepted by ConfigHandler.
+const maxConfigBody = 64 << 10
+
+func serveConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPut:
+		var cfg Config
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxConfigBody))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			http.Error(w, "invalid logging config: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ApplyConfig(cfg)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(CurrentConfig())
+}
@@ -0,0 +1,63 @@
+package logfmtr_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestConfigHandler(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+	defer logfmtr.ApplyConfig(logfmtr.Config{LoggerVerbosity: map[string]int{}, DisabledLoggers: []string{}, DisabledLoggerTrees: []string{}})
+
+	h := logfmtr.ConfigHandler()
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"verbosity":2,"logger_verbosity":{"europa":4},"disabled_loggers":["io","kafka.*"]}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, wanted %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	if v := logfmtr.Verbosity(); v != 2 {
+		t.Errorf("got verbosity %d, wanted 2", v)
+	}
+	if logfmtr.NewNamed("kafka.consumer").Enabled() {
+		t.Errorf("got kafka.consumer enabled, wanted disabled")
+	}
+	if !logfmtr.NewNamed("europa").V(4).Enabled() {
+		t.Errorf("got europa V(4) disabled, wanted enabled")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	want := `{"verbosity":2,"logger_verbosity":{"europa":4},"disabled_loggers":["io","kafka.*"],"disabled_logger_trees":[]}` + "\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"verbosity":"high"}`))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, wanted %d", rec.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(strings.Repeat(" ", 1<<20)+`{"verbosity":1}`))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for an oversized body, wanted %d", rec.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d, wanted %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
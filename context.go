@@ -0,0 +1,130 @@
+package logfmtr
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-logr/logr"
+)
+
+var (
+	extractorsMu sync.Mutex // synchronises writes to extractors
+	extractors   atomic.Value
+	anyExtractor int32 = 0 // an atomicly accessed variable that is set to 1 if any extractors have been registered
+)
+
+func init() {
+	extractors.Store([]ContextExtractor{})
+}
+
+// RegisterContextExtractor adds an extractor that is used by all loggers, after any extractors set in
+// their options. Extractors should be registered during program initialization.
+func RegisterContextExtractor(ex ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	current := extractors.Load().([]ContextExtractor)
+	extractors.Store(append(current[:len(current):len(current)], ex))
+	atomic.StoreInt32(&anyExtractor, 1)
+}
+
+// ContextValue returns a ContextExtractor that adds a field with the given name holding the value stored
+// in the context under key. No field is added if the context has no value for key. For example:
+//
+//	logfmtr.RegisterContextExtractor(logfmtr.ContextValue(requestIDKey{}, "request_id"))
+func ContextValue(key interface{}, name string) ContextExtractor {
+	return func(ctx context.Context) []interface{} {
+		v := ctx.Value(key)
+		if v == nil {
+			return nil
+		}
+		return []interface{}{name, v}
+	}
+}
+
+// A ContextExtractor returns key/value pairs derived from a context, such as trace and span ids, that
+// should be added to entries logged on behalf of that context.
+type ContextExtractor func(ctx context.Context) []interface{}
+
+// TraceExtractor returns a ContextExtractor that adds trace_id and span_id fields using ids returned
+// by fn. fn should return empty strings when the context has no active span. For example, with
+// OpenTelemetry:
+//
+//	logfmtr.TraceExtractor(func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	})
+func TraceExtractor(fn func(ctx context.Context) (traceID, spanID string)) ContextExtractor {
+	return func(ctx context.Context) []interface{} {
+		traceID, spanID := fn(ctx)
+		if traceID == "" {
+			return nil
+		}
+		return []interface{}{"trace_id", traceID, "span_id", spanID}
+	}
+}
+
+// WithContext returns a logger that adds the key/value pairs extracted from ctx by the logger's
+// ContextExtractors and any extractors added by RegisterContextExtractor to every entry. Loggers that
+// were not created by this package are returned unchanged.
+func WithContext(ctx context.Context, logger logr.Logger) logr.Logger {
+	s, ok := logger.GetSink().(*sink)
+	if !ok {
+		return logger
+	}
+	kvs := s.getCore().contextValues(ctx)
+	if len(kvs) == 0 {
+		return logger
+	}
+	return logger.WithValues(kvs...)
+}
+
+// FromContext returns the logger stored in ctx by logr.NewContext, or a new deferred logger if there is
+// none, with the key/value pairs extracted from ctx added as described by WithContext.
+func FromContext(ctx context.Context) logr.Logger {
+	logger, err := logr.FromContext(ctx)
+	if err != nil {
+		logger = New()
+	}
+	return WithContext(ctx, logger)
+}
+
+// InfoCtx logs a non-error message using the logger with the key/value pairs extracted from ctx added
+// as described by WithContext.
+func InfoCtx(ctx context.Context, logger logr.Logger, msg string, kvs ...interface{}) {
+	logger = logger.WithCallDepth(1)
+	if !logger.Enabled() {
+		return
+	}
+	WithContext(ctx, logger).Info(msg, kvs...)
+}
+
+// ErrorCtx logs an error using the logger with the key/value pairs extracted from ctx added as described
+// by WithContext.
+func ErrorCtx(ctx context.Context, logger logr.Logger, err error, msg string, kvs ...interface{}) {
+	WithContext(ctx, logger.WithCallDepth(1)).Error(err, msg, kvs...)
+}
+
+// hasExtractors reports whether any extractors apply to the core.
+func (c *core) hasExtractors() bool {
+	return len(c.extractors) > 0 || atomic.LoadInt32(&anyExtractor) != 0
+}
+
+// contextValues returns the key/value pairs extracted from ctx by the core's extractors followed by
+// any registered extractors.
+func (c *core) contextValues(ctx context.Context) []interface{} {
+	if ctx == nil || !c.hasExtractors() {
+		return nil
+	}
+	var kvs []interface{}
+	for _, ex := range c.extractors {
+		kvs = append(kvs, ex(ctx)...)
+	}
+	for _, ex := range extractors.Load().([]ContextExtractor) {
+		kvs = append(kvs, ex(ctx)...)
+	}
+	return kvs
+}
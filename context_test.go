@@ -0,0 +1,71 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+)
+
+type spanKey struct{}
+
+type span struct {
+	traceID, spanID string
+}
+
+func spanIDs(ctx context.Context) (string, string) {
+	sp, ok := ctx.Value(spanKey{}).(span)
+	if !ok {
+		return "", ""
+	}
+	return sp.traceID, sp.spanID
+}
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.ContextExtractors = []logfmtr.ContextExtractor{logfmtr.TraceExtractor(spanIDs)}
+	logger := logfmtr.NewWithOptions(opts).WithName("europa")
+
+	ctx := logr.NewContext(context.Background(), logger)
+	logfmtr.FromContext(ctx).Info("no span")
+
+	ctx = context.WithValue(ctx, spanKey{}, span{traceID: "4bf92f3577b34da6a3ce929d0e0e4736", spanID: "00f067aa0ba902b7"})
+	logfmtr.FromContext(ctx).Info("hello", "val", 1)
+
+	want := "level=0 logger=europa msg=\"no span\"\n" +
+		"level=0 logger=europa msg=hello trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 val=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+type requestIDKey struct{}
+
+func TestRegisterContextExtractor(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	logger := logfmtr.NewWithOptions(opts)
+
+	logfmtr.RegisterContextExtractor(logfmtr.ContextValue(requestIDKey{}, "request_id"))
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r-123")
+	_, _, line, _ := runtime.Caller(0)
+	logfmtr.InfoCtx(ctx, logger, "hello", "val", 1)
+	logfmtr.ErrorCtx(context.Background(), logger, nil, "goodbye")
+
+	want := fmt.Sprintf("level=0 msg=hello caller=context_test.go:%d request_id=r-123 val=1\n", line+1) +
+		fmt.Sprintf("level=0 msg=goodbye caller=context_test.go:%d error=<nil>\n", line+2)
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package logfmtr
+
+import "fmt"
+
+// DuplicateKeys selects how key/value pairs that share a key are written, such as those added by
+// nested calls to WithValues with the same key.
+type DuplicateKeys int
+
+const (
+	// DuplicateKeysKeep writes every pair, even if its key has already been written.
+	DuplicateKeysKeep DuplicateKeys = iota
+
+	// DuplicateKeysLastWins writes only the last pair with each key, so that values passed with the
+	// message override those added by WithValues.
+	DuplicateKeysLastWins
+
+	// DuplicateKeysFirstWins writes only the first pair with each key, so that values added by
+	// WithValues cannot be overridden.
+	DuplicateKeysFirstWins
+)
+
+// String returns the name of the policy.
+func (d DuplicateKeys) String() string {
+	switch d {
+	case DuplicateKeysKeep:
+		return "keep"
+	case DuplicateKeysLastWins:
+		return "lastwins"
+	case DuplicateKeysFirstWins:
+		return "firstwins"
+	default:
+		return fmt.Sprintf("DuplicateKeys(%d)", int(d))
+	}
+}
+
+// dedupeKeys returns the entry with key/value pairs whose keys are repeated in its context or values
+// removed according to policy. The key of an odd final item is not considered. The key/value slices are
+// copied before they are changed since they may be shared with other entries.
+func dedupeKeys(e Entry, policy DuplicateKeys) Entry {
+	if countPairs(e) < 2 {
+		return e
+	}
+	// winner records the index of the pair that is written for each key, numbering the pairs of the
+	// context before those of the values
+	winner := make(map[string]int)
+	dup := false
+	n := 0
+	for _, kvs := range [][]interface{}{e.Context, e.Values} {
+		for i := 0; i+1 < len(kvs); i += 2 {
+			key := rawString(kvs[i])
+			if _, ok := winner[key]; ok {
+				dup = true
+				if policy == DuplicateKeysFirstWins {
+					n++
+					continue
+				}
+			}
+			winner[key] = n
+			n++
+		}
+	}
+	if !dup {
+		return e
+	}
+
+	n = 0
+	keep := func(kvs []interface{}) []interface{} {
+		var kept []interface{}
+		for i := 0; i < len(kvs); i += 2 {
+			if i+1 >= len(kvs) {
+				kept = append(kept, kvs[i])
+				break
+			}
+			if winner[rawString(kvs[i])] == n {
+				kept = append(kept, kvs[i], kvs[i+1])
+			}
+			n++
+		}
+		return kept
+	}
+	e.Context = keep(e.Context)
+	e.Values = keep(e.Values)
+	return e
+}
@@ -0,0 +1,45 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	testCases := []struct {
+		policy logfmtr.DuplicateKeys
+		want   string
+	}{
+		{
+			policy: logfmtr.DuplicateKeysKeep,
+			want:   "level=0 msg=hello user=a req=1 user=b user=c n=2 odd=\n",
+		},
+		{
+			policy: logfmtr.DuplicateKeysLastWins,
+			want:   "level=0 msg=hello req=1 user=c n=2 odd=\n",
+		},
+		{
+			policy: logfmtr.DuplicateKeysFirstWins,
+			want:   "level=0 msg=hello user=a req=1 n=2 odd=\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.DuplicateKeys = tc.policy
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.WithValues("user", "a", "req", 1).WithValues("user", "b").Info("hello", "user", "c", "n", 2, "odd")
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package logfmtr
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+var _ Encoder = (*ECSEncoder)(nil)
+
+// ECSVersion is the version of the Elastic Common Schema written in the ecs.version field by ECSEncoder.
+const ECSVersion = "1.6.0"
+
+// ecsTimestampFormat is the format of the @timestamp field.
+const ecsTimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// ECSEncoder writes entries as newline delimited JSON objects using the field names of the Elastic Common
+// Schema so that they can be ingested by Filebeat and Elasticsearch without further processing. The time
+// is written to @timestamp, the level to log.level, the logger name to log.logger, the message to message,
+// the error to error.message and the stacktrace to error.stack_trace. The caller is split into
+// log.origin.file.name, log.origin.file.line and, when the function is included, log.origin.function.
+type ECSEncoder struct {
+	// Location is the time zone used for timestamps. When nil timestamps are written in UTC.
+	Location *time.Location
+
+	// LevelNames maps V levels to the names written to log.level. When nil, V level 0 is written as info,
+	// higher V levels as debug and entries written by Error as error.
+	LevelNames map[int]string
+}
+
+// EncodeEntry writes the entry as a single line JSON object.
+func (enc *ECSEncoder) EncodeEntry(w io.Writer, e Entry) error {
+	return encodeEntry(w, enc, e)
+}
+
+func (enc *ECSEncoder) appendEntry(b []byte, e Entry) []byte {
+	b = append(b, '{')
+	b = appendJSONKey(b, "@timestamp")
+	b = appendJSONString(b, inLocation(e.Time, enc.Location).Format(ecsTimestampFormat))
+	b = append(b, ',')
+	b = appendJSONKey(b, "log.level")
+	b = appendJSONString(b, enc.levelName(e))
+	b = append(b, ',')
+	b = appendJSONKey(b, "message")
+	b = appendJSONString(b, e.Message)
+	b = append(b, ',')
+	b = appendJSONKey(b, "ecs.version")
+	b = appendJSONString(b, ECSVersion)
+	if e.Name != "" {
+		b = append(b, ',')
+		b = appendJSONKey(b, "log.logger")
+		b = appendJSONString(b, e.Name)
+	}
+	if e.Caller != "" {
+		b = appendECSCaller(b, e.Caller)
+	}
+	if e.IsError {
+		b = appendJSONKV(b, "error.message", e.Error)
+	}
+	if e.Stacktrace != "" {
+		b = append(b, ',')
+		b = appendJSONKey(b, "error.stack_trace")
+		b = appendJSONString(b, e.Stacktrace)
+	}
+	b = appendJSONPairs(b, e.Context, e.Values)
+	return append(b, "}\n"...)
+}
+
+// levelName returns the name written to log.level for an entry.
+func (enc *ECSEncoder) levelName(e Entry) string {
+	if name, ok := levelName(enc.LevelNames, e); ok {
+		return name
+	}
+	switch {
+	case e.IsError:
+		return "error"
+	case e.Level > 0:
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
+// appendECSCaller appends the fields describing the origin of an entry. The caller has the form
+// file:line, optionally preceded by the function name and a space.
+func appendECSCaller(b []byte, caller string) []byte {
+	if i := strings.LastIndexByte(caller, ' '); i >= 0 {
+		b = append(b, ',')
+		b = appendJSONKey(b, "log.origin.function")
+		b = appendJSONString(b, caller[:i])
+		caller = caller[i+1:]
+	}
+	file, line := caller, ""
+	if i := strings.LastIndexByte(caller, ':'); i >= 0 {
+		file, line = caller[:i], caller[i+1:]
+	}
+	b = append(b, ',')
+	b = appendJSONKey(b, "log.origin.file.name")
+	b = appendJSONString(b, file)
+	if line != "" {
+		b = append(b, ',')
+		b = appendJSONKey(b, "log.origin.file.line")
+		b = append(b, line...)
+	}
+	return b
+}
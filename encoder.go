@@ -0,0 +1,929 @@
+package logfmtr
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// An Encoder writes a log entry to a writer in a particular format. Each call to EncodeEntry
+// should write a single, complete entry including any line terminator in a single call to Write.
+type Encoder interface {
+	EncodeEntry(w io.Writer, e Entry) error
+}
+
+// A LevelWriter is a writer that is told the level of each entry written to it, for example so that it
+// can route entries by level. When the writer of a logger is a LevelWriter, WriteLevel is called with each
+// encoded entry instead of Write. Level is the V level of the entry and isError reports whether it was
+// written by a call to Error.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level int, isError bool, p []byte) (int, error)
+}
+
+// Entry is a single log entry passed to an Encoder.
+type Entry struct {
+	// Time is the time the entry was logged.
+	Time time.Time
+
+	// Level is the verbosity level of the logger writing the entry.
+	Level int
+
+	// Name is the name of the logger writing the entry.
+	Name string
+
+	// Message is the log message.
+	Message string
+
+	// IsError reports whether the entry was written by a call to Error.
+	IsError bool
+
+	// Error is the error passed to Error. It may be nil even when IsError is true.
+	Error error
+
+	// Severity is SeverityFatal or SeverityPanic for entries written by Fatal or Panic, which also set
+	// IsError, and empty otherwise. It is written in place of the level.
+	Severity string
+
+	// Caller is the file and line number of the origin of the entry. It is empty unless
+	// the AddCaller option is set.
+	Caller string
+
+	// Causes holds the chain of errors wrapped by Error, outermost first. It is empty unless the
+	// ExpandErrors option is set.
+	Causes []error
+
+	// Stacktrace is the formatted call stack of the origin of the entry. It is empty unless
+	// the AddStacktrace option is set and the entry was written by Error.
+	Stacktrace string
+
+	// Context holds the key/value pairs accumulated by calls to WithValues.
+	Context []interface{}
+
+	// Values holds the key/value pairs passed with the message.
+	Values []interface{}
+}
+
+// Lookup returns the value of the last key/value pair with the given key, searching Values before
+// Context. Keys within groups are matched by the group names and key separated by dots. It reports
+// false if the entry has no pair with the key.
+func (e Entry) Lookup(key string) (interface{}, bool) {
+	for _, kvs := range [][]interface{}{e.Values, e.Context} {
+		for i := len(kvs) - 2 + len(kvs)%2; i >= 0; i -= 2 {
+			if k, ok := keyName(kvs[i]); ok && k == key {
+				if i+1 < len(kvs) {
+					return kvs[i+1], true
+				}
+				return "", true
+			}
+		}
+	}
+	return nil, false
+}
+
+var (
+	_ Encoder = (*LogfmtEncoder)(nil)
+	_ Encoder = (*HumanEncoder)(nil)
+)
+
+// FieldNames holds the keys used for the built-in fields of an entry. Empty fields use the default key.
+type FieldNames struct {
+	Level      string // default "level"
+	Logger     string // default "logger"
+	Timestamp  string // default "ts"
+	Message    string // default "msg"
+	Caller     string // default "caller"
+	Error      string // default "error"
+	Stacktrace string // default "stacktrace"
+}
+
+// withDefaults returns a copy of the field names with any empty fields set to their default key.
+func (f FieldNames) withDefaults() FieldNames {
+	if f.Level == "" {
+		f.Level = "level"
+	}
+	if f.Logger == "" {
+		f.Logger = "logger"
+	}
+	if f.Timestamp == "" {
+		f.Timestamp = "ts"
+	}
+	if f.Message == "" {
+		f.Message = "msg"
+	}
+	if f.Caller == "" {
+		f.Caller = "caller"
+	}
+	if f.Error == "" {
+		f.Error = "error"
+	}
+	if f.Stacktrace == "" {
+		f.Stacktrace = "stacktrace"
+	}
+	return f
+}
+
+// entryAppender is implemented by encoders that can append an encoded entry directly to a
+// byte slice, avoiding an intermediate buffer.
+type entryAppender interface {
+	appendEntry(b []byte, e Entry) []byte
+}
+
+// LogfmtEncoder writes entries as a line of space delimited key/value pairs.
+type LogfmtEncoder struct {
+	// TimestampFormat sets the format for log timestamps. Leave empty to disable timestamping.
+	TimestampFormat string
+
+	// TimestampMode selects how timestamps are written. The default writes timestamps using
+	// TimestampFormat. Other modes write timestamps as numbers even if TimestampFormat is empty.
+	TimestampMode TimestampMode
+
+	// Location is the time zone used for timestamps. When nil timestamps are written in UTC.
+	Location *time.Location
+
+	// LevelNames maps V levels to the names written in place of numeric levels. When set, entries
+	// written by Error are given the level name "error". Levels without a name are written as numbers.
+	LevelNames map[int]string
+
+	// FieldNames sets the keys used for built-in fields.
+	FieldNames FieldNames
+
+	// Layout sets the order in which fields are written. Fields that are not in the layout are not
+	// written. When nil the order returned by DefaultLayout is used.
+	Layout []Field
+
+	// KeyMode selects how keys that are not strings or contain characters that are not permitted
+	// are written.
+	KeyMode KeyMode
+
+	// NilError selects how the error field is written for entries written by Error with a nil error.
+	NilError NilErrorMode
+}
+
+// EncodeEntry writes the entry in logfmt style.
+func (enc *LogfmtEncoder) EncodeEntry(w io.Writer, e Entry) error {
+	return encodeEntry(w, enc, e)
+}
+
+func (enc *LogfmtEncoder) appendEntry(b []byte, e Entry) []byte {
+	names := enc.FieldNames.withDefaults()
+	layout := enc.Layout
+	if layout == nil {
+		layout = defaultLayout
+	}
+	// each field is written with a leading space, which is removed from the first
+	start := len(b)
+	for _, f := range layout {
+		switch f {
+		case FieldLevel:
+			b = append(b, ' ')
+			b = appendKey(b, names.Level)
+			if name, ok := levelName(enc.LevelNames, e); ok {
+				b = appendQuoted(b, name)
+			} else {
+				b = strconv.AppendInt(b, int64(e.Level), 10)
+			}
+		case FieldLogger:
+			if e.Name != "" {
+				b = append(b, ' ')
+				b = appendKey(b, names.Logger)
+				b = appendQuoted(b, e.Name)
+			}
+		case FieldTimestamp:
+			if enc.TimestampMode != TimestampFormatted {
+				b = append(b, ' ')
+				b = appendKey(b, names.Timestamp)
+				b = appendTimestamp(b, e.Time, enc.TimestampMode)
+			} else if enc.TimestampFormat != "" {
+				b = append(b, ' ')
+				b = appendKey(b, names.Timestamp)
+				b = appendTime(b, inLocation(e.Time, enc.Location), enc.TimestampFormat)
+			}
+		case FieldMessage:
+			b = append(b, ' ')
+			b = appendKey(b, names.Message)
+			b = appendQuoted(b, e.Message)
+		case FieldCaller:
+			if e.Caller != "" {
+				b = append(b, ' ')
+				b = appendKey(b, names.Caller)
+				b = appendQuoted(b, e.Caller)
+			}
+		case FieldError:
+			if enc.NilError.writeError(e) {
+				b = appendKV(b, names.Error, enc.NilError.value(e.Error), KeyReplace, nil)
+				b = appendCauses(b, names.Error, e.Causes, nil)
+			}
+		case FieldStacktrace:
+			if e.Stacktrace != "" {
+				b = appendKV(b, names.Stacktrace, e.Stacktrace, KeyReplace, nil)
+			}
+		case FieldContext:
+			b = appendKVs(b, e.Context, enc.KeyMode, nil)
+		case FieldValues:
+			b = appendKVs(b, e.Values, enc.KeyMode, nil)
+		}
+	}
+	if len(b) > start && b[start] == ' ' {
+		b = append(b[:start], b[start+1:]...)
+	}
+	return append(b, '\n')
+}
+
+// DefaultHumanTimestampFormat is the timestamp format used by HumanEncoder when none is set.
+const DefaultHumanTimestampFormat = "15:04:05.000000"
+
+// HumanEncoder writes entries in a human friendly format.
+type HumanEncoder struct {
+	// Colorize adds color to the output.
+	Colorize bool
+
+	// Theme sets the colors used when Colorize is set. When nil the theme returned by DefaultTheme is used.
+	Theme *Theme
+
+	// TimestampFormat sets the format for timestamps. When empty DefaultHumanTimestampFormat is used,
+	// which shows the time of day without the date. A format such as "2006-01-02 15:04:05.000 MST"
+	// shows the date and time zone.
+	TimestampFormat string
+
+	// Location is the time zone used for timestamps. When nil timestamps are written in UTC.
+	Location *time.Location
+
+	// LevelNames maps V levels to the names shown in place of "info". Entries written by Error are
+	// always shown as "error".
+	LevelNames map[int]string
+
+	// FieldNames sets the keys used for built-in fields. The level, timestamp and message are not
+	// shown with keys in the human friendly format.
+	FieldNames FieldNames
+
+	// LevelSymbols, when not nil, sets compact markers that are shown in place of level names.
+	LevelSymbols *LevelSymbols
+
+	// TimeMode selects whether the time of day, the time since the previous entry or the time since the
+	// program started is shown.
+	TimeMode HumanTimeMode
+
+	// MessageWidth is the width of the message column. Shorter messages are padded with spaces so that
+	// the key/value pairs of consecutive entries start in the same column. When zero
+	// DefaultHumanMessageWidth is used.
+	MessageWidth int
+
+	// SortKeys sorts the key/value pairs of each entry by key. Built-in fields such as the logger name
+	// and error are written first in their usual order.
+	SortKeys bool
+
+	// MaxWidth is the maximum width of each line in columns. Longer lines are truncated and end with an
+	// ellipsis. When zero lines are not truncated.
+	MaxWidth int
+
+	// MultilineValues writes values that contain newlines, such as stack traces, after the rest of the
+	// entry with each line indented, instead of quoting them.
+	MultilineValues bool
+
+	// AlignKeys pads each key/value pair to the width of the widest pair written so far with the same
+	// key so that pairs with the same keys line up in columns across entries.
+	AlignKeys bool
+
+	// KeyMode selects how keys that are not strings or contain characters that are not permitted
+	// are written.
+	KeyMode KeyMode
+
+	// NilError selects how the error field is written for entries written by Error with a nil error.
+	NilError NilErrorMode
+
+	widths map[string]int // widest pair written with each key when AlignKeys is set, guarded by stateMu
+	last   time.Time      // time of the previous entry when TimeMode shows deltas, guarded by stateMu
+
+	// maxWidth, when not nil, is called for each entry to find the maximum width of lines, such as
+	// the width of a terminal, and takes precedence over MaxWidth when it returns a positive width.
+	maxWidth func() int
+}
+
+// DefaultHumanMessageWidth is the width of the message column used by HumanEncoder when none is set.
+const DefaultHumanMessageWidth = 30
+
+// maxAlignedKeys is the number of keys for which a HumanEncoder records widths when aligning pairs.
+const maxAlignedKeys = 1024
+
+// stateMu guards the state recorded by all HumanEncoders. A single lock allows encoders to be copied.
+var stateMu sync.Mutex
+
+// sincePrevious records t as the time of the latest entry and returns the time since the previous
+// entry, or since the program started for the first entry.
+func (enc *HumanEncoder) sincePrevious(t time.Time) time.Duration {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	last := enc.last
+	if last.IsZero() {
+		last = start
+	}
+	enc.last = t
+	return t.Sub(last)
+}
+
+// widen records a pair of width n written with key and returns the widest width recorded for the key.
+func (enc *HumanEncoder) widen(key string, n int) int {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	w, ok := enc.widths[key]
+	if n <= w {
+		return w
+	}
+	if !ok && len(enc.widths) >= maxAlignedKeys {
+		return n
+	}
+	if enc.widths == nil {
+		enc.widths = make(map[string]int)
+	}
+	enc.widths[key] = n
+	return n
+}
+
+// trial returns a copy of the encoder whose recorded state is separate from the encoder's, or nil if
+// the encoder records no state.
+func (enc *HumanEncoder) trial() Encoder {
+	switch enc.TimeMode {
+	case HumanTimeDelta, HumanTimeAbsoluteDelta:
+	default:
+		if !enc.AlignKeys {
+			return nil
+		}
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	t := *enc
+	t.widths = make(map[string]int, len(enc.widths))
+	for k, w := range enc.widths {
+		t.widths[k] = w
+	}
+	return &t
+}
+
+// EncodeEntry writes the entry in a human friendly format.
+func (enc *HumanEncoder) EncodeEntry(w io.Writer, e Entry) error {
+	return encodeEntry(w, enc, e)
+}
+
+func (enc *HumanEncoder) appendEntry(b []byte, e Entry) []byte {
+	start := len(b)
+	b = enc.appendLines(b, e)
+	width := enc.MaxWidth
+	if enc.maxWidth != nil {
+		if n := enc.maxWidth(); n > 0 {
+			width = n
+		}
+	}
+	if width > 0 {
+		b = truncateLines(b, start, width, enc.Colorize)
+	}
+	return b
+}
+
+// appendLines appends the lines of the entry.
+func (enc *HumanEncoder) appendLines(b []byte, e Entry) []byte {
+	b = strconv.AppendInt(b, int64(e.Level), 10)
+	b = append(b, ' ')
+	label, labelWidth := "info", 5
+	if enc.LevelSymbols != nil {
+		label, labelWidth = enc.LevelSymbols.symbol(e), enc.LevelSymbols.width()
+	} else if name, ok := levelName(enc.LevelNames, e); ok {
+		label = name
+	} else if e.IsError {
+		label = "error"
+	}
+	theme := enc.theme()
+	b = enc.startColor(b, theme.level(e))
+	b = appendPadded(b, label, labelWidth)
+	b = enc.endColor(b, theme.level(e))
+	b = append(b, " | "...)
+	layout := enc.TimestampFormat
+	if layout == "" {
+		layout = DefaultHumanTimestampFormat
+	}
+	b = enc.startColor(b, theme.Timestamp)
+	switch enc.TimeMode {
+	case HumanTimeDelta:
+		b = appendDelta(b, enc.sincePrevious(e.Time))
+	case HumanTimeElapsed:
+		b = appendDelta(b, e.Time.Sub(start))
+	case HumanTimeAbsoluteDelta:
+		b = inLocation(e.Time, enc.Location).AppendFormat(b, layout)
+		b = append(b, ' ')
+		b = appendDelta(b, enc.sincePrevious(e.Time))
+	default:
+		b = inLocation(e.Time, enc.Location).AppendFormat(b, layout)
+	}
+	b = enc.endColor(b, theme.Timestamp)
+	b = append(b, " | "...)
+	b = enc.startColor(b, theme.Message)
+	width := enc.MessageWidth
+	if width == 0 {
+		width = DefaultHumanMessageWidth
+	}
+	b = appendPadded(b, e.Message, width)
+	b = enc.endColor(b, theme.Message)
+	names := enc.FieldNames.withDefaults()
+	if e.Name != "" {
+		b = enc.appendKey(b, names.Logger)
+		b = append(b, e.Name...)
+	}
+	if e.Caller != "" {
+		b = enc.appendKey(b, names.Caller)
+		b = append(b, e.Caller...)
+	}
+	var multi []humanPair // pairs with multi-line values, written after the entry's first line
+	if enc.NilError.writeError(e) {
+		b, multi = enc.appendField(b, multi, names.Error, enc.NilError.value(e.Error))
+		b = appendCauses(b, names.Error, e.Causes, enc.appendKey)
+	}
+	if e.Stacktrace != "" {
+		b, multi = enc.appendField(b, multi, names.Stacktrace, e.Stacktrace)
+	}
+	if enc.SortKeys || enc.AlignKeys || enc.MultilineValues {
+		b, multi = enc.appendPairs(b, multi, e.Context, e.Values)
+	} else {
+		b = appendKVs(b, e.Context, enc.KeyMode, enc.appendKey)
+		b = appendKVs(b, e.Values, enc.KeyMode, enc.appendKey)
+	}
+	b = append(b, '\n')
+	for _, p := range multi {
+		b = enc.appendMultiline(b, p.key, p.val.(string))
+	}
+	return b
+}
+
+// appendField appends a key/value pair, or adds it to multi if its value is to be written on lines
+// of its own.
+func (enc *HumanEncoder) appendField(b []byte, multi []humanPair, key string, v interface{}) ([]byte, []humanPair) {
+	if s, ok := enc.multilineValue(v); ok {
+		return b, append(multi, humanPair{key: key, val: s})
+	}
+	return appendKV(b, key, v, KeyReplace, enc.appendKey), multi
+}
+
+// multilineValue returns the value as a string if MultilineValues is set and the value contains a newline.
+func (enc *HumanEncoder) multilineValue(v interface{}) (string, bool) {
+	if !enc.MultilineValues {
+		return "", false
+	}
+	switch v.(type) {
+	case string, error, fmt.Stringer:
+	default:
+		return "", false
+	}
+	s := strings.TrimRight(rawString(v), "\n")
+	if !strings.Contains(s, "\n") {
+		return "", false
+	}
+	return s, true
+}
+
+// appendMultiline appends the key on a line of its own followed by each line of the value, indented.
+func (enc *HumanEncoder) appendMultiline(b []byte, key string, s string) []byte {
+	b = append(b, ' ')
+	b = enc.appendKey(b, key)
+	b = append(b, '\n')
+	for _, line := range strings.Split(s, "\n") {
+		b = append(b, multilineIndent...)
+		b = append(b, strings.TrimRight(line, "\r")...)
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// ellipsis ends lines that have been truncated.
+const ellipsis = "…"
+
+// truncateLines truncates each line in b[start:] that is wider than width columns, ending it with an
+// ellipsis. Color escape sequences are not counted in the width and, when reset is true, truncated lines
+// end by resetting the color.
+func truncateLines(b []byte, start int, width int, reset bool) []byte {
+	var out []byte
+	lineStart := start
+	for lineStart < len(b) {
+		lineEnd := bytes.IndexByte(b[lineStart:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(b)
+		} else {
+			lineEnd += lineStart
+		}
+		line := b[lineStart:lineEnd]
+		cut := truncateIndex(line, width)
+		if cut >= 0 && out == nil {
+			out = append(make([]byte, 0, len(b)), b[:lineStart]...)
+		}
+		if out != nil {
+			if cut < 0 {
+				out = append(out, line...)
+			} else {
+				out = append(out, line[:cut]...)
+				out = append(out, ellipsis...)
+				if reset {
+					out = append(out, colorReset...)
+				}
+			}
+			if lineEnd < len(b) {
+				out = append(out, '\n')
+			}
+		}
+		lineStart = lineEnd + 1
+	}
+	if out == nil {
+		return b
+	}
+	return append(b[:start], out[start:]...)
+}
+
+// truncateIndex returns the index at which line must be cut to leave room for an ellipsis within width
+// columns, or -1 if the line fits. Escape sequences are not counted.
+func truncateIndex(line []byte, width int) int {
+	cols := 0
+	cut := -1
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' {
+			// skip a control sequence such as a color, which ends with a letter
+			j := i + 1
+			for j < len(line) && !(line[j] >= 'A' && line[j] <= 'Z' || line[j] >= 'a' && line[j] <= 'z') {
+				j++
+			}
+			i = j + 1
+			continue
+		}
+		_, size := utf8.DecodeRune(line[i:])
+		if cols == width-1 {
+			cut = i
+		}
+		cols++
+		if cols > width {
+			return cut
+		}
+		i += size
+	}
+	return -1
+}
+
+// multilineIndent is written before each line of a multi-line value.
+const multilineIndent = "    "
+
+// LevelSymbols are compact markers shown in place of level names in human friendly output.
+type LevelSymbols struct {
+	// Error marks entries written by Error.
+	Error string
+
+	// Info marks entries with V level 0.
+	Info string
+
+	// Verbose marks entries with a V level greater than 0, unless Levels has a marker for their level.
+	Verbose string
+
+	// Levels maps V levels to markers, overriding Info and Verbose.
+	Levels map[int]string
+}
+
+// DefaultLevelSymbols returns symbols that mark errors with ✗, V level 0 with ✓ and more verbose
+// levels with ·.
+func DefaultLevelSymbols() *LevelSymbols {
+	return &LevelSymbols{
+		Error:   "✗",
+		Info:    "✓",
+		Verbose: "·",
+	}
+}
+
+// symbol returns the marker for the entry.
+func (ls *LevelSymbols) symbol(e Entry) string {
+	if e.IsError {
+		return ls.Error
+	}
+	if s, ok := ls.Levels[e.Level]; ok {
+		return s
+	}
+	if e.Level == 0 {
+		return ls.Info
+	}
+	return ls.Verbose
+}
+
+// width returns the width of the widest marker so that markers are padded to the same width.
+func (ls *LevelSymbols) width() int {
+	w := 0
+	for _, s := range []string{ls.Error, ls.Info, ls.Verbose} {
+		if n := utf8.RuneCountInString(s); n > w {
+			w = n
+		}
+	}
+	for _, s := range ls.Levels {
+		if n := utf8.RuneCountInString(s); n > w {
+			w = n
+		}
+	}
+	return w
+}
+
+// humanPair is a key/value pair written by a HumanEncoder.
+type humanPair struct {
+	key string
+	val interface{}
+}
+
+// appendPairs appends the key/value pairs in each of kvss, sorting them by key if SortKeys is set and
+// padding them into columns if AlignKeys is set. Pairs with multi-line values are added to multi
+// instead if MultilineValues is set.
+func (enc *HumanEncoder) appendPairs(b []byte, multi []humanPair, kvss ...[]interface{}) ([]byte, []humanPair) {
+	var pairs []humanPair
+	for _, kvs := range kvss {
+		for i := 0; i < len(kvs); i += 2 {
+			var v interface{} = ""
+			if i+1 < len(kvs) {
+				v = kvs[i+1]
+			}
+			key := enc.KeyMode.key(kvs[i])
+			if s, ok := enc.multilineValue(v); ok {
+				multi = append(multi, humanPair{key: key, val: s})
+				continue
+			}
+			pairs = append(pairs, humanPair{key: key, val: v})
+		}
+	}
+	if enc.SortKeys {
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].key < pairs[j].key })
+	}
+	for i, p := range pairs {
+		b = enc.appendKey(b, p.key)
+		start := len(b)
+		b = appendValue(b, p.val)
+		if !enc.AlignKeys || i == len(pairs)-1 {
+			continue
+		}
+		n := utf8.RuneCountInString(p.key) + 1 + utf8.RuneCount(b[start:])
+		for w := enc.widen(p.key, n); n < w; n++ {
+			b = append(b, ' ')
+		}
+	}
+	return b, multi
+}
+
+// appendKey appends a space, the key and an equals sign, adding color if required.
+func (enc *HumanEncoder) appendKey(b []byte, key string) []byte {
+	b = append(b, ' ')
+	if !enc.Colorize {
+		b = append(b, key...)
+		return append(b, '=')
+	}
+
+	theme := enc.theme()
+	names := enc.FieldNames.withDefaults()
+	var c Color
+	switch key {
+	case names.Error, names.Stacktrace:
+		c = theme.ErrorKey
+	case names.Logger:
+		c = theme.LoggerKey
+	case names.Caller:
+		c = theme.CallerKey
+	default:
+		if strings.HasPrefix(key, names.Error) && strings.HasPrefix(key[len(names.Error):], causeSuffix) {
+			c = theme.ErrorKey
+		} else {
+			c = theme.Key
+		}
+	}
+	b = appendColored(b, c, key)
+	return append(b, '=')
+}
+
+// theme returns the theme used to color the output.
+func (enc *HumanEncoder) theme() *Theme {
+	if enc.Theme == nil {
+		return defaultTheme
+	}
+	return enc.Theme
+}
+
+// startColor appends c if the output is colored.
+func (enc *HumanEncoder) startColor(b []byte, c Color) []byte {
+	if !enc.Colorize || c == "" {
+		return b
+	}
+	return append(b, c...)
+}
+
+// endColor restores the default color after text colored with c if the output is colored.
+func (enc *HumanEncoder) endColor(b []byte, c Color) []byte {
+	if !enc.Colorize || c == "" {
+		return b
+	}
+	return append(b, colorReset...)
+}
+
+// levelName returns the name of the entry's level from names. Entries written by Error are named
+// "error" when names is not nil.
+func levelName(names map[int]string, e Entry) (string, bool) {
+	if e.Severity != "" {
+		return e.Severity, true
+	}
+	if names == nil {
+		return "", false
+	}
+	if e.IsError {
+		return "error", true
+	}
+	name, ok := names[e.Level]
+	return name, ok
+}
+
+// encodeEntry appends the entry to a pooled buffer and writes it to w in a single write.
+func encodeEntry(w io.Writer, enc entryAppender, e Entry) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.b = enc.appendEntry(buf.b, e)
+	_, err := w.Write(buf.b)
+	return err
+}
+
+// appendKVs appends each key/value pair in kvs preceded by a space. A missing final value is written as empty.
+// If keyfn is not nil it is used to append the leading space, the key and the equals sign.
+func appendKVs(b []byte, kvs []interface{}, mode KeyMode, keyfn func([]byte, string) []byte) []byte {
+	for i := 0; i < len(kvs); i += 2 {
+		var v interface{}
+		if i+1 < len(kvs) {
+			v = kvs[i+1]
+		} else {
+			v = ""
+		}
+		b = appendKV(b, kvs[i], v, mode, keyfn)
+	}
+	return b
+}
+
+func appendKV(b []byte, k, v interface{}, mode KeyMode, keyfn func([]byte, string) []byte) []byte {
+	key := mode.key(k)
+	if keyfn != nil {
+		b = keyfn(b, key)
+	} else {
+		b = append(b, ' ')
+		b = appendKey(b, key)
+	}
+	return appendValue(b, v)
+}
+
+// appendCauses appends each error in causes with a key formed by adding ".cause" to the previous key.
+func appendCauses(b []byte, key string, causes []error, keyfn func([]byte, string) []byte) []byte {
+	for _, err := range causes {
+		key += causeSuffix
+		b = appendKV(b, key, err, KeyReplace, keyfn)
+	}
+	return b
+}
+
+// appendKey appends a key followed by an equals sign.
+func appendKey(b []byte, key string) []byte {
+	b = append(b, key...)
+	return append(b, '=')
+}
+
+// appendValue appends a value in a form suitable for use as a logfmt value.
+func appendValue(b []byte, v interface{}) []byte {
+	switch vv := v.(type) {
+	case string:
+		return appendQuoted(b, vv)
+	case bool:
+		return strconv.AppendBool(b, vv)
+	case int:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int8:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int16:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int32:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int64:
+		return strconv.AppendInt(b, vv, 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint8:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint16:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint64:
+		return strconv.AppendUint(b, vv, 10)
+	case float32:
+		return strconv.AppendFloat(b, float64(vv), 'g', -1, 32)
+	case float64:
+		return strconv.AppendFloat(b, vv, 'g', -1, 64)
+	}
+	return appendQuoted(b, rawString(v))
+}
+
+// appendQuoted appends s, quoting and escaping it if it contains any characters that are not permitted
+// in an unquoted logfmt value.
+func appendQuoted(b []byte, s string) []byte {
+	for _, r := range s {
+		if !validRune(r) {
+			return strconv.AppendQuote(b, s)
+		}
+	}
+	return append(b, s...)
+}
+
+// inLocation returns t in the given location, or in UTC if loc is nil.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
+
+// appendTime appends t formatted using layout, quoting the result if necessary.
+func appendTime(b []byte, t time.Time, layout string) []byte {
+	start := len(b)
+	b = t.AppendFormat(b, layout)
+	for _, r := range string(b[start:]) {
+		if !validRune(r) {
+			s := string(b[start:])
+			return strconv.AppendQuote(b[:start], s)
+		}
+	}
+	return b
+}
+
+// appendPadded appends s followed by enough spaces to fill width runes.
+func appendPadded(b []byte, s string, width int) []byte {
+	b = append(b, s...)
+	for n := utf8.RuneCountInString(s); n < width; n++ {
+		b = append(b, ' ')
+	}
+	return b
+}
+
+// rawString converts a value to an unquoted string. Values without a String or Error method are
+// converted using their MarshalText or MarshalJSON method if they have one, so that domain types are
+// written in the same way as elsewhere. JSON strings are unquoted. Nil pointers are written as <nil>
+// without calling their methods.
+func rawString(v interface{}) string {
+	switch vv := v.(type) {
+	case string:
+		return vv
+	case fmt.Stringer, error, encoding.TextMarshaler, json.Marshaler:
+		if nilPointer(vv) {
+			// a nil pointer's methods may panic
+			return "<nil>"
+		}
+	}
+
+	switch vv := v.(type) {
+	case fmt.Stringer:
+		return vv.String()
+	case error:
+		return vv.Error()
+	case encoding.TextMarshaler:
+		if text, err := vv.MarshalText(); err == nil {
+			return string(text)
+		}
+	case json.Marshaler:
+		if data, err := vv.MarshalJSON(); err == nil {
+			var s string
+			if json.Unmarshal(data, &s) == nil {
+				return s
+			}
+			return string(data)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// sanitizeKey replaces any characters that are not permitted in a logfmt key with underscores. An empty
+// key is written as a single underscore, since logfmt parsers reject pairs without a key.
+func sanitizeKey(s string) string {
+	if s == "" {
+		return "_"
+	}
+	for _, r := range s {
+		if !validRune(r) {
+			return strings.Map(func(r rune) rune {
+				if !validRune(r) {
+					return '_'
+				}
+				return r
+			}, s)
+		}
+	}
+	return s
+}
+
+// validRune reports whether r may appear unquoted in a logfmt key or value.
+func validRune(r rune) bool {
+	return r > ' ' && r != '=' && r != '"' && r != utf8.RuneError && unicode.IsPrint(r)
+}
@@ -0,0 +1,539 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts).WithName("europa").WithValues("user", "you")
+
+	logger.Info("hello world", "val", 1)
+	logger.Error(errors.New("uh oh"), "goodbye")
+
+	want := "level=0 logger=europa msg=\"hello world\" user=you val=1\n" +
+		"level=0 logger=europa msg=goodbye error=\"uh oh\" user=you\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestLevelNames(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.LevelNames = map[int]string{0: "info", 1: "debug"}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello")
+	logger.V(1).Info("details")
+	logger.Error(nil, "goodbye")
+
+	want := "level=info msg=hello\n" +
+		"level=debug msg=details\n" +
+		"level=error msg=goodbye error=<nil>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = "2006"
+	opts.FieldNames = logfmtr.FieldNames{
+		Timestamp: "time",
+		Message:   "message",
+		Error:     "err",
+	}
+	logger := logfmtr.NewWithOptions(opts).WithName("europa")
+
+	logger.Error(errors.New("uh oh"), "goodbye")
+
+	want := "level=0 logger=europa time=" + time.Now().UTC().Format("2006") + " message=goodbye err=\"uh oh\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestLogfmtEncoderEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("a=b", "query", `name="x"`, "text", "line1\nline2", "bell", "\a", "bad key", "v", "k=v", 1, "", "")
+
+	want := `level=0 msg="a=b" query="name=\"x\"" text="line1\nline2" bell="\a" bad_key=v k_v=1 _=` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestKeyMode(t *testing.T) {
+	testCases := []struct {
+		mode logfmtr.KeyMode
+		want string
+	}{
+		{mode: logfmtr.KeyReplace, want: `level=0 msg=hello user_id=1 a_b=2 1=3 _=4 m.x_y=5` + "\n"},
+		{mode: logfmtr.KeyQuote, want: `level=0 msg=hello "user id"=1 "a=b"=2 1=3 ""=4 "m.x y"=5` + "\n"},
+		{mode: logfmtr.KeyBadKey, want: `level=0 msg=hello !BADKEY=1 !BADKEY=2 !BADKEY=3 !BADKEY=4 !BADKEY=5` + "\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.KeyMode = tc.mode
+			opts.FlattenDepth = 1
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("hello", "user id", 1, "a=b", 2, 1, 3, "", 4, "m", map[string]int{"x y": 5})
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// level has a MarshalText method.
+type level int
+
+func (l level) MarshalText() ([]byte, error) { return []byte("L" + fmt.Sprint(int(l))), nil }
+
+// money has a MarshalJSON method that writes a JSON string.
+type money struct{ cents int }
+
+func (m money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("$%d.%02d", m.cents/100, m.cents%100))
+}
+
+// pair has a MarshalJSON method that writes a JSON array.
+type pair [2]int
+
+func (p pair) MarshalJSON() ([]byte, error) { return []byte(fmt.Sprintf("[%d,%d]", p[0], p[1])), nil }
+
+func TestLogfmtEncoderMarshalers(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello", "level", level(3), "price", money{cents: 1250}, "pair", pair{1, 2}, "at", time.Duration(0))
+
+	want := `level=0 msg=hello level=L3 price=$12.50 pair=[1,2] at=0s` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Encoder = &logfmtr.JSONEncoder{}
+	logger := logfmtr.NewWithOptions(opts).WithName("europa").WithValues("user", "you")
+
+	logger.Info("hello world", "val", 1, "ok", true, "reasons", []float64{0.1, 3.14})
+	logger.Error(nil, "goodbye", "code", -1)
+
+	want := `{"level":0,"logger":"europa","msg":"hello world","user":"you","val":1,"ok":true,"reasons":[0.1,3.14]}` + "\n" +
+		`{"level":0,"logger":"europa","msg":"goodbye","error":null,"user":"you","code":-1}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestJSONEncoderValues(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Encoder = &logfmtr.JSONEncoder{}
+	logger := logfmtr.NewWithOptions(opts)
+
+	values := []interface{}{"quote\"\\\n\t\x01<>&\u2028", 1e-7, 1e21, 123456.5, float32(0.1), uint8(200), math.Inf(1), time.Second}
+	kvs := make([]interface{}, 0, 2*len(values))
+	for i, v := range values {
+		kvs = append(kvs, fmt.Sprintf("k%d", i), v)
+	}
+	logger.Info("values", kvs...)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected error decoding %q: %v", buf.String(), err)
+	}
+	want := []interface{}{"quote\"\\\n\t\x01<>&\u2028", 1e-7, 1e21, 123456.5, 0.1, 200.0, "+Inf", "1s"}
+	for i, w := range want {
+		k := fmt.Sprintf("k%d", i)
+		if got[k] != w {
+			t.Errorf("%s: got %v, wanted %v", k, got[k], w)
+		}
+	}
+}
+
+type msgEncoder struct{}
+
+func (msgEncoder) EncodeEntry(w io.Writer, e logfmtr.Entry) error {
+	_, err := fmt.Fprintf(w, "%s %s %v %v\n", e.Name, e.Message, e.Context, e.Values)
+	return err
+}
+
+func TestCustomEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Encoder = msgEncoder{}
+	base := logfmtr.NewWithOptions(opts).WithName("europa").WithValues("a", 1)
+
+	// sibling loggers must not share accumulated values
+	l1 := base.WithValues("b", 2)
+	l2 := base.WithValues("c", 3)
+	l1.Info("one")
+	l2.Info("two", "d", 4)
+
+	want := "europa one [a 1 b 2] []\n" +
+		"europa two [a 1 c 3] [d 4]\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestTimeLocation(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	clock := func() time.Time {
+		return time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Clock = clock
+	opts.TimestampFormat = time.RFC3339
+	opts.TimeLocation = loc
+	logfmtr.NewWithOptions(opts).Info("hello")
+
+	opts.Humanize = true
+	logfmtr.NewWithOptions(opts).Info("hello")
+
+	opts.HumanTimestampFormat = "2006-01-02 15:04:05 MST"
+	logfmtr.NewWithOptions(opts).Info("hello")
+
+	want := "level=0 ts=2021-09-01T07:00:00-05:00 msg=hello\n" +
+		"0 info  | 07:00:00.000000 | hello                         \n" +
+		"0 info  | 2021-09-01 07:00:00 EST | hello                         \n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestTimestampMode(t *testing.T) {
+	clock := func() time.Time {
+		return time.Date(2023, 11, 14, 22, 13, 19, 123456789, time.UTC)
+	}
+
+	testCases := []struct {
+		mode logfmtr.TimestampMode
+		want string
+	}{
+		{mode: logfmtr.TimestampEpochSeconds, want: "level=0 ts=1699999999.123 msg=hello\n"},
+		{mode: logfmtr.TimestampEpochMillis, want: "level=0 ts=1699999999123 msg=hello\n"},
+		{mode: logfmtr.TimestampEpochNanos, want: "level=0 ts=1699999999123456789 msg=hello\n"},
+	}
+
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		opts := logfmtr.DefaultOptions()
+		opts.Writer = &buf
+		opts.Clock = clock
+		opts.TimestampMode = tc.mode
+		logfmtr.NewWithOptions(opts).Info("hello")
+		if got := buf.String(); got != tc.want {
+			t.Errorf("mode %d: got %q, wanted %q", tc.mode, got, tc.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Clock = clock
+	opts.Encoder = &logfmtr.JSONEncoder{TimestampMode: logfmtr.TimestampEpochSeconds}
+	logfmtr.NewWithOptions(opts).Info("hello")
+	if want := `{"level":0,"ts":1699999999.123,"msg":"hello"}` + "\n"; buf.String() != want {
+		t.Errorf("got %q, wanted %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	opts.Clock = nil
+	opts.Encoder = nil
+	opts.TimestampMode = logfmtr.TimestampElapsed
+	logfmtr.NewWithOptions(opts).Info("hello")
+	var secs float64
+	if _, err := fmt.Sscanf(buf.String(), "level=0 ts=%f msg=hello\n", &secs); err != nil || secs <= 0 {
+		t.Errorf("got %q, wanted positive elapsed seconds", buf.String())
+	}
+}
+
+func TestECSEncoder(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Encoder = &logfmtr.ECSEncoder{}
+	opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 678900000, time.UTC) }
+	logger := logfmtr.NewWithOptions(opts).WithName("europa").WithValues("user", "you")
+
+	logger.Info("hello", "val", 1)
+	logger.V(1).Info("details")
+	logger.Error(errors.New("uh oh"), "goodbye")
+
+	want := `{"@timestamp":"2024-01-02T03:04:05.678Z","log.level":"info","message":"hello","ecs.version":"1.6.0","log.logger":"europa","user":"you","val":1}` + "\n" +
+		`{"@timestamp":"2024-01-02T03:04:05.678Z","log.level":"debug","message":"details","ecs.version":"1.6.0","log.logger":"europa","user":"you"}` + "\n" +
+		`{"@timestamp":"2024-01-02T03:04:05.678Z","log.level":"error","message":"goodbye","ecs.version":"1.6.0","log.logger":"europa","error.message":"uh oh","user":"you"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestECSEncoderCaller(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Encoder = &logfmtr.ECSEncoder{}
+	opts.AddCaller = true
+	opts.CallerFunc = true
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello")
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected error decoding %q: %v", buf.String(), err)
+	}
+	if got["log.origin.file.name"] != "encoder_test.go" {
+		t.Errorf("got file name %v, wanted encoder_test.go", got["log.origin.file.name"])
+	}
+	if _, ok := got["log.origin.file.line"].(float64); !ok {
+		t.Errorf("got file line %v, wanted a number", got["log.origin.file.line"])
+	}
+	if got["log.origin.function"] != "logfmtr_test.TestECSEncoderCaller" {
+		t.Errorf("got function %v, wanted logfmtr_test.TestECSEncoderCaller", got["log.origin.function"])
+	}
+}
+
+func TestHumanEncoderAlignment(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Humanize = true
+	opts.HumanTimestampFormat = "15:04"
+	opts.HumanMessageWidth = 8
+	opts.HumanSortKeys = true
+	opts.HumanAlignKeys = true
+	opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	logger := logfmtr.NewWithOptions(opts).WithValues("user", "you")
+
+	logger.Info("hello", "id", 12345, "b", 1)
+	logger.Info("goodbye", "id", 7, "b", 22)
+	logger.Info("hello", "id", 8, "b", 3)
+
+	// pairs are padded to the widest pair written so far with the same key
+	want := "0 info  | 03:04 | hello    b=1 id=12345 user=you\n" +
+		"0 info  | 03:04 | goodbye  b=22 id=7     user=you\n" +
+		"0 info  | 03:04 | hello    b=3  id=8     user=you\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestHumanEncoderMultilineValues(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Humanize = true
+	opts.HumanTimestampFormat = "15:04"
+	opts.HumanMessageWidth = 8
+	opts.MultilineValues = true
+	opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Error(errors.New("line one\nline two"), "failed", "config", "a: 1\nb:\n\tc: 2\n", "id", 7)
+
+	want := "0 error | 03:04 | failed   id=7\n" +
+		"  error=\n" +
+		"    line one\n" +
+		"    line two\n" +
+		"  config=\n" +
+		"    a: 1\n" +
+		"    b:\n" +
+		"    \tc: 2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestHumanEncoderTruncation(t *testing.T) {
+	e := logfmtr.Entry{
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Message: "hello",
+		Values:  []interface{}{"a", "some long value", "b", "héllo"},
+	}
+
+	testCases := []struct {
+		name string
+		enc  *logfmtr.HumanEncoder
+		want string
+	}{
+		{
+			name: "fits",
+			enc:  &logfmtr.HumanEncoder{TimestampFormat: "15:04", MessageWidth: 5, MaxWidth: 60},
+			want: "0 info  | 03:04 | hello a=\"some long value\" b=héllo\n",
+		},
+		{
+			name: "exact",
+			enc:  &logfmtr.HumanEncoder{TimestampFormat: "15:04", MessageWidth: 5, MaxWidth: 51},
+			want: "0 info  | 03:04 | hello a=\"some long value\" b=héllo\n",
+		},
+		{
+			name: "truncated",
+			enc:  &logfmtr.HumanEncoder{TimestampFormat: "15:04", MessageWidth: 5, MaxWidth: 49},
+			want: "0 info  | 03:04 | hello a=\"some long value\" b=hé…\n",
+		},
+		{
+			name: "colorized",
+			enc:  &logfmtr.HumanEncoder{TimestampFormat: "15:04", MessageWidth: 5, MaxWidth: 12, Colorize: true},
+			want: "0 \x1b[1;32minfo \x1b[0m | 0…\x1b[0m\n",
+		},
+		{
+			name: "multiline",
+			enc:  &logfmtr.HumanEncoder{TimestampFormat: "15:04", MessageWidth: 5, MaxWidth: 10, MultilineValues: true},
+			want: "0 info  |…\n  a=\n    line …\n    short\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := e
+			if tc.enc.MultilineValues {
+				e.Values = []interface{}{"a", "line one is long\nshort"}
+			}
+			var buf bytes.Buffer
+			if err := tc.enc.EncodeEntry(&buf, e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLevelSymbols(t *testing.T) {
+	custom := &logfmtr.LevelSymbols{Error: "ERR", Info: "i", Verbose: "v", Levels: map[int]string{2: "vv"}}
+
+	testCases := []struct {
+		name    string
+		symbols *logfmtr.LevelSymbols
+		level   int
+		isError bool
+		want    string
+	}{
+		{name: "default error", symbols: logfmtr.DefaultLevelSymbols(), isError: true, want: "0 ✗ | 03:04 | m error=boom\n"},
+		{name: "default info", symbols: logfmtr.DefaultLevelSymbols(), level: 0, want: "0 ✓ | 03:04 | m\n"},
+		{name: "default verbose", symbols: logfmtr.DefaultLevelSymbols(), level: 1, want: "1 · | 03:04 | m\n"},
+		{name: "default beyond table", symbols: logfmtr.DefaultLevelSymbols(), level: 7, want: "7 · | 03:04 | m\n"},
+		{name: "custom error", symbols: custom, isError: true, want: "0 ERR | 03:04 | m error=boom\n"},
+		{name: "custom info", symbols: custom, level: 0, want: "0 i   | 03:04 | m\n"},
+		{name: "custom verbose", symbols: custom, level: 1, want: "1 v   | 03:04 | m\n"},
+		{name: "custom level", symbols: custom, level: 2, want: "2 vv  | 03:04 | m\n"},
+		{name: "custom beyond table", symbols: custom, level: 9, want: "9 v   | 03:04 | m\n"},
+		{name: "none error", isError: true, want: "0 error | 03:04 | m error=boom\n"},
+		{name: "none info", level: 0, want: "0 info  | 03:04 | m\n"},
+		{name: "none verbose", level: 3, want: "3 info  | 03:04 | m\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			enc := &logfmtr.HumanEncoder{TimestampFormat: "15:04", MessageWidth: 1, LevelSymbols: tc.symbols}
+			e := logfmtr.Entry{
+				Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Level:   tc.level,
+				IsError: tc.isError,
+				Message: "m",
+			}
+			if tc.isError {
+				e.Error = errors.New("boom")
+			}
+			var buf bytes.Buffer
+			if err := enc.EncodeEntry(&buf, e); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHumanTimeMode(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	deltas := []time.Duration{0, 12300 * time.Microsecond, 1507 * time.Millisecond, 150 * time.Nanosecond, 45 * time.Microsecond, 2*time.Minute + 3*time.Second}
+
+	testCases := []struct {
+		mode logfmtr.HumanTimeMode
+		want []string
+	}{
+		{
+			mode: logfmtr.HumanTimeDelta,
+			want: []string{"+12.3ms ", "+1.51s  ", "+150ns  ", "+45.0µs ", "+2m3s   "},
+		},
+		{
+			mode: logfmtr.HumanTimeAbsoluteDelta,
+			want: []string{"03:04:05 +12.3ms ", "03:04:06 +1.51s  ", "03:04:06 +150ns  ", "03:04:06 +45.0µs ", "03:06:09 +2m3s   "},
+		},
+	}
+
+	for _, tc := range testCases {
+		enc := &logfmtr.HumanEncoder{TimestampFormat: "15:04:05", TimeMode: tc.mode, MessageWidth: 1}
+		var got []string
+		now := base
+		for i, d := range deltas {
+			now = now.Add(d)
+			var buf bytes.Buffer
+			if err := enc.EncodeEntry(&buf, logfmtr.Entry{Time: now, Message: "m"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if i == 0 {
+				// the first entry shows the time since the program started
+				continue
+			}
+			line := strings.TrimPrefix(buf.String(), "0 info  | ")
+			got = append(got, strings.TrimSuffix(line, " | m\n"))
+		}
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("mode %d: got %q, wanted %q", tc.mode, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package logfmtr
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by OptionsFromEnv.
+const (
+	EnvVerbosity       = "LOGFMTR_VERBOSITY"        // global verbosity, an integer
+	EnvFormat          = "LOGFMTR_FORMAT"           // output format: logfmt, json, ecs or human
+	EnvHumanize        = "LOGFMTR_HUMANIZE"         // boolean, equivalent to a format of human
+	EnvColorize        = "LOGFMTR_COLORIZE"         // boolean, adds color to human output
+	EnvColor           = "LOGFMTR_COLOR"            // color mode for human output: auto, always or never
+	EnvAddCaller       = "LOGFMTR_ADD_CALLER"       // boolean, sets Options.AddCaller
+	EnvTimestampFormat = "LOGFMTR_TIMESTAMP_FORMAT" // sets Options.TimestampFormat
+	EnvDisable         = "LOGFMTR_DISABLE"          // comma separated names or patterns of loggers to disable
+)
+
+// Output formats accepted by OptionsFromEnv.
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+	FormatECS    = "ecs"
+	FormatHuman  = "human"
+)
+
+// OptionsFromEnv returns DefaultOptions modified by the environment variables named by the Env constants. It also
+// sets the global verbosity from LOGFMTR_VERBOSITY and disables the loggers listed in LOGFMTR_DISABLE.
+// Unset variables leave the corresponding option unchanged. If any variable has an invalid value, if
+// LOGFMTR_HUMANIZE contradicts LOGFMTR_FORMAT, or if the resulting options are rejected by Validate, an
+// error is returned and no changes are made to the global verbosity or disabled loggers.
+func OptionsFromEnv() (Options, error) {
+	opts := DefaultOptions()
+
+	var verbosity int
+	v, hasVerbosity := os.LookupEnv(EnvVerbosity)
+	if hasVerbosity {
+		var err error
+		verbosity, err = strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", EnvVerbosity, err)
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvTimestampFormat); ok {
+		opts.TimestampFormat = v
+	}
+
+	format, hasFormat := os.LookupEnv(EnvFormat)
+	if hasFormat {
+		if err := setFormat(&opts, format); err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", EnvFormat, err)
+		}
+	}
+
+	if v, ok := os.LookupEnv(EnvColor); ok {
+		m, err := parseColorMode(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", EnvColor, err)
+		}
+		opts.ColorMode = m
+	}
+
+	for _, bv := range []struct {
+		name string
+		dst  *bool
+	}{
+		{EnvHumanize, &opts.Humanize},
+		{EnvColorize, &opts.Colorize},
+		{EnvAddCaller, &opts.AddCaller},
+	} {
+		v, ok := os.LookupEnv(bv.name)
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", bv.name, err)
+		}
+		*bv.dst = b
+	}
+
+	if hasFormat {
+		if v, ok := os.LookupEnv(EnvHumanize); ok && opts.Humanize != isHumanFormat(format) {
+			return opts, fmt.Errorf("%s=%s conflicts with %s=%s", EnvHumanize, strings.TrimSpace(v), EnvFormat, strings.TrimSpace(format))
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, fmt.Errorf("invalid logging environment: %w", err)
+	}
+
+	if hasVerbosity {
+		SetVerbosity(verbosity)
+	}
+	for _, name := range strings.Split(os.Getenv(EnvDisable), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			DisableLogger(name)
+		}
+	}
+
+	return opts, nil
+}
+
+// isHumanFormat reports whether format names the human friendly format.
+func isHumanFormat(format string) bool {
+	return strings.ToLower(strings.TrimSpace(format)) == FormatHuman
+}
+
+// setFormat configures opts to write in the named format.
+func setFormat(opts *Options, format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatLogfmt, "":
+		opts.Encoder = nil
+		opts.Humanize = false
+	case FormatJSON:
+		opts.Encoder = &JSONEncoder{
+			TimestampFormat: opts.TimestampFormat,
+			TimestampMode:   opts.TimestampMode,
+			Location:        opts.location(),
+			LevelNames:      opts.LevelNames,
+			FieldNames:      opts.FieldNames,
+			NilError:        opts.NilError,
+		}
+		opts.Humanize = false
+	case FormatECS:
+		opts.Encoder = &ECSEncoder{
+			Location:   opts.location(),
+			LevelNames: opts.LevelNames,
+		}
+		opts.Humanize = false
+	case FormatHuman:
+		opts.Encoder = nil
+		opts.Humanize = true
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package logfmtr_test
+
+import (
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestOptionsFromEnv(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+	defer logfmtr.EnableLogger("kafka.*")
+	defer logfmtr.EnableLogger("io")
+
+	t.Setenv(logfmtr.EnvVerbosity, "3")
+	t.Setenv(logfmtr.EnvFormat, "JSON")
+	t.Setenv(logfmtr.EnvAddCaller, "true")
+	t.Setenv(logfmtr.EnvTimestampFormat, "")
+	t.Setenv(logfmtr.EnvDisable, "io, kafka.*")
+
+	opts, err := logfmtr.OptionsFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.AddCaller {
+		t.Errorf("got AddCaller false, wanted true")
+	}
+	enc, ok := opts.Encoder.(*logfmtr.JSONEncoder)
+	if !ok {
+		t.Fatalf("got encoder %T, wanted *logfmtr.JSONEncoder", opts.Encoder)
+	}
+	if enc.TimestampFormat != "" {
+		t.Errorf("got timestamp format %q, wanted empty", enc.TimestampFormat)
+	}
+	if v := logfmtr.Verbosity(); v != 3 {
+		t.Errorf("got verbosity %d, wanted 3", v)
+	}
+	if logfmtr.NewNamed("kafka.consumer").Enabled() {
+		t.Errorf("got kafka.consumer enabled, wanted disabled")
+	}
+}
+
+func TestOptionsFromEnvInvalid(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+
+	t.Setenv(logfmtr.EnvVerbosity, "2")
+	t.Setenv(logfmtr.EnvHumanize, "maybe")
+
+	if _, err := logfmtr.OptionsFromEnv(); err == nil {
+		t.Errorf("got no error, wanted error for invalid %s", logfmtr.EnvHumanize)
+	}
+	if v := logfmtr.Verbosity(); v != 0 {
+		t.Errorf("got verbosity %d, wanted 0", v)
+	}
+}
+
+func TestOptionsFromEnvColor(t *testing.T) {
+	t.Setenv(logfmtr.EnvColor, "Auto")
+	opts, err := logfmtr.OptionsFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.ColorMode != logfmtr.ColorAuto {
+		t.Errorf("got color mode %v, wanted %v", opts.ColorMode, logfmtr.ColorAuto)
+	}
+
+	t.Setenv(logfmtr.EnvColor, "sometimes")
+	if _, err := logfmtr.OptionsFromEnv(); err == nil {
+		t.Errorf("got no error for invalid color mode, wanted error")
+	}
+}
+
+func TestOptionsFromEnvConflict(t *testing.T) {
+	for _, tc := range []struct {
+		format, humanize string
+		wantErr          bool
+	}{
+		{format: "json", humanize: "true", wantErr: true},
+		{format: "human", humanize: "false", wantErr: true},
+		{format: "human", humanize: "true"},
+		{format: "logfmt", humanize: "false"},
+	} {
+		t.Setenv(logfmtr.EnvFormat, tc.format)
+		t.Setenv(logfmtr.EnvHumanize, tc.humanize)
+		opts, err := logfmtr.OptionsFromEnv()
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("format %s, humanize %s: got no error, wanted conflict", tc.format, tc.humanize)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("format %s, humanize %s: unexpected error: %v", tc.format, tc.humanize, err)
+			continue
+		}
+		if err := opts.Validate(); err != nil {
+			t.Errorf("format %s, humanize %s: got invalid options: %v", tc.format, tc.humanize, err)
+		}
+	}
+}
@@ -46,6 +46,10 @@ func deferred() {
 	l2.Info("this should be logged with new global options, humanized and colorized, since instantiation was deferred until first write")
 	l3.Info("this should also be logged with new options, humanized and colorized")
 	l1.Info("this should be logged with the old options since first write was before we set global options")
+
+	logfmtr.SetLiveOptions(true)
+	defer logfmtr.SetLiveOptions(false)
+	l1.Info("this should be logged with the new options since live options reconfigure instantiated loggers")
 }
 
 func disableDemo() {
@@ -0,0 +1,100 @@
+package logfmtr
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// A Filter reports whether an entry should be written. Filters added with AddFilter apply to all loggers.
+type Filter func(e Entry) bool
+
+var (
+	filtersMu sync.Mutex // synchronises writes to filters
+	filters   atomic.Value
+	anyFilter int32 = 0 // an atomicly accessed variable that is set to 1 if there are any filters
+
+	filterID uint64 // identifies filters so they can be removed, guarded by filtersMu
+)
+
+type registeredFilter struct {
+	id uint64
+	f  Filter
+}
+
+func init() {
+	filters.Store([]registeredFilter{})
+}
+
+// AddFilter adds a filter that is applied to the entries written by every logger. An entry is written
+// only if all filters return true. Filters are applied before any hooks. The returned function removes
+// the filter.
+func AddFilter(f Filter) (remove func()) {
+	filtersMu.Lock()
+	defer filtersMu.Unlock()
+	filterID++
+	id := filterID
+	current := filters.Load().([]registeredFilter)
+	next := append(current[:len(current):len(current)], registeredFilter{id: id, f: f})
+	storeFilters(next)
+
+	return func() {
+		filtersMu.Lock()
+		defer filtersMu.Unlock()
+		current := filters.Load().([]registeredFilter)
+		next := make([]registeredFilter, 0, len(current))
+		for _, rf := range current {
+			if rf.id != id {
+				next = append(next, rf)
+			}
+		}
+		storeFilters(next)
+	}
+}
+
+// storeFilters replaces the filters. filtersMu must be held.
+func storeFilters(next []registeredFilter) {
+	filters.Store(next)
+	if len(next) == 0 {
+		atomic.StoreInt32(&anyFilter, 0)
+	} else {
+		atomic.StoreInt32(&anyFilter, 1)
+	}
+}
+
+// filtered reports whether any filter rejects the entry.
+func filtered(e Entry) bool {
+	if atomic.LoadInt32(&anyFilter) == 0 {
+		return false
+	}
+	for _, rf := range filters.Load().([]registeredFilter) {
+		if !rf.f(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// DropMessages returns a filter that rejects entries whose message matches re.
+func DropMessages(re *regexp.Regexp) Filter {
+	return func(e Entry) bool {
+		return !re.MatchString(e.Message)
+	}
+}
+
+// DropField returns a filter that rejects entries with a key/value pair whose key is key and whose
+// value, formatted with fmt.Sprint, is value. For example DropField("path", "/healthz") drops health
+// check access logs.
+func DropField(key string, value string) Filter {
+	return func(e Entry) bool {
+		v, ok := e.Lookup(key)
+		if !ok {
+			return true
+		}
+		if s, ok := v.(string); ok {
+			return s != value
+		}
+		return fmt.Sprint(v) != value
+	}
+}
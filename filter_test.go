@@ -0,0 +1,60 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestAddFilter(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	removeField := logfmtr.AddFilter(logfmtr.DropField("path", "/healthz"))
+	removeMsg := logfmtr.AddFilter(logfmtr.DropMessages(regexp.MustCompile(`^heartbeat`)))
+
+	logger.Info("request", "path", "/healthz")
+	logger.WithValues("path", "/healthz").Info("request")
+	logger.Info("request", "path", "/index")
+	logger.Info("heartbeat 1")
+	logger.Info("beat")
+
+	removeField()
+	removeMsg()
+	logger.Info("request", "path", "/healthz")
+
+	want := "level=0 msg=request path=/index\n" +
+		"level=0 msg=beat\n" +
+		"level=0 msg=request path=/healthz\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestEntryLookup(t *testing.T) {
+	e := logfmtr.Entry{
+		Context: []interface{}{"a", 1, "b", 2},
+		Values:  []interface{}{"b", 3, "c"},
+	}
+	testCases := []struct {
+		key   string
+		value interface{}
+		found bool
+	}{
+		{key: "a", value: 1, found: true},
+		{key: "b", value: 3, found: true},
+		{key: "c", value: "", found: true},
+		{key: "d", value: nil, found: false},
+	}
+	for _, tc := range testCases {
+		v, ok := e.Lookup(tc.key)
+		if v != tc.value || ok != tc.found {
+			t.Errorf("%s: got %v, %v, wanted %v, %v", tc.key, v, ok, tc.value, tc.found)
+		}
+	}
+}
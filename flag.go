@@ -0,0 +1,92 @@
+package logfmtr
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// VerbosityFlag returns a flag.Value that reads and sets the global verbosity.
+//
+//	flag.Var(logfmtr.VerbosityFlag(), "v", "log verbosity")
+func VerbosityFlag() flag.Value {
+	return verbosityFlag{}
+}
+
+type verbosityFlag struct{}
+
+func (verbosityFlag) String() string {
+	return strconv.Itoa(Verbosity())
+}
+
+func (verbosityFlag) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	SetVerbosity(v)
+	return nil
+}
+
+// FormatFlag returns a flag.Value that reads and sets the output format of the options set by UseOptions.
+// The format may be one of logfmt, json, ecs or human. As with UseOptions, loggers that have already been
+// instantiated also use the new format if live options have been enabled by SetLiveOptions.
+func FormatFlag() flag.Value {
+	return formatFlag{}
+}
+
+type formatFlag struct{}
+
+func (formatFlag) String() string {
+	goptionsmu.Lock()
+	defer goptionsmu.Unlock()
+	switch {
+	case goptions.Encoder != nil:
+		switch goptions.Encoder.(type) {
+		case *JSONEncoder:
+			return FormatJSON
+		case *ECSEncoder:
+			return FormatECS
+		}
+		return ""
+	case goptions.Humanize:
+		return FormatHuman
+	default:
+		return FormatLogfmt
+	}
+}
+
+func (formatFlag) Set(s string) error {
+	return changeOptions(func(o *Options) error {
+		return setFormat(o, s)
+	})
+}
+
+// DisableFlag returns a flag.Value that disables loggers by name or pattern, as DisableLogger. The value is a
+// comma separated list and the flag may be repeated.
+func DisableFlag() flag.Value {
+	return disableFlag{}
+}
+
+type disableFlag struct{}
+
+func (disableFlag) String() string {
+	return strings.Join(CurrentConfig().DisabledLoggers, ",")
+}
+
+func (disableFlag) Set(s string) error {
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			DisableLogger(name)
+		}
+	}
+	return nil
+}
+
+// AddFlags defines the flags v, log-format and log-disable in fs using VerbosityFlag, FormatFlag and
+// DisableFlag.
+func AddFlags(fs *flag.FlagSet) {
+	fs.Var(VerbosityFlag(), "v", "log verbosity level")
+	fs.Var(FormatFlag(), "log-format", "log output format: logfmt, json, ecs or human")
+	fs.Var(DisableFlag(), "log-disable", "comma separated names or patterns of loggers to disable")
+}
@@ -0,0 +1,74 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestAddFlags(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+	defer logfmtr.EnableLogger("io")
+	defer logfmtr.EnableLogger("kafka.*")
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logfmtr.UseOptions(opts)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	logfmtr.AddFlags(fs)
+
+	if err := fs.Parse([]string{"-v", "2", "-log-format", "json", "-log-disable", "io,kafka.*"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v := logfmtr.Verbosity(); v != 2 {
+		t.Errorf("got verbosity %d, wanted 2", v)
+	}
+	if got := fs.Lookup("log-format").Value.String(); got != "json" {
+		t.Errorf("got format %q, wanted %q", got, "json")
+	}
+	if got := fs.Lookup("log-disable").Value.String(); got != "io,kafka.*" {
+		t.Errorf("got disabled %q, wanted %q", got, "io,kafka.*")
+	}
+	if logfmtr.NewNamed("kafka.consumer").Enabled() {
+		t.Errorf("got kafka.consumer enabled, wanted disabled")
+	}
+
+	logfmtr.New().Info("hello")
+	if want := `{"level":0,"msg":"hello"}` + "\n"; buf.String() != want {
+		t.Errorf("got %q, wanted %q", buf.String(), want)
+	}
+}
+
+func TestFormatFlagLiveOptions(t *testing.T) {
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+	defer logfmtr.SetLiveOptions(false)
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logfmtr.UseOptions(opts)
+	logfmtr.SetLiveOptions(true)
+
+	logger := logfmtr.New()
+	logger.Info("one")
+
+	if err := logfmtr.FormatFlag().Set("json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger.Info("two")
+
+	want := "level=0 msg=one\n" + `{"level":0,"msg":"two"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
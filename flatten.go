@@ -0,0 +1,125 @@
+package logfmtr
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// flattenPairs returns the entry with map and struct values of its key/value pairs replaced by a pair for
+// each of their entries or fields, nested up to depth levels. The key/value slices are copied before they
+// are changed since they may be shared with other entries.
+func flattenPairs(e Entry, depth int) Entry {
+	e.Context = flattenKVs(e.Context, depth)
+	e.Values = flattenKVs(e.Values, depth)
+	return e
+}
+
+// flattenKVs returns kvs with map and struct values flattened, or kvs itself if there are none.
+func flattenKVs(kvs []interface{}, depth int) []interface{} {
+	i := 1
+	for ; i < len(kvs); i += 2 {
+		if flattenable(kvs[i]) {
+			break
+		}
+	}
+	if i >= len(kvs) {
+		return kvs
+	}
+
+	flat := append([]interface{}(nil), kvs[:i-1]...)
+	for ; i < len(kvs); i += 2 {
+		flat = appendFlattened(flat, rawString(kvs[i-1]), kvs[i], depth)
+	}
+	if len(kvs)%2 != 0 {
+		flat = append(flat, kvs[len(kvs)-1])
+	}
+	return flat
+}
+
+// appendFlattened appends the pairs for a value with the given key to kvs, flattening maps and structs
+// up to depth levels. Maps and structs that have no entries or fields are appended unchanged.
+func appendFlattened(kvs []interface{}, key string, v interface{}, depth int) []interface{} {
+	if depth <= 0 || !flattenable(v) {
+		return append(kvs, key, v)
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	n := len(kvs)
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := rv.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		sort.Sort(mapKeys{names: names, keys: keys})
+		for i, k := range keys {
+			kvs = appendFlattened(kvs, key+"."+names[i], rv.MapIndex(k).Interface(), depth-1)
+		}
+	case reflect.Struct:
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := fieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			kvs = appendFlattened(kvs, key+"."+name, rv.Field(i).Interface(), depth-1)
+		}
+	}
+	if len(kvs) == n {
+		return append(kvs, key, v)
+	}
+	return kvs
+}
+
+// flattenable reports whether v is a map, a struct or a pointer to a struct that does not have its own
+// string or JSON representation.
+func flattenable(v interface{}) bool {
+	switch v.(type) {
+	case nil, fmt.Stringer, error, json.Marshaler, encoding.TextMarshaler:
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return false
+		}
+		rv = rv.Elem()
+		if rv.Kind() != reflect.Struct {
+			return false
+		}
+	}
+	return rv.Kind() == reflect.Map || rv.Kind() == reflect.Struct
+}
+
+// fieldName returns the key used for a struct field when flattening, which is the name given by its
+// json tag if it has one. It reports false for unexported fields and those with a json tag of "-".
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+// mapKeys sorts the keys of a map by their names.
+type mapKeys struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (m mapKeys) Len() int           { return len(m.names) }
+func (m mapKeys) Less(i, j int) bool { return m.names[i] < m.names[j] }
+func (m mapKeys) Swap(i, j int) {
+	m.names[i], m.names[j] = m.names[j], m.names[i]
+	m.keys[i], m.keys[j] = m.keys[j], m.keys[i]
+}
@@ -0,0 +1,89 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+type address struct {
+	City     string `json:"city"`
+	Postcode string `json:"-"`
+	Geo      struct{ Lat, Lon float64 }
+}
+
+type user struct {
+	ID      int
+	Name    string `json:"name,omitempty"`
+	Address *address
+	Joined  time.Time
+	secret  string
+}
+
+func TestFlattenDepth(t *testing.T) {
+	u := user{
+		ID:      7,
+		Name:    "jo",
+		Address: &address{City: "Leeds", Postcode: "LS1"},
+		Joined:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		secret:  "hidden",
+	}
+	u.Address.Geo.Lat = 53.8
+
+	testCases := []struct {
+		name  string
+		depth int
+		kvs   []interface{}
+		want  string
+	}{
+		{
+			name:  "disabled",
+			depth: 0,
+			kvs:   []interface{}{"m", map[string]int{"b": 2, "a": 1}},
+			want:  `level=0 msg=hello m="map[a:1 b:2]"` + "\n",
+		},
+		{
+			name:  "map",
+			depth: 1,
+			kvs:   []interface{}{"m", map[string]int{"b": 2, "a": 1}, "after", true},
+			want:  `level=0 msg=hello m.a=1 m.b=2 after=true` + "\n",
+		},
+		{
+			name:  "struct",
+			depth: 3,
+			kvs:   []interface{}{"user", u},
+			want:  `level=0 msg=hello user.ID=7 user.name=jo user.Address.city=Leeds user.Address.Geo.Lat=53.8 user.Address.Geo.Lon=0 user.Joined="2024-01-02 00:00:00 +0000 UTC"` + "\n",
+		},
+		{
+			name:  "depth limited",
+			depth: 2,
+			kvs:   []interface{}{"user", &u},
+			want:  `level=0 msg=hello user.ID=7 user.name=jo user.Address.city=Leeds user.Address.Geo="{53.8 0}" user.Joined="2024-01-02 00:00:00 +0000 UTC"` + "\n",
+		},
+		{
+			name:  "empty and nil",
+			depth: 1,
+			kvs:   []interface{}{"empty", map[string]int{}, "nil", (*user)(nil)},
+			want:  `level=0 msg=hello empty=map[] nil=<nil>` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.FlattenDepth = tc.depth
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("hello", tc.kvs...)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
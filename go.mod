@@ -2,4 +2,4 @@ module github.com/iand/logfmtr
 
 go 1.15
 
-require github.com/go-logr/logr v1.3.0
+require github.com/go-logr/logr v1.4.2
@@ -0,0 +1,25 @@
+package logfmtr
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the id of the current goroutine, parsed from the header of its stack trace.
+// It returns 0 if the id cannot be determined.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
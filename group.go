@@ -0,0 +1,168 @@
+package logfmtr
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// groupDelim separates the names of nested groups in keys.
+const groupDelim = "."
+
+// groupKey is a key qualified by the names of the groups that enclose it. It is written as the group
+// names and key separated by dots in logfmt and human friendly output and as nested objects in JSON.
+type groupKey struct {
+	groups []string
+	key    interface{}
+}
+
+// String returns the group names and key separated by dots.
+func (k groupKey) String() string {
+	return strings.Join(k.groups, groupDelim) + groupDelim + rawString(k.key)
+}
+
+// keyName returns the name of a key that is a string or a string within groups. It reports false for
+// keys of any other type.
+func keyName(k interface{}) (string, bool) {
+	switch kk := k.(type) {
+	case string:
+		return kk, true
+	case groupKey:
+		if _, ok := kk.key.(string); ok {
+			return kk.String(), true
+		}
+	}
+	return "", false
+}
+
+// WithGroup returns a logger that qualifies the keys of all subsequent key/value pairs with the group
+// name, in the same way as slog.Logger.WithGroup. Keys are prefixed with the group name and a dot in
+// logfmt and human friendly output and pairs in the group are nested in an object in JSON. Pairs
+// already added by WithValues are not affected. Loggers not created by this package and empty names
+// return the logger unchanged.
+func WithGroup(l logr.Logger, name string) logr.Logger {
+	s, ok := l.GetSink().(*sink)
+	if !ok || name == "" {
+		return l
+	}
+	return l.WithSink(s.withGroup(name))
+}
+
+// withGroup returns a sink that qualifies the keys of all subsequent key/value pairs with the group name.
+func (l *sink) withGroup(name string) *sink {
+	return l.child(func(c *core) {
+		c.appendGroup(name)
+	})
+}
+
+func (c *core) appendGroup(name string) {
+	if name == "" {
+		return
+	}
+	// Limit capacity so that sibling loggers never share a backing array
+	c.groups = append(c.groups[:len(c.groups):len(c.groups)], name)
+}
+
+// groupValues returns kvs with keys qualified by the core's current groups. The key of an odd final
+// item is left unchanged.
+func (c *core) groupValues(kvs []interface{}) []interface{} {
+	if len(c.groups) == 0 || len(kvs) < 2 {
+		return kvs
+	}
+	grouped := make([]interface{}, len(kvs))
+	copy(grouped, kvs)
+	for i := 0; i+1 < len(grouped); i += 2 {
+		grouped[i] = groupKey{groups: c.groups, key: grouped[i]}
+	}
+	return grouped
+}
+
+// jsonGroup holds the fields of a JSON object, nesting fields whose keys are within groups in an
+// object for each group.
+type jsonGroup struct {
+	fields []jsonField
+	index  map[string]int // position of each nested group in fields
+}
+
+type jsonField struct {
+	key   interface{}
+	value interface{}
+	group *jsonGroup // set for nested groups
+}
+
+// appendJSONPairs appends the key/value pairs of the context followed by those of the values. Pairs
+// whose keys are within groups are written in nested objects, with the pairs of each group written in
+// the same object wherever they appear.
+func appendJSONPairs(b []byte, context, values []interface{}) []byte {
+	if !hasGroupKeys(context) && !hasGroupKeys(values) {
+		b = appendJSONKVs(b, context)
+		return appendJSONKVs(b, values)
+	}
+	root := &jsonGroup{}
+	root.add(context)
+	root.add(values)
+	return root.append(b)
+}
+
+// hasGroupKeys reports whether any key in kvs is within a group.
+func hasGroupKeys(kvs []interface{}) bool {
+	for i := 0; i < len(kvs); i += 2 {
+		if _, ok := kvs[i].(groupKey); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *jsonGroup) add(kvs []interface{}) {
+	for i := 0; i < len(kvs); i += 2 {
+		var v interface{}
+		if i+1 < len(kvs) {
+			v = kvs[i+1]
+		} else {
+			v = ""
+		}
+		gk, ok := kvs[i].(groupKey)
+		if !ok {
+			g.fields = append(g.fields, jsonField{key: kvs[i], value: v})
+			continue
+		}
+		sub := g
+		for _, name := range gk.groups {
+			sub = sub.group(name)
+		}
+		sub.fields = append(sub.fields, jsonField{key: gk.key, value: v})
+	}
+}
+
+// group returns the nested group with the given name, adding it if necessary.
+func (g *jsonGroup) group(name string) *jsonGroup {
+	if i, ok := g.index[name]; ok {
+		return g.fields[i].group
+	}
+	if g.index == nil {
+		g.index = make(map[string]int)
+	}
+	sub := &jsonGroup{}
+	g.index[name] = len(g.fields)
+	g.fields = append(g.fields, jsonField{key: name, group: sub})
+	return sub
+}
+
+// append appends the fields of the group, each preceded by a comma.
+func (g *jsonGroup) append(b []byte) []byte {
+	for _, f := range g.fields {
+		if f.group == nil {
+			b = appendJSONKV(b, f.key, f.value)
+			continue
+		}
+		b = append(b, ',')
+		b = appendJSONKey(b, rawString(f.key))
+		n := len(b)
+		b = f.group.append(b)
+		// the nested group always has a field, replace its leading comma to open the object
+		b[n] = '{'
+		b = append(b, '}')
+	}
+	return b
+}
@@ -0,0 +1,68 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestWithGroup(t *testing.T) {
+	testCases := []struct {
+		name    string
+		encoder logfmtr.Encoder
+		want    string
+	}{
+		{
+			name: "logfmt",
+			want: "level=0 msg=hello user=you req.id=7 req.method=GET odd=\n" +
+				"level=0 msg=hi user=you req.id=7 req.client.addr=127.0.0.1\n",
+		},
+		{
+			name:    "json",
+			encoder: &logfmtr.JSONEncoder{},
+			want: `{"level":0,"msg":"hello","user":"you","req":{"id":7,"method":"GET"},"odd":""}` + "\n" +
+				`{"level":0,"msg":"hi","user":"you","req":{"id":7,"client":{"addr":"127.0.0.1"}}}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.Encoder = tc.encoder
+			logger := logfmtr.NewWithOptions(opts).WithValues("user", "you")
+			req := logfmtr.WithGroup(logger, "req").WithValues("id", 7)
+
+			logfmtr.WithGroup(req, "").Info("hello", "method", "GET", "odd")
+			logfmtr.WithGroup(req, "client").Info("hi", "addr", "127.0.0.1")
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyPrefixFromName(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.KeyPrefixFromName = true
+	logger := logfmtr.NewWithOptions(opts).WithValues("pid", 1)
+
+	logger.Info("hello", "count", 1)
+	http := logger.WithName("http").WithValues("count", 2)
+	http.Info("request", "latency", 3)
+	http.WithName("client").Info("response", "latency", 4)
+
+	want := "level=0 msg=hello pid=1 count=1\n" +
+		"level=0 logger=http msg=request pid=1 http.count=2 http.latency=3\n" +
+		"level=0 logger=http.client msg=response pid=1 http.count=2 http.client.latency=4\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,28 @@
+package logfmtr
+
+// A Hook intercepts entries before they are encoded. Hooks can be used to redact, enrich, sample or
+// count entries.
+type Hook interface {
+	// Apply is called with each entry before it is encoded and may modify the entry. The Context
+	// and Values slices are shared with other entries so they must be replaced rather than modified
+	// in place. Apply returns false to prevent the entry from being written.
+	Apply(e *Entry) bool
+}
+
+// HookFunc is an adapter to allow the use of an ordinary function as a Hook.
+type HookFunc func(e *Entry) bool
+
+// Apply calls f(e).
+func (f HookFunc) Apply(e *Entry) bool {
+	return f(e)
+}
+
+// applyHooks runs each hook in turn against a copy of the entry, stopping if any hook vetoes it.
+func applyHooks(hooks []Hook, e Entry) (Entry, bool) {
+	for _, h := range hooks {
+		if !h.Apply(&e) {
+			return e, false
+		}
+	}
+	return e, true
+}
@@ -0,0 +1,78 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestHooks(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Hooks = []logfmtr.Hook{
+		// drop health checks
+		logfmtr.HookFunc(func(e *logfmtr.Entry) bool {
+			return e.Message != "health check"
+		}),
+		// redact passwords
+		logfmtr.HookFunc(func(e *logfmtr.Entry) bool {
+			values := make([]interface{}, len(e.Values))
+			copy(values, e.Values)
+			for i := 0; i+1 < len(values); i += 2 {
+				if values[i] == "password" {
+					values[i+1] = "[redacted]"
+				}
+			}
+			e.Values = values
+			return true
+		}),
+	}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("health check")
+	logger.Info("login", "user", "you", "password", "secret")
+
+	want := "level=0 msg=login user=you password=[redacted]\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+// forwardingHook counts error entries and the number of times it is flushed.
+type forwardingHook struct {
+	errors  int
+	flushes int
+}
+
+func (h *forwardingHook) Apply(e *logfmtr.Entry) bool {
+	if e.IsError {
+		h.errors++
+	}
+	return true
+}
+
+func (h *forwardingHook) Flush() error {
+	h.flushes++
+	return nil
+}
+
+func TestHookFlush(t *testing.T) {
+	hook := &forwardingHook{}
+	defer logfmtr.RegisterHook(hook)()
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = io.Discard
+	opts.Hooks = []logfmtr.Hook{hook}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Error(nil, "goodbye")
+	if err := logfmtr.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+	if hook.errors != 1 || hook.flushes != 1 {
+		t.Errorf("got %d errors and %d flushes, wanted 1 of each", hook.errors, hook.flushes)
+	}
+}
@@ -0,0 +1,249 @@
+package logfmtr
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+var _ Encoder = (*JSONEncoder)(nil)
+
+// JSONEncoder writes entries as newline delimited JSON objects.
+type JSONEncoder struct {
+	// TimestampFormat sets the format for log timestamps. Leave empty to disable timestamping.
+	TimestampFormat string
+
+	// TimestampMode selects how timestamps are written. The default writes timestamps using
+	// TimestampFormat. Other modes write timestamps as numbers even if TimestampFormat is empty.
+	TimestampMode TimestampMode
+
+	// Location is the time zone used for timestamps. When nil timestamps are written in UTC.
+	Location *time.Location
+
+	// LevelNames maps V levels to the names written in place of numeric levels. When set, entries
+	// written by Error are given the level name "error". Levels without a name are written as numbers.
+	LevelNames map[int]string
+
+	// FieldNames sets the keys used for built-in fields.
+	FieldNames FieldNames
+
+	// NilError selects whether the error field is omitted for entries written by Error with a nil
+	// error. Otherwise a nil error is written as null.
+	NilError NilErrorMode
+}
+
+// EncodeEntry writes the entry as a single line JSON object.
+func (enc *JSONEncoder) EncodeEntry(w io.Writer, e Entry) error {
+	return encodeEntry(w, enc, e)
+}
+
+func (enc *JSONEncoder) appendEntry(b []byte, e Entry) []byte {
+	names := enc.FieldNames.withDefaults()
+	b = append(b, '{')
+	b = appendJSONKey(b, names.Level)
+	if name, ok := levelName(enc.LevelNames, e); ok {
+		b = appendJSONString(b, name)
+	} else {
+		b = strconv.AppendInt(b, int64(e.Level), 10)
+	}
+	if e.Name != "" {
+		b = append(b, ',')
+		b = appendJSONKey(b, names.Logger)
+		b = appendJSONString(b, e.Name)
+	}
+	if enc.TimestampMode != TimestampFormatted {
+		b = append(b, ',')
+		b = appendJSONKey(b, names.Timestamp)
+		b = appendTimestamp(b, e.Time, enc.TimestampMode)
+	} else if enc.TimestampFormat != "" {
+		b = append(b, ',')
+		b = appendJSONKey(b, names.Timestamp)
+		b = appendJSONString(b, inLocation(e.Time, enc.Location).Format(enc.TimestampFormat))
+	}
+	b = append(b, ',')
+	b = appendJSONKey(b, names.Message)
+	b = appendJSONString(b, e.Message)
+	if e.Caller != "" {
+		b = append(b, ',')
+		b = appendJSONKey(b, names.Caller)
+		b = appendJSONString(b, e.Caller)
+	}
+	if enc.NilError.writeError(e) {
+		b = appendJSONKV(b, names.Error, e.Error)
+		key := names.Error
+		for _, err := range e.Causes {
+			key += causeSuffix
+			b = appendJSONKV(b, key, err)
+		}
+	}
+	if e.Stacktrace != "" {
+		b = append(b, ',')
+		b = appendJSONKey(b, names.Stacktrace)
+		b = appendJSONString(b, e.Stacktrace)
+	}
+	b = appendJSONPairs(b, e.Context, e.Values)
+	return append(b, "}\n"...)
+}
+
+func appendJSONKVs(b []byte, kvs []interface{}) []byte {
+	for i := 0; i < len(kvs); i += 2 {
+		var v interface{}
+		if i+1 < len(kvs) {
+			v = kvs[i+1]
+		} else {
+			v = ""
+		}
+		b = appendJSONKV(b, kvs[i], v)
+	}
+	return b
+}
+
+func appendJSONKV(b []byte, k, v interface{}) []byte {
+	b = append(b, ',')
+	b = appendJSONKey(b, rawString(k))
+	return appendJSONValue(b, v)
+}
+
+// appendJSONKey appends a quoted key followed by a colon.
+func appendJSONKey(b []byte, key string) []byte {
+	b = appendJSONString(b, key)
+	return append(b, ':')
+}
+
+func appendJSONValue(b []byte, v interface{}) []byte {
+	switch vv := v.(type) {
+	case nil:
+		return append(b, "null"...)
+	case string:
+		return appendJSONString(b, vv)
+	case bool:
+		return strconv.AppendBool(b, vv)
+	case int:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int8:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int16:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int32:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int64:
+		return strconv.AppendInt(b, vv, 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint8:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint16:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint64:
+		return strconv.AppendUint(b, vv, 10)
+	case float32:
+		return appendJSONFloat(b, float64(vv), 32)
+	case float64:
+		return appendJSONFloat(b, vv, 64)
+	case rawJSON:
+		return append(b, vv...)
+	case number:
+		if json.Valid([]byte(vv)) {
+			return append(b, vv...)
+		}
+		return appendJSONString(b, string(vv))
+	case json.Marshaler:
+		// handled by json.Marshal below
+	case error:
+		if nilPointer(vv) {
+			return append(b, "null"...)
+		}
+		return appendJSONString(b, vv.Error())
+	case interface{ String() string }:
+		if nilPointer(vv) {
+			return append(b, "null"...)
+		}
+		return appendJSONString(b, vv.String())
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return appendJSONString(b, rawString(v))
+	}
+	return append(b, data...)
+}
+
+// appendJSONFloat appends f using the same representation as encoding/json. Values that cannot be
+// represented in JSON are written as strings.
+func appendJSONFloat(b []byte, f float64, bits int) []byte {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return appendJSONString(b, strconv.FormatFloat(f, 'g', -1, bits))
+	}
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string. Unlike encoding/json it does not escape HTML characters.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,69 @@
+package logfmtr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// KeyMode selects how keys of key/value pairs that are not strings or contain characters that are not
+// permitted in logfmt keys, such as spaces, equals signs and quotes, are written in logfmt and human
+// friendly output.
+type KeyMode int
+
+const (
+	// KeyReplace converts keys to strings and replaces characters that are not permitted with
+	// underscores, so that a key of "user id" is written as user_id. An empty key is written as _.
+	KeyReplace KeyMode = iota
+
+	// KeyQuote converts keys to strings and quotes and escapes keys that are empty or contain
+	// characters that are not permitted, so that a key of "user id" is written as "user id". Not all
+	// logfmt parsers accept quoted keys.
+	KeyQuote
+
+	// KeyBadKey writes keys that are not strings, are empty or contain characters that are not
+	// permitted as !BADKEY, matching log/slog, so that the mistake is visible.
+	KeyBadKey
+)
+
+// String returns the name of the mode.
+func (m KeyMode) String() string {
+	switch m {
+	case KeyReplace:
+		return "replace"
+	case KeyQuote:
+		return "quote"
+	case KeyBadKey:
+		return "badkey"
+	default:
+		return fmt.Sprintf("KeyMode(%d)", int(m))
+	}
+}
+
+// key returns the text written for the key k.
+func (m KeyMode) key(k interface{}) string {
+	switch m {
+	case KeyQuote:
+		s := rawString(k)
+		if s == "" || !validKey(s) {
+			return strconv.Quote(s)
+		}
+		return s
+	case KeyBadKey:
+		if s, ok := keyName(k); ok && s != "" && validKey(s) {
+			return s
+		}
+		return badKey
+	default:
+		return sanitizeKey(rawString(k))
+	}
+}
+
+// validKey reports whether s contains only characters that are permitted in logfmt keys.
+func validKey(s string) bool {
+	for _, r := range s {
+		if !validRune(r) {
+			return false
+		}
+	}
+	return true
+}
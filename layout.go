@@ -0,0 +1,94 @@
+package logfmtr
+
+import "fmt"
+
+// Field identifies a part of an entry whose position in logfmt output is set by a layout.
+type Field int
+
+const (
+	// FieldLevel is the level of the entry.
+	FieldLevel Field = iota + 1
+
+	// FieldLogger is the name of the logger.
+	FieldLogger
+
+	// FieldTimestamp is the time of the entry.
+	FieldTimestamp
+
+	// FieldMessage is the log message.
+	FieldMessage
+
+	// FieldCaller is the file and line number of the origin of the entry.
+	FieldCaller
+
+	// FieldError is the error passed to Error, followed by any causes.
+	FieldError
+
+	// FieldStacktrace is the stack trace of the origin of the entry.
+	FieldStacktrace
+
+	// FieldContext is the key/value pairs added by WithValues and DefaultFields.
+	FieldContext
+
+	// FieldValues is the key/value pairs passed with the message.
+	FieldValues
+)
+
+// String returns the name of the field.
+func (f Field) String() string {
+	switch f {
+	case FieldLevel:
+		return "level"
+	case FieldLogger:
+		return "logger"
+	case FieldTimestamp:
+		return "timestamp"
+	case FieldMessage:
+		return "message"
+	case FieldCaller:
+		return "caller"
+	case FieldError:
+		return "error"
+	case FieldStacktrace:
+		return "stacktrace"
+	case FieldContext:
+		return "context"
+	case FieldValues:
+		return "values"
+	default:
+		return fmt.Sprintf("Field(%d)", int(f))
+	}
+}
+
+// defaultLayout is the order in which fields are written when no layout is set.
+var defaultLayout = []Field{
+	FieldLevel,
+	FieldLogger,
+	FieldTimestamp,
+	FieldMessage,
+	FieldCaller,
+	FieldError,
+	FieldStacktrace,
+	FieldContext,
+	FieldValues,
+}
+
+// DefaultLayout returns the order in which fields are written when no layout is set.
+func DefaultLayout() []Field {
+	return append([]Field(nil), defaultLayout...)
+}
+
+// validateLayout reports an error if the layout contains an unknown or repeated field.
+func validateLayout(layout []Field) error {
+	seen := map[Field]bool{}
+	for _, f := range layout {
+		if f < FieldLevel || f > FieldValues {
+			return fmt.Errorf("unknown field %d", int(f))
+		}
+		if seen[f] {
+			return fmt.Errorf("field %s is repeated", f)
+		}
+		seen[f] = true
+	}
+	return nil
+}
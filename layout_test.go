@@ -0,0 +1,60 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestLayout(t *testing.T) {
+	testCases := []struct {
+		name   string
+		layout []logfmtr.Field
+		want   string
+	}{
+		{
+			name:   "default",
+			layout: logfmtr.DefaultLayout(),
+			want:   "level=0 logger=europa ts=2024-01-02T03:04:05Z msg=goodbye error=failed user=you val=1\n",
+		},
+		{
+			name: "timestamp first and message last",
+			layout: []logfmtr.Field{
+				logfmtr.FieldTimestamp,
+				logfmtr.FieldLevel,
+				logfmtr.FieldLogger,
+				logfmtr.FieldValues,
+				logfmtr.FieldContext,
+				logfmtr.FieldError,
+				logfmtr.FieldMessage,
+			},
+			want: "ts=2024-01-02T03:04:05Z level=0 logger=europa val=1 user=you error=failed msg=goodbye\n",
+		},
+		{
+			name:   "omitted fields",
+			layout: []logfmtr.Field{logfmtr.FieldLogger, logfmtr.FieldMessage},
+			want:   "logger=europa msg=goodbye\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = time.RFC3339
+			opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+			opts.Layout = tc.layout
+			logger := logfmtr.NewWithOptions(opts).WithName("europa").WithValues("user", "you")
+
+			logger.Error(errors.New("failed"), "goodbye", "val", 1)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package logfmtr
+
+// Lazy is a value of a key/value pair that is computed only when an entry containing it is written.
+// Wrapping an expensive value in Lazy avoids computing it for entries that are not written because
+// their V level is disabled or they are dropped by a filter, sampler or hook:
+//
+//	logger.V(2).Info("request", "body", logfmtr.Lazy(func() interface{} { return dump(req) }))
+//
+// The function is called once for each entry that is written, so a Lazy value passed to WithValues is
+// computed again for every entry. Hooks see the Lazy value itself rather than its result.
+type Lazy func() interface{}
+
+// resolveLazy returns the entry with each Lazy value of its key/value pairs replaced by the result of
+// calling it. The key/value slices are copied before they are changed since they may be shared with
+// other entries.
+func resolveLazy(e Entry) Entry {
+	e.Context = mapValues(e.Context, resolveValue)
+	e.Values = mapValues(e.Values, resolveValue)
+	return e
+}
+
+func resolveValue(v interface{}) (interface{}, bool) {
+	if l, ok := v.(Lazy); ok {
+		if l == nil {
+			return nil, true
+		}
+		return l(), true
+	}
+	return v, false
+}
+
+// lazyPlaceholder stands in for a Lazy value where a value is needed without computing it.
+const lazyPlaceholder = "\x00lazy"
+
+func placeholdLazy(v interface{}) (interface{}, bool) {
+	if _, ok := v.(Lazy); ok {
+		return lazyPlaceholder, true
+	}
+	return v, false
+}
@@ -0,0 +1,77 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestLazy(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+
+	calls := 0
+	expensive := logfmtr.Lazy(func() interface{} {
+		calls++
+		return calls
+	})
+
+	var buf, extra bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Outputs = []logfmtr.Output{{Writer: &extra, Encoder: &logfmtr.JSONEncoder{}}}
+	opts.Hooks = []logfmtr.Hook{logfmtr.HookFunc(func(e *logfmtr.Entry) bool { return e.Message != "dropped" })}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.V(1).Info("disabled", "n", expensive)
+	logger.Info("dropped", "n", expensive)
+	if calls != 0 {
+		t.Fatalf("got %d calls for entries that were not written, wanted 0", calls)
+	}
+
+	logger.Info("hello", "n", expensive)
+	logger.WithValues("n", expensive).Info("again")
+	logger.Info("nil", "n", logfmtr.Lazy(nil))
+
+	if want := "level=0 msg=hello n=1\nlevel=0 msg=again n=2\nlevel=0 msg=nil n=<nil>\n"; buf.String() != want {
+		t.Errorf("got %q, wanted %q", buf.String(), want)
+	}
+	if want := `{"level":0,"msg":"hello","n":1}` + "\n" + `{"level":0,"msg":"again","n":2}` + "\n" + `{"level":0,"msg":"nil","n":null}` + "\n"; extra.String() != want {
+		t.Errorf("got output %q, wanted %q", extra.String(), want)
+	}
+}
+
+func TestLazyCoalesced(t *testing.T) {
+	calls := 0
+	expensive := logfmtr.Lazy(func() interface{} {
+		calls++
+		return calls
+	})
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Coalescer = logfmtr.NewCoalescer(time.Hour)
+	defer opts.Coalescer.Close()
+	logger := logfmtr.NewWithOptions(opts)
+
+	for i := 0; i < 3; i++ {
+		logger.Info("hello", "n", expensive)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls before held entries were written, wanted 1", calls)
+	}
+	if err := opts.Coalescer.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, wanted 2", calls)
+	}
+
+	if want := "level=0 msg=hello n=1\nlevel=0 msg=hello n=2 repeated=2\n"; buf.String() != want {
+		t.Errorf("got %q, wanted %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,158 @@
+package logfmtr
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"sync"
+)
+
+var (
+	registryMu sync.Mutex
+	registryID int
+	writers    []registered // writers registered to be flushed and closed, in order of registration
+	pending    []registered // holders of entries that have not yet been written, flushed before writers
+)
+
+type flusher interface {
+	Flush() error
+}
+
+// registered is a writer or holder of pending entries recorded by RegisterWriter or RegisterHook.
+type registered struct {
+	id int
+	v  interface{}
+}
+
+// RegisterWriter records w so that it is flushed by Flush and flushed and closed by Close. Writers are
+// flushed if they have a Flush or Sync method and closed if they implement io.Closer, except for
+// os.Stdout and os.Stderr. Writers created by this package, such as AsyncWriter and FileWriter, are
+// registered when they are created and unregistered when they are closed; other writers used by
+// loggers must be registered explicitly to be reached by Flush and Close. RegisterWriter returns a
+// function that removes the registration.
+func RegisterWriter(w io.Writer) (unregister func()) {
+	return register(&writers, w)
+}
+
+// RegisterHook records a hook that has a Flush method, such as one that sends entries in batches, so
+// that it is flushed by Flush and Close. RegisterHook returns a function that removes the
+// registration.
+func RegisterHook(h Hook) (unregister func()) {
+	return register(&pending, h)
+}
+
+// register adds v to list, unless it is already present, and returns a function that removes it.
+func register(list *[]registered, v interface{}) func() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if reflect.TypeOf(v).Comparable() {
+		for _, r := range *list {
+			if r.v == v {
+				return unregisterFunc(list, r.id)
+			}
+		}
+	}
+	registryID++
+	*list = append(*list, registered{id: registryID, v: v})
+	return unregisterFunc(list, registryID)
+}
+
+func unregisterFunc(list *[]registered, id int) func() {
+	return func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		for i, r := range *list {
+			if r.id == id {
+				*list = append((*list)[:i:i], (*list)[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Flush flushes any buffered entries held by registered writers and hooks. Writers are flushed if they
+// have a Flush or Sync method. Flush waits for queued entries of an AsyncWriter to be written and
+// writes any repeated entries held by a Coalescer. It returns the first error encountered.
+func Flush() error {
+	registryMu.Lock()
+	ps, ws := pending, writers
+	registryMu.Unlock()
+
+	firstErr := flushPending(ps)
+	for _, r := range ws {
+		if err := flushWriter(r.v.(io.Writer)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close flushes registered writers and hooks and then closes any writers that implement io.Closer,
+// except for os.Stdout and os.Stderr, and removes all registrations. It should be called before the
+// program exits to avoid losing buffered entries. Loggers should not be used after Close has been
+// called. It returns the first error encountered.
+func Close() error {
+	registryMu.Lock()
+	ps, ws := pending, writers
+	pending, writers = nil, nil
+	registryMu.Unlock()
+
+	firstErr := flushPending(ps)
+	for _, r := range ws {
+		w := r.v.(io.Writer)
+		if err := flushWriter(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if isStdStream(w) {
+			continue
+		}
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// flushPending flushes holders of pending entries.
+func flushPending(ps []registered) error {
+	var firstErr error
+	for _, r := range ps {
+		f, ok := r.v.(flusher)
+		if !ok {
+			continue
+		}
+		if err := f.Flush(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// flush flushes the writers of the core, which may not be registered.
+func (c *core) flush() {
+	_ = flushWriter(c.w)
+	_ = flushWriter(c.errw)
+	for _, out := range c.outputs {
+		_ = flushWriter(out.Writer)
+	}
+}
+
+func flushWriter(w io.Writer) error {
+	switch fw := w.(type) {
+	case flusher:
+		return fw.Flush()
+	case interface{ Sync() error }:
+		if isStdStream(w) {
+			// syncing a terminal or pipe is not supported on all platforms
+			return nil
+		}
+		return fw.Sync()
+	}
+	return nil
+}
+
+func isStdStream(w io.Writer) bool {
+	return w == io.Writer(os.Stdout) || w == io.Writer(os.Stderr)
+}
@@ -0,0 +1,79 @@
+package logfmtr_test
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestFlushAndClose(t *testing.T) {
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	logfmtr.RegisterWriter(bw)
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = bw
+	opts.TimestampFormat = ""
+	logfmtr.NewWithOptions(opts).Info("buffered")
+
+	var cr closeRecorder
+	aw := logfmtr.NewAsyncWriter(&cr, 10)
+	opts.Writer = aw
+	logfmtr.NewWithOptions(opts).Info("queued")
+
+	if err := logfmtr.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+	if got, want := out.String(), "level=0 msg=buffered\n"; got != want {
+		t.Errorf("got %q after flush, wanted %q", got, want)
+	}
+	if got, want := cr.String(), "level=0 msg=queued\n"; got != want {
+		t.Errorf("got %q after flush, wanted %q", got, want)
+	}
+
+	if err := logfmtr.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if !cr.closed {
+		t.Errorf("writer underlying async writer was not closed")
+	}
+}
+
+func TestCloseUnregisteredWriter(t *testing.T) {
+	var cr closeRecorder
+	logfmtr.NewWith(logfmtr.WithWriter(&cr)).Info("hello")
+
+	if err := logfmtr.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if cr.closed {
+		t.Errorf("writer that was not registered was closed")
+	}
+}
+
+func TestUnregisterWriter(t *testing.T) {
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	unregister := logfmtr.RegisterWriter(bw)
+	logfmtr.NewWith(logfmtr.WithWriter(bw), logfmtr.WithTimestampFormat("")).Info("buffered")
+
+	unregister()
+	if err := logfmtr.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("got %q written by flush, wanted nothing after unregistering", out.String())
+	}
+}
@@ -0,0 +1,93 @@
+package logfmtr
+
+// fieldsDroppedKey is the key of the field added to entries that had key/value pairs removed to keep
+// within the MaxFields or MaxEntryBytes limits.
+const fieldsDroppedKey = "fields_dropped"
+
+// countPairs returns the number of key/value pairs in the entry's context and values.
+func countPairs(e Entry) int {
+	return (len(e.Context)+1)/2 + (len(e.Values)+1)/2
+}
+
+// limitPairs returns the entry with only the first n key/value pairs of its context and values, which
+// are kept in preference to the values passed with the message. The slices are not modified.
+func limitPairs(e Entry, n int) Entry {
+	nc := (len(e.Context) + 1) / 2
+	if n < nc {
+		e.Context = e.Context[:2*n]
+		e.Values = nil
+		return e
+	}
+	n -= nc
+	if 2*n < len(e.Values) {
+		e.Values = e.Values[:2*n]
+	}
+	return e
+}
+
+// annotateDropped returns the entry with a field recording the number of key/value pairs that were dropped.
+func annotateDropped(e Entry, dropped int) Entry {
+	if dropped > 0 {
+		e.Values = append(e.Values[:len(e.Values):len(e.Values)], fieldsDroppedKey, dropped)
+	}
+	return e
+}
+
+// trialEncoder is implemented by encoders that record state as entries are encoded, such as the time
+// of the previous entry.
+type trialEncoder interface {
+	// trial returns an encoder that encodes entries as the encoder would without changing the
+	// encoder's state, or nil if the encoder records no state.
+	trial() Encoder
+}
+
+// encodeLimited encodes the entry into buf using enc, removing key/value pairs from the end of the entry
+// until the encoded entry is no longer than max bytes. The number of pairs removed, along with the number
+// already dropped, is recorded in the entry. If the entry is too long without any pairs it is encoded
+// without them. A max of zero or less means that the size of the entry is not limited. Encoders that
+// record state are measured using a trial encoder so that only the entry written changes their state.
+func encodeLimited(buf *buffer, enc Encoder, e Entry, dropped int, max int) error {
+	if max <= 0 {
+		return encodeInto(buf, enc, annotateDropped(e, dropped))
+	}
+	measure, stateful := enc, false
+	if te, ok := enc.(trialEncoder); ok {
+		if t := te.trial(); t != nil {
+			measure, stateful = t, true
+		}
+	}
+
+	n := countPairs(e)
+	if err := encodeInto(buf, measure, annotateDropped(e, dropped)); err != nil {
+		return err
+	}
+	if len(buf.b) > max {
+		// find the largest number of pairs that fit, knowing that all of them do not
+		lo, hi := 0, n
+		for lo < hi-1 {
+			mid := (lo + hi) / 2
+			if err := encodeInto(buf, measure, annotateDropped(limitPairs(e, mid), dropped+n-mid)); err != nil {
+				return err
+			}
+			if len(buf.b) <= max {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		e, dropped = limitPairs(e, lo), dropped+n-lo
+	} else if !stateful {
+		return nil
+	}
+	return encodeInto(buf, enc, annotateDropped(e, dropped))
+}
+
+// encodeInto replaces the contents of buf with the entry encoded using enc.
+func encodeInto(buf *buffer, enc Encoder, e Entry) error {
+	buf.b = buf.b[:0]
+	if app, ok := enc.(entryAppender); ok {
+		buf.b = app.appendEntry(buf.b, e)
+		return nil
+	}
+	return enc.EncodeEntry(buf, e)
+}
@@ -0,0 +1,108 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestEntryLimits(t *testing.T) {
+	testCases := []struct {
+		name string
+		fn   func(*logfmtr.Options)
+		want string
+	}{
+		{
+			name: "no limits",
+			fn:   func(o *logfmtr.Options) {},
+			want: "level=0 msg=hello request=r1 a=aaaaaaaaaa b=bbbbbbbbbb c=cccccccccc\n",
+		},
+		{
+			name: "max fields",
+			fn:   func(o *logfmtr.Options) { o.MaxFields = 2 },
+			want: "level=0 msg=hello request=r1 a=aaaaaaaaaa fields_dropped=2\n",
+		},
+		{
+			name: "max fields keeps context",
+			fn:   func(o *logfmtr.Options) { o.MaxFields = 1 },
+			want: "level=0 msg=hello request=r1 fields_dropped=3\n",
+		},
+		{
+			name: "max entry bytes",
+			fn:   func(o *logfmtr.Options) { o.MaxEntryBytes = 60 },
+			want: "level=0 msg=hello request=r1 a=aaaaaaaaaa fields_dropped=2\n",
+		},
+		{
+			name: "max entry bytes and max fields",
+			fn:   func(o *logfmtr.Options) { o.MaxFields = 3; o.MaxEntryBytes = 50 },
+			want: "level=0 msg=hello request=r1 fields_dropped=3\n",
+		},
+		{
+			name: "max entry bytes too small",
+			fn:   func(o *logfmtr.Options) { o.MaxEntryBytes = 10 },
+			want: "level=0 msg=hello fields_dropped=4\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			tc.fn(&opts)
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.WithValues("request", "r1").Info("hello", "a", "aaaaaaaaaa", "b", "bbbbbbbbbb", "c", "cccccccccc")
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMaxEntryBytesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithEncoder(&logfmtr.JSONEncoder{}),
+		logfmtr.WithMaxEntryBytes(100),
+	)
+	logger.Info("hello", "small", "x", "body", strings.Repeat("y", 200))
+
+	want := `{"level":0,"msg":"hello","small":"x","fields_dropped":1}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestMaxEntryBytesHumanState(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	var buf bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithClock(clock),
+		logfmtr.WithEncoder(&logfmtr.HumanEncoder{TimeMode: logfmtr.HumanTimeDelta, AlignKeys: true, MessageWidth: 5}),
+		logfmtr.WithMaxEntryBytes(60),
+	)
+	logger.Info("hello", "a", "x")
+	logger.Info("hello", "a", "x", "body", strings.Repeat("y", 100))
+	logger.Info("hello", "a", "x", "body", "z", "b", "w")
+
+	// the first entry shows the time since the program started
+	lines := strings.SplitAfterN(buf.String(), "\n", 2)
+	want := "0 info  | +1.00s   | hello a=x fields_dropped=1\n" +
+		"0 info  | +1.00s   | hello a=x body=z b=w\n"
+	if len(lines) != 2 || lines[1] != want {
+		t.Errorf("got %q, wanted %q to follow the first entry", buf.String(), want)
+	}
+}
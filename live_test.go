@@ -0,0 +1,45 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestLiveOptions(t *testing.T) {
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+	defer logfmtr.SetLiveOptions(false)
+
+	var buf1, buf2 bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf1
+	opts.TimestampFormat = ""
+	logfmtr.UseOptions(opts)
+
+	logger := logfmtr.NewNamed("europa").WithValues("user", "you")
+	logger.Info("one")
+
+	// without live options the logger keeps its original options
+	opts.Writer = &buf2
+	logfmtr.UseOptions(opts)
+	logger.Info("two")
+
+	logfmtr.SetLiveOptions(true)
+	logger.Info("three")
+
+	opts.Encoder = &logfmtr.JSONEncoder{}
+	logfmtr.UseOptions(opts)
+	logger.WithName("moon").Info("four")
+
+	want1 := "level=0 logger=europa msg=one user=you\n" +
+		"level=0 logger=europa msg=two user=you\n"
+	if got := buf1.String(); got != want1 {
+		t.Errorf("got %q, wanted %q", got, want1)
+	}
+	want2 := "level=0 logger=europa msg=three user=you\n" +
+		`{"level":0,"logger":"europa.moon","msg":"four","user":"you"}` + "\n"
+	if got := buf2.String(); got != want2 {
+		t.Errorf("got %q, wanted %q", got, want2)
+	}
+}
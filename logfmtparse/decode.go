@@ -0,0 +1,140 @@
+// Package logfmtparse decodes lines written in logfmt style, such as those written by logfmtr. Each
+// line is a sequence of key=value pairs separated by spaces. Values containing spaces, quotes, equals
+// signs or non-printable characters are quoted using Go string literal syntax. A key with no equals
+// sign or with nothing following the equals sign has an empty value.
+package logfmtparse
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// A Pair is a single key/value pair decoded from a line.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// A SyntaxError describes a line that could not be decoded.
+type SyntaxError struct {
+	// Line is the number of the line containing the error, counting from 1. It is zero for errors
+	// returned by Decode and DecodePairs.
+	Line int
+
+	// Offset is the byte offset within the line at which the error was detected.
+	Offset int
+
+	msg string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line > 0 {
+		return "logfmt syntax error on line " + strconv.Itoa(e.Line) + " at offset " + strconv.Itoa(e.Offset) + ": " + e.msg
+	}
+	return "logfmt syntax error at offset " + strconv.Itoa(e.Offset) + ": " + e.msg
+}
+
+// Decode decodes the key/value pairs in a line into a map. When a key occurs more than once the last
+// value is used.
+func Decode(line string) (map[string]string, error) {
+	pairs, err := DecodePairs(line)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		m[p.Key] = p.Value
+	}
+	return m, nil
+}
+
+// DecodePairs decodes the key/value pairs in a line, preserving their order and any repeated keys.
+func DecodePairs(line string) ([]Pair, error) {
+	var pairs []Pair
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return pairs, nil
+		}
+
+		start := i
+		for i < len(line) && !isSpace(line[i]) && line[i] != '=' && line[i] != '"' {
+			i++
+		}
+		if i < len(line) && line[i] == '"' {
+			return nil, syntaxError(i, "unexpected quote in key")
+		}
+		if i == start {
+			return nil, syntaxError(i, "missing key")
+		}
+		key := line[start:i]
+		if i >= len(line) || isSpace(line[i]) {
+			pairs = append(pairs, Pair{Key: key})
+			continue
+		}
+
+		// skip the equals sign
+		i++
+		if i >= len(line) || isSpace(line[i]) {
+			pairs = append(pairs, Pair{Key: key})
+			continue
+		}
+
+		if line[i] == '"' {
+			end, err := quotedEnd(line, i)
+			if err != nil {
+				return nil, err
+			}
+			value, err := strconv.Unquote(line[i:end])
+			if err != nil {
+				return nil, syntaxError(i, "invalid quoted value")
+			}
+			if end < len(line) && !isSpace(line[end]) {
+				return nil, syntaxError(end, "missing space after quoted value")
+			}
+			pairs = append(pairs, Pair{Key: key, Value: value})
+			i = end
+			continue
+		}
+
+		start = i
+		for i < len(line) && !isSpace(line[i]) {
+			switch line[i] {
+			case '"':
+				return nil, syntaxError(i, "unexpected quote in value")
+			case '=':
+				return nil, syntaxError(i, "unexpected equals sign in value")
+			}
+			i++
+		}
+		value := line[start:i]
+		if !utf8.ValidString(value) {
+			return nil, syntaxError(start, "invalid UTF-8 in value")
+		}
+		pairs = append(pairs, Pair{Key: key, Value: value})
+	}
+}
+
+// quotedEnd returns the offset just beyond the closing quote of the quoted string starting at offset i.
+func quotedEnd(line string, i int) (int, error) {
+	for j := i + 1; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		}
+	}
+	return 0, syntaxError(i, "unterminated quoted value")
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func syntaxError(offset int, msg string) error {
+	return &SyntaxError{Offset: offset, msg: msg}
+}
@@ -0,0 +1,97 @@
+package logfmtparse_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtparse"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		line string
+		want map[string]string
+	}{
+		{line: "", want: map[string]string{}},
+		{line: "a=1 b=two", want: map[string]string{"a": "1", "b": "two"}},
+		{line: "  a=1\tb=2  ", want: map[string]string{"a": "1", "b": "2"}},
+		{line: `msg="hello world" error="uh \"oh\"\n"`, want: map[string]string{"msg": "hello world", "error": "uh \"oh\"\n"}},
+		{line: "flag empty= x=1", want: map[string]string{"flag": "", "empty": "", "x": "1"}},
+		{line: "a=1 a=2", want: map[string]string{"a": "2"}},
+		{line: `a="" b=é`, want: map[string]string{"a": "", "b": "é"}},
+	}
+
+	for _, tc := range testCases {
+		got, err := logfmtparse.Decode(tc.line)
+		if err != nil {
+			t.Errorf("Decode(%q): unexpected error: %v", tc.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Decode(%q): got %v, wanted %v", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	testCases := []struct {
+		line   string
+		offset int
+	}{
+		{line: "=1", offset: 0},
+		{line: `a="unterminated`, offset: 2},
+		{line: `a="bad \q escape"`, offset: 2},
+		{line: `a="x"b=1`, offset: 5},
+		{line: `"a"=1`, offset: 0},
+		{line: `a=x"y`, offset: 3},
+		{line: "a=x=y", offset: 3},
+		{line: "a=\xff", offset: 2},
+	}
+
+	for _, tc := range testCases {
+		_, err := logfmtparse.Decode(tc.line)
+		var se *logfmtparse.SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("Decode(%q): got error %v, wanted syntax error", tc.line, err)
+			continue
+		}
+		if se.Offset != tc.offset {
+			t.Errorf("Decode(%q): got offset %d, wanted %d", tc.line, se.Offset, tc.offset)
+		}
+	}
+}
+
+func TestDecodeLogfmtrOutput(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	logger := logfmtr.NewWithOptions(opts).WithName("europa")
+
+	logger.Info("hello world", "path", "/a b", "quote", `say "hi"`, "eq", "a=b", "val", 1, "", "x")
+
+	got, err := logfmtparse.DecodePairs(strings.TrimSuffix(buf.String(), "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 9 || got[2].Key != "ts" {
+		t.Fatalf("got %v, wanted 9 pairs including ts", got)
+	}
+	got = append(got[:2], got[3:]...)
+	want := []logfmtparse.Pair{
+		{Key: "level", Value: "0"},
+		{Key: "logger", Value: "europa"},
+		{Key: "msg", Value: "hello world"},
+		{Key: "path", Value: "/a b"},
+		{Key: "quote", Value: `say "hi"`},
+		{Key: "eq", Value: "a=b"},
+		{Key: "val", Value: "1"},
+		{Key: "_", Value: "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
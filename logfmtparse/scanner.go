@@ -0,0 +1,84 @@
+package logfmtparse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// maxLineSize is the maximum length of a line read by a Scanner.
+const maxLineSize = 1 << 20
+
+// Scanner reads logfmt lines from a reader and decodes them one at a time. Blank lines are skipped.
+// Lines longer than 1MiB cause scanning to stop with bufio.ErrTooLong.
+type Scanner struct {
+	s     *bufio.Scanner
+	line  int
+	text  string
+	pairs []Pair
+	err   error
+}
+
+// NewScanner returns a Scanner that reads from r.
+func NewScanner(r io.Reader) *Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineSize)
+	return &Scanner{s: s}
+}
+
+// Scan advances the scanner to the next non-blank line and decodes it. It returns false when there
+// are no more lines or when a line could not be decoded, after which Err reports any error.
+func (s *Scanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	for s.s.Scan() {
+		s.line++
+		text := strings.TrimSuffix(s.s.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		pairs, err := DecodePairs(text)
+		if err != nil {
+			var se *SyntaxError
+			if errors.As(err, &se) {
+				se.Line = s.line
+			}
+			s.err = err
+			s.text, s.pairs = text, nil
+			return false
+		}
+		s.text, s.pairs = text, pairs
+		return true
+	}
+	s.err = s.s.Err()
+	s.text, s.pairs = "", nil
+	return false
+}
+
+// Err returns the first error encountered by the scanner. It returns nil if the end of the input
+// was reached without error.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Text returns the most recently scanned line.
+func (s *Scanner) Text() string {
+	return s.text
+}
+
+// Pairs returns the key/value pairs of the most recently scanned line in the order they appear.
+func (s *Scanner) Pairs() []Pair {
+	return s.pairs
+}
+
+// Fields returns the key/value pairs of the most recently scanned line as a map. When a key occurs
+// more than once the last value is used.
+func (s *Scanner) Fields() map[string]string {
+	m := make(map[string]string, len(s.pairs))
+	for _, p := range s.pairs {
+		m[p.Key] = p.Value
+	}
+	return m
+}
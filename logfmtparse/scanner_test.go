@@ -0,0 +1,54 @@
+package logfmtparse_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr/logfmtparse"
+)
+
+func TestScanner(t *testing.T) {
+	input := "level=0 msg=hello\r\n\n   \nlevel=1 msg=\"second line\" val=2\nlevel=0 msg=\"broken\nlevel=0 msg=unreached\n"
+	s := logfmtparse.NewScanner(strings.NewReader(input))
+
+	var got []map[string]string
+	for s.Scan() {
+		got = append(got, s.Fields())
+	}
+	want := []map[string]string{
+		{"level": "0", "msg": "hello"},
+		{"level": "1", "msg": "second line", "val": "2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+
+	var se *logfmtparse.SyntaxError
+	if !errors.As(s.Err(), &se) {
+		t.Fatalf("got error %v, wanted syntax error", s.Err())
+	}
+	if se.Line != 5 {
+		t.Errorf("got error on line %d, wanted line 5", se.Line)
+	}
+	if s.Text() != `level=0 msg="broken` {
+		t.Errorf("got text %q, wanted the line in error", s.Text())
+	}
+}
+
+func TestScannerEOF(t *testing.T) {
+	s := logfmtparse.NewScanner(strings.NewReader("a=1"))
+	if !s.Scan() {
+		t.Fatalf("unexpected end of scan: %v", s.Err())
+	}
+	if got := s.Pairs(); !reflect.DeepEqual(got, []logfmtparse.Pair{{Key: "a", Value: "1"}}) {
+		t.Errorf("got %v, wanted a=1", got)
+	}
+	if s.Scan() {
+		t.Errorf("got additional line %q", s.Text())
+	}
+	if s.Err() != nil {
+		t.Errorf("unexpected error: %v", s.Err())
+	}
+}
@@ -1,13 +1,11 @@
 package logfmtr
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
-	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,28 +20,73 @@ var gv int32 = 0
 // SetVerbosity sets the global log level. Only loggers with a V level less than
 // or equal to this value will be enabled.
 func SetVerbosity(v int) int {
-	old := atomic.SwapInt32(&gv, int32(v))
-	return int(old)
+	return changeVerbosity(func(int32) int32 { return int32(v) })
+}
+
+// adjustVerbosity adds delta to the global log level, to a minimum of zero, and returns the new level.
+func adjustVerbosity(delta int) int {
+	var next int32
+	changeVerbosity(func(v int32) int32 {
+		next = v + int32(delta)
+		if next < 0 {
+			next = 0
+		}
+		return next
+	})
+	return int(next)
+}
+
+// Verbosity returns the global log level set by SetVerbosity.
+func Verbosity() int {
+	return int(atomic.LoadInt32(&gv))
 }
 
 var (
-	goptionsmu sync.Mutex
-	goptions   = DefaultOptions()
+	goptionsmu  sync.Mutex
+	goptions    = DefaultOptions()
+	optionsGen  uint32 // incremented each time the global options are changed, guarded by goptionsmu for writes
+	liveOptions int32  // an atomicly accessed variable that is set to 1 if loggers should be reconfigured when options change
 
 	disabledLoggersMu sync.Mutex // synchronises writes to disabledLoggers map
 	disabledLoggers   atomic.Value
 	anyDisabled       int32 = 0 // an atomicly accessed variable that is set to 1 if there are any loggers that have been manually disabled
+
+	loggerOptionsMu sync.Mutex // synchronises writes to loggerOptions map
+	loggerOptions   atomic.Value
+	anyOptions      int32 = 0 // an atomicly accessed variable that is set to 1 if there are any per-logger options
+
+	loggerVerbosityMu sync.Mutex // synchronises writes to loggerVerbosity map
+	loggerVerbosity   atomic.Value
+	anyVerbosity      int32 = 0 // an atomicly accessed variable that is set to 1 if there are any per-logger verbosity overrides
 )
 
 func init() {
-	disabledLoggers.Store(map[string]bool{})
+	disabledLoggers.Store(&loggerSet{})
+	loggerVerbosity.Store(map[string]int{})
+	loggerOptions.Store(map[string]Options{})
 }
 
-// UseOptions sets options that new loggers will use when it they are instantiated.
+// UseOptions sets options that new loggers will use when it they are instantiated. If live options have
+// been enabled by SetLiveOptions then loggers that have already been instantiated also start using the
+// options.
 func UseOptions(opts Options) {
-	goptionsmu.Lock()
-	goptions = opts
-	goptionsmu.Unlock()
+	_ = changeOptions(func(o *Options) error {
+		*o = opts
+		return nil
+	})
+}
+
+// SetLiveOptions sets whether loggers created by New and NewNamed, and their children, are reconfigured
+// when UseOptions is called after they have been instantiated. It is disabled by default. When enabled,
+// each entry written checks whether the options have changed, which adds a small cost per entry that
+// grows with the number of WithName and WithValues calls used to create the logger. Loggers created by
+// NewWithOptions are never reconfigured.
+func SetLiveOptions(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&liveOptions, 1)
+	} else {
+		atomic.StoreInt32(&liveOptions, 0)
+	}
 }
 
 // New returns a deferred logger that writes in logfmt using the default options.
@@ -67,6 +110,15 @@ func NewWithOptions(opts Options) logr.Logger {
 	return logr.New(s)
 }
 
+// NewWithOptionsE returns an instantiated logger that writes in logfmt using the supplied options. It returns
+// an error if the options are not valid, as reported by Options.Validate.
+func NewWithOptionsE(opts Options) (logr.Logger, error) {
+	if err := opts.Validate(); err != nil {
+		return logr.Discard(), err
+	}
+	return NewWithOptions(opts), nil
+}
+
 // DefaultOptions returns the default options used by New unless overridden by a call to UseOptions.
 // Override the option field to customise behaviour and then pass to UseOptions or NewWithOptions.
 func DefaultOptions() Options {
@@ -79,28 +131,485 @@ func DefaultOptions() Options {
 
 // Options contains fields and flags for customizing logger behaviour
 type Options struct {
-	// Writer is where logs will be written to
+	// Writer is where logs will be written to. Each entry is written in a single call to Write, or to
+	// WriteLevel if the writer is a LevelWriter, so entries from concurrent loggers do not interleave
+	// when the writer's writes are atomic. See SerializeWrites for writers whose writes are not.
 	Writer io.Writer
 
+	// ErrorWriter is where logs written by calls to Error will be written to. When nil these logs are
+	// written to Writer.
+	ErrorWriter io.Writer
+
+	// SerializeWrites guards Writer, ErrorWriter and the writers of any outputs with a mutex, so that
+	// entries written concurrently cannot interleave. Use it for writers whose writes are not atomic,
+	// such as files on network file systems or pipes written with more than PIPE_BUF bytes. The mutex
+	// for a writer is shared by loggers created by New and NewNamed and by the loggers derived from a
+	// logger created by NewWithOptions. Loggers created by separate calls to NewWithOptions, and those
+	// that do not set SerializeWrites, are not serialized with each other.
+	SerializeWrites bool
+
+	// OnWriteError, when not nil, is called when writing an encoded entry to Writer, ErrorWriter or an
+	// output fails, with the error and the encoded entry. The entry must not be retained after the call
+	// returns. It may be called concurrently by loggers used from multiple goroutines.
+	OnWriteError func(err error, entry []byte)
+
+	// WriteErrorFallback writes encoded entries to os.Stderr when writing them to Writer, ErrorWriter or
+	// an output fails, so that entries are not silently lost when a disk is full or a pipe is broken.
+	// Entries that fail to be written to os.Stderr itself are not written again.
+	WriteErrorFallback bool
+
 	// Humanize changes the log output to a human friendly format
 	Humanize bool
 
-	// Colorize adds color to the log output. Only applies if Humanize is also true.
+	// Colorize adds color to the log output. Only applies if Humanize is also true and ColorMode is
+	// ColorDefault.
 	Colorize bool
 
+	// ColorMode selects when human friendly output is colored. ColorAuto colors output only when it is
+	// written to a terminal, respecting the NO_COLOR and TERM environment variables. The default of
+	// ColorDefault uses Colorize. Only applies if Humanize is also true.
+	ColorMode ColorMode
+
+	// Theme sets the colors of human friendly output. When nil the theme returned by DefaultTheme is used.
+	Theme *Theme
+
 	// TimestampFormat sets the format for log timestamps. Set to empty to disable timestamping
-	// of log messages. Humanize uses a fixed short timestamp format.
+	// of log messages. Humanize uses HumanTimestampFormat instead.
 	TimestampFormat string
 
+	// HumanTimestampFormat sets the format for timestamps when Humanize is true. When empty
+	// DefaultHumanTimestampFormat is used, which shows the time of day without the date.
+	HumanTimestampFormat string
+
+	// HumanLevelSymbols, when not nil, sets compact markers that are shown in place of level names when
+	// Humanize is true, for example those returned by DefaultLevelSymbols.
+	HumanLevelSymbols *LevelSymbols
+
+	// HumanTimeMode selects how the time of each entry is shown when Humanize is true. Showing the time
+	// since the previous entry, for example +12.3ms, helps when looking for latency.
+	HumanTimeMode HumanTimeMode
+
+	// HumanMessageWidth is the width of the message column when Humanize is true. When zero
+	// DefaultHumanMessageWidth is used.
+	HumanMessageWidth int
+
+	// HumanSortKeys sorts key/value pairs by key when Humanize is true.
+	HumanSortKeys bool
+
+	// HumanAlignKeys pads key/value pairs when Humanize is true so that pairs with the same keys line up
+	// in columns across entries.
+	HumanAlignKeys bool
+
+	// HumanLineWidth is the maximum width in columns of lines written when Humanize is true. Longer lines
+	// are truncated and end with an ellipsis. When zero the width of the terminal is used if the output
+	// is written to a terminal.
+	HumanLineWidth int
+
+	// HumanNoTruncate disables truncation of long lines when Humanize is true.
+	HumanNoTruncate bool
+
+	// MultilineValues writes values that contain newlines, such as stack traces, on the lines following
+	// an entry with each line indented when Humanize is true. Otherwise such values are quoted.
+	MultilineValues bool
+
+	// TimestampMode selects how timestamps are written. The default of TimestampFormatted uses
+	// TimestampFormat. Other modes write timestamps as numbers, such as seconds since the Unix epoch.
+	// Humanize always uses HumanTimestampFormat.
+	TimestampMode TimestampMode
+
+	// TimeLocation is the time zone used for timestamps. When nil timestamps are written in UTC.
+	TimeLocation *time.Location
+
+	// UseLocalTime indicates that timestamps should be written in the local time zone. It takes
+	// precedence over TimeLocation.
+	UseLocalTime bool
+
 	// NameDelim is the delimiter character used when appending names of loggers.
 	NameDelim string
 
+	// KeyPrefixFromName qualifies the keys of key/value pairs added to a logger after a call to
+	// WithName with the name, as if WithGroup had been called with the same name. Keys are written
+	// with the name and a dot as a prefix, such as http.latency for a logger named http, and are
+	// nested in an object in JSON. This keeps keys such as count and duration unique when they are
+	// logged by many subsystems.
+	KeyPrefixFromName bool
+
 	// AddCaller indicates that log messages should include the file and line number of the caller of the logger.
 	AddCaller bool
 
+	// CallerFormat selects how the file of the caller is written. The default writes the base name of
+	// the file, which may be ambiguous when several packages have files with the same name.
+	CallerFormat CallerFormat
+
+	// CallerFunc indicates that the package qualified name of the calling function should be written
+	// before the file and line number of the caller, for example caller="http.(*Client).Do client.go:42".
+	CallerFunc bool
+
 	// CallerSkip adds frames to skip when determining the caller of the logger. Useful when the logger is wrapped
 	// by another logger.
 	CallerSkip int
+
+	// LevelNames maps V levels to names that are written in place of numeric levels, for example
+	// {0: "info", 1: "debug"}. When set, entries written by Error are given the level name "error".
+	// Levels without a name are written as numbers.
+	LevelNames map[int]string
+
+	// AddStacktrace indicates that entries written by Error should include a stack trace of the caller
+	// of the logger.
+	AddStacktrace bool
+
+	// StacktraceVerbosity is the minimum verbosity that the logger must be enabled for before stack
+	// traces are added. Only applies if AddStacktrace is also true. The default of zero adds stack
+	// traces to all error entries.
+	StacktraceVerbosity int
+
+	// ExpandErrors indicates that errors passed to Error that wrap other errors should be written
+	// with an additional field for each wrapped error in the chain, for example error.cause and
+	// error.cause.cause.
+	ExpandErrors bool
+
+	// ExitFunc is called with status 1 to exit the program after Fatal has written an entry. When nil
+	// os.Exit is used. Replacing it is useful for testing code that calls Fatal.
+	ExitFunc func(code int)
+
+	// Sampler limits the number of repeated entries that are written. A sampler may be shared between
+	// loggers by using the same options. The sampler is applied before any hooks.
+	Sampler *Sampler
+
+	// Coalescer collapses runs of identical entries into a single entry annotated with the number of
+	// repeats. A coalescer may be shared between loggers by using the same options. It is applied after
+	// any hooks.
+	Coalescer *Coalescer
+
+	// FlightRecorder holds entries that are more verbose than the logger's verbosity in memory and
+	// writes them only when the logger writes an error. Loggers enabled by the recorder report that
+	// they are enabled for the recorded levels.
+	FlightRecorder *FlightRecorder
+
+	// RateLimiter limits the rate at which each named logger writes entries. A rate limiter may be
+	// shared between loggers by using the same options. It is applied after any hooks.
+	RateLimiter *RateLimiter
+
+	// ContextExtractors derive key/value pairs from a context. They are applied to the context passed to
+	// a slog handler and to the context passed to WithContext or FromContext.
+	ContextExtractors []ContextExtractor
+
+	// Hooks are called in order with each entry before it is encoded. A hook may modify the entry or
+	// prevent it from being written. Hooks that have a Flush() error method, such as those that forward
+	// entries to another service, are flushed by Flush and Close when registered with RegisterHook.
+	Hooks []Hook
+
+	// AddGoroutineID indicates that each entry should include the id of the goroutine that wrote it
+	// using the key goid. Finding the id adds a small cost to each entry.
+	AddGoroutineID bool
+
+	// AddPID indicates that each entry should include the id of the process using the key pid.
+	AddPID bool
+
+	// DefaultFields holds key/value pairs that are written with every entry, before any values added
+	// by WithValues. Useful for fields such as hostname, pid or service version.
+	DefaultFields []interface{}
+
+	// Layout sets the order in which the built-in fields and key/value pairs are written in logfmt
+	// output, for example to write the timestamp first. Fields that are not in the layout are not
+	// written. When nil the order returned by DefaultLayout is used.
+	Layout []Field
+
+	// NilError selects how the error field is written for entries written by Error with a nil error. The
+	// default of NilErrorString writes <nil>. NilErrorOmit omits the field and NilErrorNull writes null.
+	NilError NilErrorMode
+
+	// KeyMode selects how keys of key/value pairs that are not strings or contain characters that are not
+	// permitted in logfmt keys, such as spaces, equals signs and quotes, are written in logfmt and human
+	// friendly output. The default of KeyReplace replaces such characters with underscores. KeyQuote
+	// quotes the key and KeyBadKey writes the key as !BADKEY. Keys created by FlattenDepth are treated
+	// in the same way.
+	KeyMode KeyMode
+
+	// FieldNames sets the keys used for the built-in fields such as the timestamp and message.
+	// Empty fields use the default keys.
+	FieldNames FieldNames
+
+	// OddArgs selects how key/value pairs with an odd number of items are written, which is usually a
+	// programming mistake. The default of OddArgsEmptyValue writes the final key with an empty value.
+	// OddArgsBadKey writes the final item as the value of the key !BADKEY, matching log/slog, and
+	// OddArgsError adds a logfmtr_error field so that the mistake is visible.
+	OddArgs OddArgsMode
+
+	// DuplicateKeys selects how key/value pairs that share a key are written, such as those added by nested
+	// calls to WithValues with the same key. The default of DuplicateKeysKeep writes every pair.
+	// DuplicateKeysLastWins writes only the last pair with each key and DuplicateKeysFirstWins only the
+	// first, across the pairs added by DefaultFields, WithValues and those passed with the message.
+	DuplicateKeys DuplicateKeys
+
+	// FlattenDepth, when greater than zero, writes map and struct values of key/value pairs as a pair for
+	// each of their entries or fields with keys joined by dots, such as user.id=7, so that they can be
+	// queried by log stores such as Loki and Splunk. Nested maps and structs are flattened up to
+	// FlattenDepth levels. Map entries are sorted by key and struct fields use the names given by their
+	// json tags. Values that have a String, Error or marshaling method are not flattened.
+	FlattenDepth int
+
+	// ComplexValueJSON writes map, slice, array and struct values of key/value pairs as compact JSON, such
+	// as reasons=[0.1,0.11,3.14], using their MarshalJSON method if they have one. Values with a String
+	// or Error method but no MarshalJSON method are written using the String or Error method. When used
+	// with FlattenDepth, values nested more deeply than the flattening depth are written as JSON.
+	ComplexValueJSON bool
+
+	// BytesFormat selects how []byte values of key/value pairs are written, for example as hexadecimal for
+	// digests and keys or as their length only for payloads. The default writes a list of decimal bytes.
+	BytesFormat BytesFormat
+
+	// DurationFormat selects how time.Duration values of key/value pairs are written, for example as a
+	// number of seconds so that the same metric is written consistently across services. The default
+	// writes durations using their String method, such as 1.5s.
+	DurationFormat DurationFormat
+
+	// TimeFormat selects how time.Time values of key/value pairs are written, for example in RFC 3339
+	// format, using the timestamp format or as seconds since the Unix epoch.
+	TimeFormat TimeFormat
+
+	// FloatFormat, when not empty, is a fmt verb such as %.6g or %.2f used to write float32 and float64
+	// values of key/value pairs, including durations written as numbers, so that numeric fields are
+	// written consistently. When empty the shortest representation of each value is written.
+	FloatFormat string
+
+	// ValueTransforms maps keys to transforms that replace the values of key/value pairs with those keys
+	// before they are formatted and written, such as hashing an email value or upper-casing a method.
+	// Keys within groups use the transform for their full name, such as req.email, if there is one,
+	// otherwise the transform for the key alone. Transforms are called with the results of Lazy values.
+	ValueTransforms map[string]Transform
+
+	// MaxValueLen, when greater than zero, is the maximum length in bytes of the value of a key/value pair
+	// or error. Longer values are truncated and followed by a marker such as "...(truncated, 1024 bytes)"
+	// giving the number of bytes removed, so that an accidental dump of a large value does not produce
+	// lines too long for downstream parsers. Numbers, booleans, times and durations are not truncated.
+	MaxValueLen int
+
+	// MaxFields, when greater than zero, is the maximum number of key/value pairs written with an entry,
+	// including those added by WithValues and DefaultFields. Excess pairs are dropped, starting with the
+	// last, and a fields_dropped field is added giving the number that were dropped.
+	MaxFields int
+
+	// MaxEntryBytes, when greater than zero, is the maximum size in bytes of an encoded entry, not including
+	// any LinePrefix or LineSuffix. Key/value pairs are dropped from larger entries, starting with the last,
+	// until they fit and a fields_dropped field is added giving the number that were dropped. Log shippers
+	// such as journald and Loki may silently drop lines that are too long. An entry may still exceed the
+	// limit if it is too long without any key/value pairs, which MaxValueLen can help prevent.
+	MaxEntryBytes int
+
+	// LinePrefix and LineSuffix are written at the start and end of each line of output, for example
+	// a routing token required by a log collector or an RFC 5424 priority header. The suffix is written
+	// before the line terminator. They are added to the output of every encoder, including those of
+	// Outputs.
+	LinePrefix string
+	LineSuffix string
+
+	// LinePrefixFunc and LineSuffixFunc, when not nil, are called once for each entry and return text
+	// that is written at the start and end of each of its lines, after LinePrefix and LineSuffix.
+	LinePrefixFunc func(e Entry) string
+	LineSuffixFunc func(e Entry) string
+
+	// Template, when not empty, is a text/template used to write each entry in place of logfmt, for
+	// output that must match a bespoke line format. See TemplateEncoder for the fields and functions
+	// available to the template. Timestamps use TimestampFormat.
+	Template string
+
+	// Clock returns the time that is recorded for each entry, including entries written through a
+	// slog handler. When nil time.Now is used. Setting a fixed clock is useful for comparing log output
+	// in tests.
+	Clock func() time.Time
+
+	// Encoder is used to write log entries. When nil an encoder is chosen based on the Template, Humanize, Colorize, ColorMode,
+	// TimestampFormat, TimestampMode, TimeLocation, UseLocalTime, LevelNames and FieldNames options.
+	Encoder Encoder
+
+	// Outputs are additional destinations that every entry is written to, each with its own encoder.
+	// They allow a single logger to write, for example, humanized output to a terminal and logfmt to
+	// a file.
+	Outputs []Output
+}
+
+// Output is an additional destination for log entries.
+type Output struct {
+	// Writer is where entries are written to.
+	Writer io.Writer
+
+	// Encoder is used to write entries to Writer. When nil an encoder is chosen based on the logger's
+	// options in the same way as for the logger's Writer.
+	Encoder Encoder
+}
+
+// encoder returns the encoder to be used with these options.
+func (o Options) encoder() Encoder {
+	return o.encoderFor(o.Writer)
+}
+
+// encoderFor returns the encoder to be used with these options when writing to w.
+func (o Options) encoderFor(w io.Writer) Encoder {
+	if o.Encoder != nil {
+		return o.Encoder
+	}
+	if o.Template != "" {
+		enc, err := NewTemplateEncoder(o.Template)
+		if err != nil {
+			panic("logger was supplied with an invalid template: " + err.Error())
+		}
+		enc.TimestampFormat = o.TimestampFormat
+		enc.Location = o.location()
+		enc.LevelNames = o.LevelNames
+		return enc
+	}
+	if o.Humanize {
+		enc := &HumanEncoder{
+			Colorize:        o.colorize(w),
+			Theme:           o.Theme,
+			MessageWidth:    o.HumanMessageWidth,
+			SortKeys:        o.HumanSortKeys,
+			AlignKeys:       o.HumanAlignKeys,
+			MultilineValues: o.MultilineValues,
+			KeyMode:         o.KeyMode,
+			NilError:        o.NilError,
+			TimestampFormat: o.HumanTimestampFormat,
+			TimeMode:        o.HumanTimeMode,
+			LevelSymbols:    o.HumanLevelSymbols,
+			Location:        o.location(),
+			LevelNames:      o.LevelNames,
+			FieldNames:      o.FieldNames,
+		}
+		if !o.HumanNoTruncate {
+			enc.MaxWidth = o.HumanLineWidth
+			if o.HumanLineWidth == 0 && isTerminal(w) {
+				enc.maxWidth = func() int { return TerminalWidth(w) }
+			}
+		}
+		return enc
+	}
+	return &LogfmtEncoder{
+		Layout:          o.Layout,
+		KeyMode:         o.KeyMode,
+		NilError:        o.NilError,
+		TimestampFormat: o.TimestampFormat,
+		TimestampMode:   o.TimestampMode,
+		Location:        o.location(),
+		LevelNames:      o.LevelNames,
+		FieldNames:      o.FieldNames,
+	}
+}
+
+// location returns the time zone to be used for timestamps, nil meaning UTC.
+func (o Options) location() *time.Location {
+	if o.UseLocalTime {
+		return time.Local
+	}
+	return o.TimeLocation
+}
+
+// Validate reports an error if the options cannot be used to create a logger or contain settings that
+// conflict with one another.
+func (o Options) Validate() error {
+	if o.Writer == nil {
+		return errors.New("invalid options: Writer must not be nil")
+	}
+	if o.CallerSkip < 0 {
+		return fmt.Errorf("invalid options: CallerSkip must not be negative, got %d", o.CallerSkip)
+	}
+	if o.HumanTimeMode < HumanTimeAbsolute || o.HumanTimeMode > HumanTimeAbsoluteDelta {
+		return fmt.Errorf("invalid options: unknown human time mode %d", int(o.HumanTimeMode))
+	}
+	if o.HumanLineWidth < 0 {
+		return fmt.Errorf("invalid options: HumanLineWidth must not be negative, got %d", o.HumanLineWidth)
+	}
+	if o.OddArgs < OddArgsEmptyValue || o.OddArgs > OddArgsError {
+		return fmt.Errorf("invalid options: unknown odd arguments mode %d", int(o.OddArgs))
+	}
+	if o.NilError < NilErrorString || o.NilError > NilErrorNull {
+		return fmt.Errorf("invalid options: unknown nil error mode %d", int(o.NilError))
+	}
+	if o.KeyMode < KeyReplace || o.KeyMode > KeyBadKey {
+		return fmt.Errorf("invalid options: unknown key mode %d", int(o.KeyMode))
+	}
+	if o.DuplicateKeys < DuplicateKeysKeep || o.DuplicateKeys > DuplicateKeysFirstWins {
+		return fmt.Errorf("invalid options: unknown duplicate keys policy %d", int(o.DuplicateKeys))
+	}
+	if o.FlattenDepth < 0 {
+		return fmt.Errorf("invalid options: FlattenDepth must not be negative, got %d", o.FlattenDepth)
+	}
+	if o.BytesFormat < BytesDefault || o.BytesFormat > BytesLength {
+		return fmt.Errorf("invalid options: unknown bytes format %d", int(o.BytesFormat))
+	}
+	if o.DurationFormat < DurationString || o.DurationFormat > DurationNanos {
+		return fmt.Errorf("invalid options: unknown duration format %d", int(o.DurationFormat))
+	}
+	if o.TimeFormat < TimeDefault || o.TimeFormat > TimeEpochMillis {
+		return fmt.Errorf("invalid options: unknown time format %d", int(o.TimeFormat))
+	}
+	if o.FloatFormat != "" {
+		if err := validateFloatFormat(o.FloatFormat); err != nil {
+			return fmt.Errorf("invalid options: FloatFormat: %w", err)
+		}
+	}
+	if o.MaxFields < 0 {
+		return fmt.Errorf("invalid options: MaxFields must not be negative, got %d", o.MaxFields)
+	}
+	if o.MaxEntryBytes < 0 {
+		return fmt.Errorf("invalid options: MaxEntryBytes must not be negative, got %d", o.MaxEntryBytes)
+	}
+	if o.MaxValueLen < 0 {
+		return fmt.Errorf("invalid options: MaxValueLen must not be negative, got %d", o.MaxValueLen)
+	}
+	if o.HumanMessageWidth < 0 {
+		return fmt.Errorf("invalid options: HumanMessageWidth must not be negative, got %d", o.HumanMessageWidth)
+	}
+	if o.Colorize && !o.Humanize {
+		return errors.New("invalid options: Colorize requires Humanize")
+	}
+	if o.ColorMode == ColorAlways && !o.Humanize {
+		return errors.New("invalid options: ColorAlways requires Humanize")
+	}
+	if o.ColorMode < ColorDefault || o.ColorMode > ColorNever {
+		return fmt.Errorf("invalid options: unknown color mode %d", int(o.ColorMode))
+	}
+	if o.Humanize && o.Encoder != nil {
+		return errors.New("invalid options: Humanize cannot be used with an Encoder")
+	}
+	if o.Template != "" {
+		if o.Encoder != nil {
+			return errors.New("invalid options: Template cannot be used with an Encoder")
+		}
+		if o.Humanize {
+			return errors.New("invalid options: Template cannot be used with Humanize")
+		}
+		if _, err := NewTemplateEncoder(o.Template); err != nil {
+			return fmt.Errorf("invalid options: Template: %w", err)
+		}
+	}
+	if len(o.DefaultFields)%2 != 0 {
+		return fmt.Errorf("invalid options: DefaultFields must contain key/value pairs, got %d items", len(o.DefaultFields))
+	}
+	for i, h := range o.Hooks {
+		if h == nil {
+			return fmt.Errorf("invalid options: hook %d is nil", i)
+		}
+	}
+	for i, out := range o.Outputs {
+		if out.Writer == nil {
+			return fmt.Errorf("invalid options: Writer of output %d must not be nil", i)
+		}
+	}
+
+	if err := validateLayout(o.Layout); err != nil {
+		return fmt.Errorf("invalid options: Layout: %w", err)
+	}
+
+	names := o.FieldNames.withDefaults()
+	seen := map[string]bool{}
+	for _, name := range []string{names.Level, names.Logger, names.Timestamp, names.Message, names.Caller, names.Error, names.Stacktrace} {
+		if seen[name] {
+			return fmt.Errorf("invalid options: field name %q is used for more than one field", name)
+		}
+		seen[name] = true
+	}
+	return nil
 }
 
 var _ logr.LogSink = (*sink)(nil)
@@ -113,6 +622,15 @@ type sink struct {
 	parent      *sink
 	runtimeInfo logr.RuntimeInfo
 	dfn         func(*core)
+	fixed       bool         // set for loggers created with explicit options and their children, which ignore UseOptions and SetLoggerOptions
+	live        atomic.Value // holds a *liveCore, the core to use when live options are enabled
+}
+
+// liveCore is a core along with the parent core or options generation it was derived from.
+type liveCore struct {
+	core   *core
+	parent *core
+	gen    uint32
 }
 
 func (l *sink) instantiate() {
@@ -121,235 +639,461 @@ func (l *sink) instantiate() {
 	}
 	if l.parent == nil {
 		goptionsmu.Lock()
-		l.core = &core{
-			runtimeInfo: l.runtimeInfo,
-		}
-		l.core.applyOptions(goptions)
+		l.core = l.rootCore()
+		l.live.Store(&liveCore{core: l.core, gen: optionsGen})
 		goptionsmu.Unlock()
-		if l.dfn != nil {
-			l.dfn(l.core)
-		}
+		registerLogger(l.core, 0)
 		return
 	}
 
-	l.core = l.parent.copyCore(l.dfn)
+	pc := l.parent.getCore()
+	l.core = pc.derive(l.dfn)
+	if !l.fixed {
+		l.core = l.core.withLoggerOptions(pc.name)
+	}
+	l.live.Store(&liveCore{core: l.core, parent: pc})
+	registerChild(l.core, pc)
+}
+
+// rootCore returns a new core using the global options. goptionsmu must be held.
+func (l *sink) rootCore() *core {
+	c := &core{
+		runtimeInfo: l.runtimeInfo,
+		locks:       &sharedLocks,
+	}
+	c.applyOptions(goptions)
+	if l.dfn != nil {
+		l.dfn(c)
+	}
+	return c.withLoggerOptions("")
 }
 
-func (l *sink) copyCore(dfn func(*core)) *core {
+// getCore returns the core that the sink should use to write entries, instantiating the sink if
+// necessary. When live options are enabled the core is rebuilt if the options or any parent core have
+// changed since it was created.
+func (l *sink) getCore() *core {
 	l.init.Do(l.instantiate)
+	if atomic.LoadInt32(&liveOptions) == 0 || l.fixed {
+		return l.core
+	}
+
+	lc := l.live.Load().(*liveCore)
+	if l.parent == nil {
+		if gen := atomic.LoadUint32(&optionsGen); lc.gen != gen {
+			goptionsmu.Lock()
+			lc = &liveCore{core: l.rootCore(), gen: optionsGen}
+			goptionsmu.Unlock()
+			l.live.Store(lc)
+		}
+		return lc.core
+	}
+
+	if pc := l.parent.getCore(); lc.parent != pc {
+		lc = &liveCore{core: pc.derive(l.dfn).withLoggerOptions(pc.name), parent: pc}
+		l.live.Store(lc)
+	}
+	return lc.core
+}
+
+// withLoggerOptions returns a core that uses any options set by SetLoggerOptions for the core's name.
+// The core is returned unchanged if its name is the same as its parent's or has no options set.
+func (c *core) withLoggerOptions(parentName string) *core {
+	if c.name == parentName || atomic.LoadInt32(&anyOptions) == 0 {
+		return c
+	}
+	opts, ok := loggerOptions.Load().(map[string]Options)[c.name]
+	if !ok {
+		return c
+	}
+	return c.reconfigure(opts)
+}
+
+// reconfigure returns a new core that uses opts in place of the options the core was created with,
+// keeping its name, values and call depth.
+func (c *core) reconfigure(opts Options) *core {
+	nc := &core{
+		runtimeInfo: c.runtimeInfo,
+		locks:       &sharedLocks,
+	}
+	nc.applyOptions(opts)
+	nc.name = c.name
+	nc.groups = c.groups
+	nc.callDepth = c.callDepth
+	nc.appendValues(c.values[c.defaults:])
+	return nc
+}
+
+// child returns a sink derived from the sink by dfn. Children of loggers created with explicit options
+// are also fixed.
+func (l *sink) child(dfn func(*core)) *sink {
+	return &sink{parent: l, dfn: dfn, fixed: l.fixed}
+}
 
-	c := *l.core
-	dfn(&c)
-	return &c
+// derive returns a copy of the core modified by dfn.
+func (c *core) derive(dfn func(*core)) *core {
+	d := *c
+	dfn(&d)
+	return &d
 }
 
 func (l *sink) applyOptions(opts Options) {
 	l.core = &core{}
 	l.core.applyOptions(opts)
+	l.fixed = true
+	registerLogger(l.core, 0)
 }
 
 func (l *sink) Init(info logr.RuntimeInfo) {
 	l.runtimeInfo = info
+	if l.core != nil {
+		// loggers created by NewWithOptions are instantiated before Init is called
+		l.core.runtimeInfo = info
+	}
 }
 
 // Enabled reports whether this Logger is enabled with respect to the current global log level.
 func (l *sink) Enabled(level int) bool {
-	l.init.Do(l.instantiate)
-	if level > int(atomic.LoadInt32(&gv)) {
-		return false
-	}
-	if l.core.name == "" || atomic.LoadInt32(&anyDisabled) == 0 {
-		return true
-	}
-	disabled := disabledLoggers.Load().(map[string]bool)
-	return !disabled[l.core.name]
+	return l.getCore().enabled(level)
 }
 
 // Info logs a non-error message with the given key/value pairs as context.
 func (l *sink) Info(level int, msg string, kvs ...interface{}) {
-	l.init.Do(l.instantiate)
-	l.core.write(level, "info", msg, l.core.flatten(kvs...))
+	l.getCore().write(level, false, msg, nil, kvs)
 }
 
 // Error logs an error, with the given message and key/value pairs as context.
 func (l *sink) Error(err error, msg string, kvs ...interface{}) {
-	l.init.Do(l.instantiate)
-	l.core.write(0, "error", msg, l.core.flatten(kvs...), "error", err)
+	l.getCore().write(0, true, msg, err, kvs)
 }
 
 // WithName returns a logger with a new element added to the logger's name.
 func (l *sink) WithName(name string) logr.LogSink {
-	return &sink{
-		parent: l,
-		dfn: func(c *core) {
-			c.appendName(name)
-		},
-	}
+	return l.child(func(c *core) {
+		c.appendName(name)
+	})
 }
 
 // WithValues returns a logger with additional key-value pairs of context.
 func (l *sink) WithValues(kvs ...interface{}) logr.LogSink {
-	return &sink{
-		parent: l,
-		dfn: func(c *core) {
-			values := c.flatten(kvs...)
-			c.appendValues(values)
-		},
-	}
+	return l.child(func(c *core) {
+		c.appendValues(c.groupValues(completeOddKVs(kvs, c.oddArgs)))
+	})
 }
 
 func (l *sink) WithCallDepth(depth int) logr.LogSink {
-	return &sink{
-		parent: l,
-		dfn: func(c *core) {
-			c.callerSkip += depth
-		},
-	}
+	return l.child(func(c *core) {
+		c.callDepth += depth
+	})
 }
 
 type core struct {
-	w           io.Writer
-	name        string
-	values      string
-	humanize    bool
-	tsFormat    string
-	nameDelim   string
-	colorize    bool
-	addCaller   bool
-	callerSkip  int
-	runtimeInfo logr.RuntimeInfo
+	opts          *Options // the options applied to the core, shared by derived cores
+	w             io.Writer
+	errw          io.Writer
+	locks         *writerLocks // mutexes used when writes are serialized, shared with other loggers
+	onWriteError  func(err error, entry []byte)
+	writeFallback bool // write entries to os.Stderr when writing fails
+	enc           Encoder
+	outputs       []Output     // additional outputs with their encoders resolved
+	affixes       *lineAffixes // text added to each line, nil if none
+	maxValueLen   int
+	transforms    map[string]Transform
+	oddArgs       OddArgsMode
+	duplicateKeys DuplicateKeys
+	flattenDepth  int
+	formatter     *valueFormatter // formats values of key/value pairs, nil if none need formatting
+	maxFields     int
+	maxEntryBytes int
+	name          string
+	values        []interface{}
+	groups        []string // names of the groups that qualify subsequent keys
+	nameDelim     string
+	nameKeys      bool // qualify subsequent keys with each name that is appended
+	addCaller     bool
+	callerSkip    int
+	callerFormat  CallerFormat
+	callerFunc    bool
+	clock         func() time.Time
+	callDepth     int // additional frames to skip added by WithCallDepth
+	defaults      int // number of values that were supplied by Options.DefaultFields
+	addStack      bool
+	stackV        int
+	expandErrs    bool
+	exit          func(code int) // called by Fatal, os.Exit when nil
+	severity      string         // written in place of the level, set for loggers used by Fatal and Panic
+	addGoid       bool
+	hooks         []Hook
+	extractors    []ContextExtractor
+	limiter       *RateLimiter
+	coalescer     *Coalescer
+	recorder      *FlightRecorder
+	runtimeInfo   logr.RuntimeInfo
 }
 
-func (c *core) write(level int, humanprefix, msg string, values string, extras ...interface{}) {
-	var b bytes.Buffer
-	if c.humanize {
-		if c.colorize {
-			if humanprefix == "error" {
-				humanprefix = colorRed + humanprefix + colorDefault
-			} else {
-				humanprefix = colorGreen + humanprefix + " " + colorDefault
-			}
+// verbosity returns the log level that applies to the core, taking into account any per-logger overrides.
+func (c *core) verbosity() int {
+	if c.name != "" && atomic.LoadInt32(&anyVerbosity) != 0 {
+		if lv, ok := loggerVerbosity.Load().(map[string]int)[c.name]; ok {
+			return lv
 		}
+	}
+	return int(atomic.LoadInt32(&gv))
+}
 
-		b.WriteString(fmt.Sprintf("%d %-5s | %15s | %-30s", level, humanprefix, time.Now().UTC().Format("15:04:05.000000"), msg))
-		if c.name != "" {
-			b.WriteRune(' ')
-			b.WriteString(c.key("logger"))
-			b.WriteString("=")
-			b.WriteString(c.name)
+func (c *core) enabled(level int) bool {
+	if level > c.verbosity() && (c.recorder == nil || level > c.recorder.maxLevel) {
+		return false
+	}
+	if c.name == "" || atomic.LoadInt32(&anyDisabled) == 0 {
+		return true
+	}
+	disabled := disabledLoggers.Load().(*loggerSet)
+	return !disabled.contains(c.name, c.nameDelim)
+}
+
+func (c *core) write(level int, isError bool, msg string, err error, kvs []interface{}) {
+	e := c.entry(level, isError, msg, err, kvs)
+	// skip this function and the sink method that called it
+	if c.addCaller {
+		e.Caller = c.caller(2)
+	}
+	if isError && c.addStack && c.verbosity() >= c.stackV {
+		e.Stacktrace = c.stacktrace(2)
+	}
+	_ = c.emit(e)
+}
+
+// entry returns a new entry with the core's name and values, without caller information or a stack trace.
+func (c *core) entry(level int, isError bool, msg string, err error, kvs []interface{}) Entry {
+	e := Entry{
+		Time:     c.now(),
+		Level:    level,
+		Name:     c.name,
+		Message:  msg,
+		IsError:  isError,
+		Error:    err,
+		Severity: c.severity,
+		Context:  c.values,
+		Values:   c.groupValues(kvs),
+	}
+	if c.addGoid {
+		e.Context = append(e.Context[:len(e.Context):len(e.Context)], "goid", goroutineID())
+	}
+	if err != nil && c.expandErrs {
+		e.Causes = causes(err)
+	}
+	return e
+}
+
+// now returns the current time according to the core's clock.
+func (c *core) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// emit encodes the entry and writes it to the core's writer in a single write, unless it is rejected
+// by a filter or held by a flight recorder.
+func (c *core) emit(e Entry) error {
+	if filtered(e) {
+		return nil
+	}
+	if c.recorder != nil {
+		if !e.IsError && e.Level > c.verbosity() {
+			c.recorder.record(c, e)
+			return nil
 		}
-		if c.addCaller {
-			b.WriteRune(' ')
-			b.WriteString(c.key("caller"))
-			b.WriteString("=")
-			b.WriteString(c.caller(1))
+		if e.IsError {
+			c.recorder.dump(c, e)
 		}
-	} else {
-		b.WriteString("level=")
-		b.WriteString(strconv.Itoa(level))
-		if c.name != "" {
-			b.WriteRune(' ')
-			b.WriteString("logger=")
-			b.WriteString(quote(c.name))
+	}
+	return c.output(e)
+}
+
+// output applies hooks and limits to the entry before encoding it.
+func (c *core) output(e Entry) error {
+	if len(c.hooks) > 0 {
+		var ok bool
+		if e, ok = applyHooks(c.hooks, e); !ok {
+			return nil
 		}
-		if c.tsFormat != "" {
-			b.WriteRune(' ')
-			b.WriteString("ts=")
-			b.WriteString(quote(time.Now().UTC().Format(c.tsFormat)))
+	}
+
+	if c.coalescer != nil && !c.coalescer.add(c, e) {
+		return nil
+	}
+
+	if c.limiter != nil {
+		ok, dropped := c.limiter.allow(c, e.Name, e.Time)
+		if !ok {
+			return nil
 		}
-		b.WriteRune(' ')
-		b.WriteString("msg=")
-		b.WriteString(quote(msg))
-		if c.addCaller {
-			b.WriteRune(' ')
-			b.WriteString("caller=")
-			b.WriteString(c.caller(1))
+		if dropped > 0 {
+			_ = c.encode(rateLimitSummary(e.Time, e.Name, dropped))
 		}
 	}
-	if len(extras) > 0 {
-		b.WriteRune(' ')
-		b.WriteString(c.flatten(extras...))
-	}
 
-	if c.values != "" {
-		b.WriteRune(' ')
-		b.WriteString(c.values)
+	return c.encode(e)
+}
+
+// encode encodes the entry and writes it to the appropriate writer and to any additional outputs.
+// It returns the first error encountered.
+func (c *core) encode(e Entry) error {
+	e = resolveLazy(e)
+	if len(c.transforms) > 0 {
+		e = transformValues(e, c.transforms)
+	}
+	if c.oddArgs != OddArgsEmptyValue {
+		e = fixOddArgs(e, c.oddArgs)
+	}
+	if c.duplicateKeys != DuplicateKeysKeep {
+		e = dedupeKeys(e, c.duplicateKeys)
 	}
-	if values != "" {
-		b.WriteRune(' ')
-		b.WriteString(values)
+	if c.flattenDepth > 0 {
+		e = flattenPairs(e, c.flattenDepth)
 	}
-	b.WriteRune('\n')
-	_, _ = c.w.Write(b.Bytes())
+	if c.formatter != nil {
+		e = c.formatter.apply(e)
+	}
+	if c.maxValueLen > 0 {
+		e = truncateValues(e, c.maxValueLen)
+	}
+	dropped := 0
+	if c.maxFields > 0 {
+		if n := countPairs(e); n > c.maxFields {
+			e = limitPairs(e, c.maxFields)
+			dropped = n - c.maxFields
+		}
+	}
+	w := c.w
+	if e.IsError {
+		w = c.errw
+	}
+	err := c.writeEntry(w, c.enc, e, dropped)
+	for _, out := range c.outputs {
+		if oerr := c.writeEntry(out.Writer, out.Encoder, e, dropped); oerr != nil && err == nil {
+			err = oerr
+		}
+	}
+	return err
 }
 
-func (c *core) caller(skip int) string {
-	for i := 1; i < 3; i++ {
-		_, file, line, ok := runtime.Caller(c.runtimeInfo.CallDepth + skip + c.callerSkip + i)
-		if ok && file != "<autogenerated>" {
-			return path.Base(file) + ":" + strconv.Itoa(line)
+// writeEntry encodes the entry using enc and writes it to w, keeping within the maximum entry size and
+// adding any line prefix and suffix. Dropped is the number of key/value pairs already removed from the entry.
+func (c *core) writeEntry(w io.Writer, enc Encoder, e Entry, dropped int) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeLimited(buf, enc, e, dropped, c.maxEntryBytes); err != nil {
+		return err
+	}
+	if c.affixes != nil {
+		lines := getBuffer()
+		defer putBuffer(lines)
+		lines.b = c.affixes.appendLines(lines.b, buf.b, e)
+		buf = lines
+	}
+	if lw, ok := w.(LevelWriter); ok {
+		if _, err := lw.WriteLevel(e.Level, e.IsError, buf.b); err != nil {
+			c.writeFailed(w, err, buf.b)
+			return err
 		}
+		return nil
+	}
+	if _, err := w.Write(buf.b); err != nil {
+		c.writeFailed(w, err, buf.b)
+		return err
+	}
+	return nil
+}
+
+// writeFailed reports an error writing an encoded entry to w and writes the entry to the fallback
+// writer if enabled.
+func (c *core) writeFailed(w io.Writer, err error, entry []byte) {
+	if c.onWriteError != nil {
+		c.onWriteError(err, entry)
+	}
+	if c.writeFallback && unwrapWriter(w) != io.Writer(os.Stderr) {
+		_, _ = os.Stderr.Write(entry)
 	}
-	return "unknown"
 }
 
 func (c *core) applyOptions(opts Options) {
 	if opts.Writer == nil {
 		panic("logger was supplied with nil writer")
 	}
+	c.opts = &opts
 	c.w = opts.Writer
-	c.humanize = opts.Humanize
-	c.tsFormat = opts.TimestampFormat
-	c.nameDelim = opts.NameDelim
-	c.colorize = opts.Colorize && opts.Humanize
-	c.addCaller = opts.AddCaller
-	c.callerSkip = opts.CallerSkip
-}
-
-func (c *core) flatten(kvs ...interface{}) string {
-	if len(kvs) == 0 {
-		return ""
+	c.errw = opts.Writer
+	if opts.ErrorWriter != nil {
+		c.errw = opts.ErrorWriter
+	}
+	if opts.SerializeWrites && c.locks == nil {
+		c.locks = &writerLocks{}
 	}
-	var b strings.Builder
-	for i := 0; i < len(kvs); i += 2 {
-		if i > 0 {
-			b.WriteRune(' ')
+	if opts.SerializeWrites {
+		c.w = c.locks.wrap(c.w)
+		c.errw = c.locks.wrap(c.errw)
+	}
+	c.enc = opts.encoder()
+	c.onWriteError = opts.OnWriteError
+	c.writeFallback = opts.WriteErrorFallback
+	c.outputs = nil
+	for _, out := range opts.Outputs {
+		if out.Writer == nil {
+			panic("logger was supplied with an output with a nil writer")
 		}
-
-		k := kvs[i]
-		var v interface{}
-		if i+1 < len(kvs) {
-			v = kvs[i+1]
-		} else {
-			v = ""
+		if out.Encoder == nil {
+			out.Encoder = opts.encoderFor(out.Writer)
+		}
+		if opts.SerializeWrites {
+			out.Writer = c.locks.wrap(out.Writer)
 		}
-		b.WriteString(c.key(stringify(k)))
-		b.WriteRune('=')
-		b.WriteString(stringify(v))
+		c.outputs = append(c.outputs, out)
 	}
-
-	return b.String()
-}
-
-func (c *core) key(s string) string {
-	if !c.colorize {
-		return s
+	c.affixes = newLineAffixes(opts)
+	c.maxValueLen = opts.MaxValueLen
+	c.transforms = opts.ValueTransforms
+	c.oddArgs = opts.OddArgs
+	c.duplicateKeys = opts.DuplicateKeys
+	c.flattenDepth = opts.FlattenDepth
+	c.formatter = newValueFormatter(opts)
+	c.maxFields = opts.MaxFields
+	c.maxEntryBytes = opts.MaxEntryBytes
+	c.clock = opts.Clock
+	c.values = opts.DefaultFields[:len(opts.DefaultFields):len(opts.DefaultFields)]
+	if opts.AddPID {
+		c.values = append(c.values, "pid", os.Getpid())
 	}
-
-	switch s {
-	case "error":
-		return colorRed + s + colorDefault
-	case "logger", "caller":
-		return colorBlue + s + colorDefault
-	default:
-		return colorYellow + s + colorDefault
+	c.defaults = len(c.values)
+	c.addGoid = opts.AddGoroutineID
+	c.nameDelim = opts.NameDelim
+	c.nameKeys = opts.KeyPrefixFromName
+	c.addCaller = opts.AddCaller
+	c.callerSkip = opts.CallerSkip
+	c.callerFormat = opts.CallerFormat
+	c.callerFunc = opts.CallerFunc
+	c.addStack = opts.AddStacktrace
+	c.stackV = opts.StacktraceVerbosity
+	c.expandErrs = opts.ExpandErrors
+	c.exit = opts.ExitFunc
+	c.hooks = opts.Hooks
+	c.extractors = opts.ContextExtractors
+	if opts.Sampler != nil {
+		c.hooks = append([]Hook{opts.Sampler}, opts.Hooks...)
 	}
+	c.limiter = opts.RateLimiter
+	c.coalescer = opts.Coalescer
+	c.recorder = opts.FlightRecorder
 }
 
 func (c *core) appendName(name string) {
 	if name == "" {
 		return
 	}
+	if c.nameKeys {
+		c.appendGroup(name)
+	}
 	if c.name != "" {
 		c.name = c.name + c.nameDelim + name
 	} else {
@@ -357,73 +1101,238 @@ func (c *core) appendName(name string) {
 	}
 }
 
-func (c *core) appendValues(values string) {
-	if values == "" {
+func (c *core) appendValues(kvs []interface{}) {
+	if len(kvs) == 0 {
 		return
 	}
-	if len(c.values) > 0 {
-		c.values = c.values + " " + values
+	// Limit capacity so that sibling loggers never share a backing array
+	c.values = append(c.values[:len(c.values):len(c.values)], kvs...)
+}
+
+// DisableLogger disables all loggers with the given name. The name may be a pattern using the syntax
+// of path.Match, in which case every logger whose name matches the pattern is disabled, including
+// loggers created after the call. For example "kafka.*" disables all loggers whose names start
+// with "kafka.".
+func DisableLogger(name string) {
+	setLoggerDisabledStatus(name, true)
+}
+
+// EnableLogger enables loggers with the given name or pattern that were disabled by DisableLogger.
+// The name must match the one passed to DisableLogger.
+func EnableLogger(name string) {
+	setLoggerDisabledStatus(name, false)
+}
+
+// DisableLoggerTree disables the logger with the given name and all of its descendants, which are
+// loggers whose names start with the name followed by the logger's name delimiter. For example
+// DisableLoggerTree("europa") disables europa, europa.moon and europa.moon.crater but not europan.
+func DisableLoggerTree(name string) {
+	setLoggerTreeDisabledStatus(name, true)
+}
+
+// EnableLoggerTree enables loggers that were disabled by DisableLoggerTree. The name must match the one
+// passed to DisableLoggerTree.
+func EnableLoggerTree(name string) {
+	setLoggerTreeDisabledStatus(name, false)
+}
+
+func setLoggerDisabledStatus(name string, disabled bool) {
+	disabledLoggersMu.Lock()
+	defer disabledLoggersMu.Unlock()
+	current := disabledLoggers.Load().(*loggerSet)
+	next := current.without(name)
+	if disabled {
+		next = next.with(name)
+	}
+	storeDisabledLoggers(next)
+}
+
+func setLoggerTreeDisabledStatus(name string, disabled bool) {
+	disabledLoggersMu.Lock()
+	defer disabledLoggersMu.Unlock()
+	current := disabledLoggers.Load().(*loggerSet)
+	next := current.withoutTree(name)
+	if disabled {
+		next.trees = append(next.trees, name)
+	}
+	storeDisabledLoggers(next)
+}
+
+// storeDisabledLoggers replaces the set of disabled loggers. disabledLoggersMu must be held.
+func storeDisabledLoggers(next *loggerSet) {
+	disabledLoggers.Store(next)
+	if next.empty() {
+		atomic.StoreInt32(&anyDisabled, 0)
 	} else {
-		c.values = values
+		atomic.StoreInt32(&anyDisabled, 1)
 	}
 }
 
-func stringify(v interface{}) string {
-	var s string
-	switch vv := v.(type) {
-	case string:
-		s = vv
-	case fmt.Stringer:
-		s = vv.String()
-	case error:
-		s = vv.Error()
-	default:
-		s = fmt.Sprint(v)
+// loggerSet is an immutable set of logger names, name patterns and the names of trees of loggers.
+type loggerSet struct {
+	names    map[string]bool
+	patterns []string
+	trees    []string
+}
+
+func (s *loggerSet) empty() bool {
+	return len(s.names) == 0 && len(s.patterns) == 0 && len(s.trees) == 0
+}
+
+// contains reports whether the name is in the set, matches any pattern in the set or is within any tree
+// in the set. delim is the delimiter between the elements of the name.
+func (s *loggerSet) contains(name string, delim string) bool {
+	if s.names[name] {
+		return true
+	}
+	for _, p := range s.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	for _, t := range s.trees {
+		if name == t || (strings.HasPrefix(name, t) && strings.HasPrefix(name[len(t):], delim)) {
+			return true
+		}
 	}
-	return quote(s)
+	return false
 }
 
-func quote(s string) string {
-	if strings.ContainsAny(s, " ") {
-		return fmt.Sprintf("%q", s)
+// with returns a copy of the set with the name or pattern added.
+func (s *loggerSet) with(name string) *loggerSet {
+	next := s.without(name)
+	if isPattern(name) {
+		next.patterns = append(next.patterns, name)
+	} else {
+		next.names[name] = true
 	}
-	return s
+	return next
 }
 
-const (
-	colorDefault = "\x1b[0m"
-	colorRed     = "\x1b[1;31m"
-	colorGreen   = "\x1b[1;32m"
-	colorYellow  = "\x1b[1;33m"
-	colorBlue    = "\x1b[1;34m"
-)
+// without returns a copy of the set with the name or pattern removed.
+func (s *loggerSet) without(name string) *loggerSet {
+	next := s.clone()
+	delete(next.names, name)
+	next.patterns = next.patterns[:0]
+	for _, p := range s.patterns {
+		if p != name {
+			next.patterns = append(next.patterns, p)
+		}
+	}
+	return next
+}
 
-func DisableLogger(name string) {
-	setLoggerDisabledStatus(name, true)
+// withoutTree returns a copy of the set with the tree removed.
+func (s *loggerSet) withoutTree(name string) *loggerSet {
+	next := s.clone()
+	next.trees = next.trees[:0]
+	for _, t := range s.trees {
+		if t != name {
+			next.trees = append(next.trees, t)
+		}
+	}
+	return next
 }
 
-func EnableLogger(name string) {
-	setLoggerDisabledStatus(name, false)
+func (s *loggerSet) clone() *loggerSet {
+	next := &loggerSet{
+		names:    make(map[string]bool, len(s.names)),
+		patterns: append([]string(nil), s.patterns...),
+		trees:    append([]string(nil), s.trees...),
+	}
+	for k := range s.names {
+		next.names[k] = true
+	}
+	return next
 }
 
-func setLoggerDisabledStatus(name string, disabled bool) {
-	disabledLoggersMu.Lock()
-	defer disabledLoggersMu.Unlock()
-	current := disabledLoggers.Load().(map[string]bool)
-	next := make(map[string]bool, len(current))
-	for k, v := range current {
+// isPattern reports whether name is a valid pattern containing special characters.
+func isPattern(name string) bool {
+	if !strings.ContainsAny(name, `*?[\`) {
+		return false
+	}
+	_, err := path.Match(name, "")
+	return err == nil
+}
+
+// SetLoggerVerbosity sets the log level for loggers with the given name, overriding the global
+// level set by SetVerbosity. Only loggers with a V level less than or equal to this value will be enabled.
+func SetLoggerVerbosity(name string, v int) {
+	setLoggerVerbosity(name, v, true)
+}
+
+// ClearLoggerVerbosity removes any log level set for loggers with the given name by SetLoggerVerbosity
+// so that they follow the global log level.
+func ClearLoggerVerbosity(name string) {
+	setLoggerVerbosity(name, 0, false)
+}
+
+func setLoggerVerbosity(name string, v int, set bool) {
+	loggerVerbosityMu.Lock()
+	defer loggerVerbosityMu.Unlock()
+	current := loggerVerbosity.Load().(map[string]int)
+	next := make(map[string]int, len(current))
+	for k, lv := range current {
 		if k == name {
 			continue
 		}
-		next[k] = v
+		next[k] = lv
 	}
-	if disabled {
-		next[name] = disabled
+	if set {
+		next[name] = v
 	}
-	disabledLoggers.Store(next)
+	storeLoggerVerbosity(next)
+}
+
+// storeLoggerVerbosity replaces the map of per-logger verbosity. loggerVerbosityMu must be held.
+func storeLoggerVerbosity(next map[string]int) {
+	loggerVerbosity.Store(next)
 	if len(next) == 0 {
-		atomic.StoreInt32(&anyDisabled, 0)
+		atomic.StoreInt32(&anyVerbosity, 0)
 	} else {
-		atomic.StoreInt32(&anyDisabled, 1)
+		atomic.StoreInt32(&anyVerbosity, 1)
 	}
 }
+
+// SetLoggerOptions sets the options used by loggers with the given name in place of the options set by
+// UseOptions. Child loggers created from these loggers also use the options. Only loggers created by
+// New or NewNamed that are instantiated after the call are affected, unless live options are enabled by
+// SetLiveOptions. Panics if no writer is supplied in the options.
+func SetLoggerOptions(name string, opts Options) {
+	if opts.Writer == nil {
+		panic("logger was supplied with nil writer")
+	}
+	setLoggerOptions(name, opts, true)
+}
+
+// ClearLoggerOptions removes any options set for loggers with the given name by SetLoggerOptions.
+func ClearLoggerOptions(name string) {
+	setLoggerOptions(name, Options{}, false)
+}
+
+func setLoggerOptions(name string, opts Options, set bool) {
+	loggerOptionsMu.Lock()
+	defer loggerOptionsMu.Unlock()
+	current := loggerOptions.Load().(map[string]Options)
+	next := make(map[string]Options, len(current))
+	for k, o := range current {
+		if k == name {
+			continue
+		}
+		next[k] = o
+	}
+	if set {
+		next[name] = opts
+	}
+	loggerOptions.Store(next)
+	if len(next) == 0 {
+		atomic.StoreInt32(&anyOptions, 0)
+	} else {
+		atomic.StoreInt32(&anyOptions, 1)
+	}
+
+	// rebuild live loggers so they pick up the change
+	goptionsmu.Lock()
+	atomic.AddUint32(&optionsGen, 1)
+	goptionsmu.Unlock()
+}
@@ -1,9 +1,18 @@
 package logfmtr_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/iand/logfmtr"
 )
 
@@ -47,6 +56,19 @@ func ExampleDisableLogger() {
 	logger.Info("the sun not shining now")
 }
 
+func ExampleSetLoggerVerbosity() {
+	logfmtr.SetVerbosity(0)
+
+	// Raise the verbosity of a single logger
+	logfmtr.SetLoggerVerbosity("europa", 2)
+
+	logger := logfmtr.NewNamed("europa")
+	logger.V(2).Info("the sun is shining")
+
+	other := logfmtr.NewNamed("io")
+	other.V(2).Info("not logged")
+}
+
 func discard() logfmtr.Options {
 	opts := logfmtr.DefaultOptions()
 	opts.Writer = io.Discard
@@ -67,3 +89,393 @@ func TestIssue3(t *testing.T) {
 	// Should not panic
 	log.Error(nil, "uh oh", "trouble", true, "reasons", []float64{0.1, 0.11, 3.14})
 }
+
+func TestSetLoggerVerbosity(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+	defer logfmtr.ClearLoggerVerbosity("europa")
+
+	logfmtr.SetLoggerVerbosity("europa", 2)
+	base := logfmtr.NewWithOptions(discard())
+
+	if !base.WithName("europa").V(2).Enabled() {
+		t.Errorf("logger with verbosity override was not enabled at V(2)")
+	}
+	if base.WithName("europa").V(3).Enabled() {
+		t.Errorf("logger with verbosity override was enabled at V(3)")
+	}
+	if base.WithName("io").V(1).Enabled() {
+		t.Errorf("logger without verbosity override was enabled at V(1)")
+	}
+
+	logfmtr.ClearLoggerVerbosity("europa")
+	if base.WithName("europa").V(1).Enabled() {
+		t.Errorf("logger with cleared verbosity override was enabled at V(1)")
+	}
+}
+
+func TestDisableLoggerPattern(t *testing.T) {
+	defer logfmtr.EnableLogger("kafka.*")
+
+	logfmtr.DisableLogger("kafka.*")
+	base := logfmtr.NewWithOptions(discard())
+
+	if !base.WithName("kafka").Enabled() {
+		t.Errorf("logger not matching pattern was disabled")
+	}
+	if base.WithName("kafka").WithName("producer").Enabled() {
+		t.Errorf("logger matching pattern was enabled")
+	}
+	if base.WithName("kafka").WithName("producer").WithName("batch").Enabled() {
+		t.Errorf("descendant of logger matching pattern was enabled")
+	}
+
+	logfmtr.EnableLogger("kafka.*")
+	if !base.WithName("kafka").WithName("producer").Enabled() {
+		t.Errorf("logger matching removed pattern was disabled")
+	}
+}
+
+func TestErrorWriter(t *testing.T) {
+	var out, errout bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &out
+	opts.ErrorWriter = &errout
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello")
+	logger.Error(errors.New("failed"), "goodbye")
+
+	if got, want := out.String(), "level=0 msg=hello\n"; got != want {
+		t.Errorf("got %q written to writer, wanted %q", got, want)
+	}
+	if got, want := errout.String(), "level=0 msg=goodbye error=failed\n"; got != want {
+		t.Errorf("got %q written to error writer, wanted %q", got, want)
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	var human, file, same bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &human
+	opts.Humanize = true
+	opts.HumanTimestampFormat = "15:04"
+	opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	opts.Outputs = []logfmtr.Output{
+		{Writer: &file, Encoder: &logfmtr.LogfmtEncoder{TimestampFormat: time.RFC3339}},
+		{Writer: &same},
+	}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello", "val", 1)
+
+	if got, want := human.String(), "0 info  | 03:04 | hello                          val=1\n"; got != want {
+		t.Errorf("got %q written to writer, wanted %q", got, want)
+	}
+	if got, want := file.String(), "level=0 ts=2024-01-02T03:04:05Z msg=hello val=1\n"; got != want {
+		t.Errorf("got %q written to output, wanted %q", got, want)
+	}
+	if got, want := same.String(), human.String(); got != want {
+		t.Errorf("got %q written to output without encoder, wanted %q", got, want)
+	}
+}
+
+func TestDefaultFields(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.DefaultFields = []interface{}{"service", "europa", "version", "1.2"}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.WithValues("user", "you").Info("hello", "val", 1)
+	logger.Info("goodbye")
+
+	want := "level=0 msg=hello service=europa version=1.2 user=you val=1\n" +
+		"level=0 msg=goodbye service=europa version=1.2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestAddCaller(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	logger := logfmtr.NewWithOptions(opts)
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("hello")
+	logger.WithName("europa").Error(nil, "goodbye")
+
+	want := fmt.Sprintf("level=0 msg=hello caller=logfmtr_test.go:%d\n", line+1) +
+		fmt.Sprintf("level=0 logger=europa msg=goodbye caller=logfmtr_test.go:%d error=<nil>\n", line+2)
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestCallerFormat(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+
+	testCases := []struct {
+		format logfmtr.CallerFormat
+		fn     bool
+		want   string
+	}{
+		{format: logfmtr.CallerShort, want: "caller=logfmtr_test.go:%d"},
+		{format: logfmtr.CallerPackage, want: "caller=" + filepath.Base(filepath.Dir(file)) + "/logfmtr_test.go:%d"},
+		{format: logfmtr.CallerFull, want: "caller=" + file + ":%d"},
+		{format: logfmtr.CallerShort, fn: true, want: "caller=\"logfmtr_test.TestCallerFormat logfmtr_test.go:%d\""},
+	}
+
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		opts := logfmtr.DefaultOptions()
+		opts.Writer = &buf
+		opts.TimestampFormat = ""
+		opts.AddCaller = true
+		opts.CallerFormat = tc.format
+		opts.CallerFunc = tc.fn
+		logger := logfmtr.NewWithOptions(opts)
+
+		_, _, line, _ := runtime.Caller(0)
+		logger.Info("hello")
+
+		want := "level=0 msg=hello " + fmt.Sprintf(tc.want, line+1) + "\n"
+		if got := buf.String(); got != want {
+			t.Errorf("got %q, wanted %q", got, want)
+		}
+	}
+}
+
+func logHelper(logger logr.Logger, msg string) {
+	logfmtr.MarkHelper()
+	logger.Info(msg)
+}
+
+func outerLogHelper(logger logr.Logger, msg string) {
+	logfmtr.MarkHelper()
+	logHelper(logger, msg)
+}
+
+func TestMarkHelper(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	logger := logfmtr.NewWithOptions(opts)
+
+	_, _, line, _ := runtime.Caller(0)
+	logHelper(logger, "hello")
+	outerLogHelper(logger, "goodbye")
+
+	want := fmt.Sprintf("level=0 msg=hello caller=logfmtr_test.go:%d\n", line+1) +
+		fmt.Sprintf("level=0 msg=goodbye caller=logfmtr_test.go:%d\n", line+2)
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestAddCallerDeferred(t *testing.T) {
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	logfmtr.UseOptions(opts)
+	logger := logfmtr.New()
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("hello")
+
+	want := fmt.Sprintf("level=0 msg=hello caller=logfmtr_test.go:%d\n", line+1)
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestAddStacktrace(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.AddStacktrace = true
+	opts.StacktraceVerbosity = 1
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Error(nil, "no stack")
+	if strings.Contains(buf.String(), "stacktrace=") {
+		t.Errorf("stack trace was added below the stack trace verbosity: %q", buf.String())
+	}
+
+	buf.Reset()
+	logfmtr.SetVerbosity(1)
+	logger.Error(nil, "with stack")
+	if !strings.Contains(buf.String(), `stacktrace="github.com/iand/logfmtr_test.TestAddStacktrace\n\t`) {
+		t.Errorf("stack trace did not start with the caller of the logger: %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.Info("info")
+	if strings.Contains(buf.String(), "stacktrace=") {
+		t.Errorf("stack trace was added to info entry: %q", buf.String())
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.ExpandErrors = true
+	logger := logfmtr.NewWithOptions(opts)
+
+	root := errors.New("disk full")
+	err := fmt.Errorf("write failed: %w", fmt.Errorf("flush: %w", root))
+	logger.Error(err, "goodbye")
+	logger.Error(root, "again")
+
+	want := `level=0 msg=goodbye error="write failed: flush: disk full" error.cause="flush: disk full" error.cause.cause="disk full"` + "\n" +
+		`level=0 msg=again error="disk full"` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestNewWithOptionsE(t *testing.T) {
+	opts := logfmtr.DefaultOptions()
+	if _, err := logfmtr.NewWithOptionsE(opts); err != nil {
+		t.Errorf("unexpected error for default options: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		fn   func(*logfmtr.Options)
+	}{
+		{name: "nil writer", fn: func(o *logfmtr.Options) { o.Writer = nil }},
+		{name: "negative caller skip", fn: func(o *logfmtr.Options) { o.CallerSkip = -1 }},
+		{name: "negative message width", fn: func(o *logfmtr.Options) { o.HumanMessageWidth = -1 }},
+		{name: "unknown odd args mode", fn: func(o *logfmtr.Options) { o.OddArgs = 3 }},
+		{name: "unknown nil error mode", fn: func(o *logfmtr.Options) { o.NilError = -1 }},
+		{name: "unknown key mode", fn: func(o *logfmtr.Options) { o.KeyMode = 3 }},
+		{name: "unknown duplicate keys policy", fn: func(o *logfmtr.Options) { o.DuplicateKeys = -1 }},
+		{name: "negative flatten depth", fn: func(o *logfmtr.Options) { o.FlattenDepth = -1 }},
+		{name: "unknown bytes format", fn: func(o *logfmtr.Options) { o.BytesFormat = -1 }},
+		{name: "unknown duration format", fn: func(o *logfmtr.Options) { o.DurationFormat = -1 }},
+		{name: "unknown time format", fn: func(o *logfmtr.Options) { o.TimeFormat = 99 }},
+		{name: "float format without verb", fn: func(o *logfmtr.Options) { o.FloatFormat = "%.2" }},
+		{name: "float format with integer verb", fn: func(o *logfmtr.Options) { o.FloatFormat = "%d" }},
+		{name: "float format with space flag", fn: func(o *logfmtr.Options) { o.FloatFormat = "% .2f" }},
+		{name: "negative max fields", fn: func(o *logfmtr.Options) { o.MaxFields = -1 }},
+		{name: "negative max entry bytes", fn: func(o *logfmtr.Options) { o.MaxEntryBytes = -1 }},
+		{name: "negative max value length", fn: func(o *logfmtr.Options) { o.MaxValueLen = -1 }},
+		{name: "negative line width", fn: func(o *logfmtr.Options) { o.HumanLineWidth = -1 }},
+		{name: "unknown human time mode", fn: func(o *logfmtr.Options) { o.HumanTimeMode = -1 }},
+		{name: "repeated layout field", fn: func(o *logfmtr.Options) { o.Layout = []logfmtr.Field{logfmtr.FieldMessage, logfmtr.FieldMessage} }},
+		{name: "unknown layout field", fn: func(o *logfmtr.Options) { o.Layout = []logfmtr.Field{0} }},
+		{name: "colorize without humanize", fn: func(o *logfmtr.Options) { o.Colorize = true }},
+		{name: "color always without humanize", fn: func(o *logfmtr.Options) { o.ColorMode = logfmtr.ColorAlways }},
+		{name: "humanize with encoder", fn: func(o *logfmtr.Options) { o.Humanize = true; o.Encoder = &logfmtr.JSONEncoder{} }},
+		{name: "invalid template", fn: func(o *logfmtr.Options) { o.Template = "{{.Msg" }},
+		{name: "template with humanize", fn: func(o *logfmtr.Options) { o.Template = "{{.Msg}}"; o.Humanize = true }},
+		{name: "template with encoder", fn: func(o *logfmtr.Options) { o.Template = "{{.Msg}}"; o.Encoder = &logfmtr.JSONEncoder{} }},
+		{name: "odd default fields", fn: func(o *logfmtr.Options) { o.DefaultFields = []interface{}{"a"} }},
+		{name: "nil hook", fn: func(o *logfmtr.Options) { o.Hooks = []logfmtr.Hook{nil} }},
+		{name: "nil output writer", fn: func(o *logfmtr.Options) { o.Outputs = []logfmtr.Output{{}} }},
+		{name: "duplicate field name", fn: func(o *logfmtr.Options) { o.FieldNames.Message = "error" }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := logfmtr.DefaultOptions()
+			tc.fn(&opts)
+			if _, err := logfmtr.NewWithOptionsE(opts); err == nil {
+				t.Errorf("got no error, wanted error")
+			}
+		})
+	}
+}
+
+func TestDisableLoggerTree(t *testing.T) {
+	defer logfmtr.EnableLoggerTree("europa")
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	base := logfmtr.NewWithOptions(opts)
+
+	logfmtr.DisableLoggerTree("europa")
+	base.WithName("europa").Info("hidden")
+	base.WithName("europa").WithName("moon").Info("hidden")
+	base.WithName("europan").Info("shown")
+
+	logfmtr.EnableLoggerTree("europa")
+	base.WithName("europa").WithName("moon").Info("shown again")
+
+	want := "level=0 logger=europan msg=shown\n" +
+		"level=0 logger=europa.moon msg=\"shown again\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestClock(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Clock = func() time.Time {
+		return time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello")
+
+	want := "level=0 ts=2021-09-01T12:00:00.000000000Z msg=hello\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestAddGoroutineIDAndPID(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddGoroutineID = true
+	opts.AddPID = true
+	logger := logfmtr.NewWithOptions(opts).WithValues("user", "you")
+
+	logger.Info("hello")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		logger.Info("hello")
+	}()
+	<-done
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, wanted 2: %q", len(lines), buf.String())
+	}
+	var goids [2]uint64
+	for i, line := range lines {
+		var pid int
+		if _, err := fmt.Sscanf(line, "level=0 msg=hello pid=%d user=you goid=%d", &pid, &goids[i]); err != nil {
+			t.Fatalf("unexpected error parsing %q: %v", line, err)
+		}
+		if pid != os.Getpid() {
+			t.Errorf("got pid %d, wanted %d", pid, os.Getpid())
+		}
+	}
+	if goids[0] == 0 || goids[0] == goids[1] {
+		t.Errorf("got goroutine ids %v, wanted distinct non-zero ids", goids)
+	}
+}
@@ -0,0 +1,58 @@
+package logfmtrgrpc_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtrgrpc"
+)
+
+func TestLoggerV2(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	l := logfmtrgrpc.NewLoggerV2(logfmtr.NewWithOptions(opts).WithName("grpc"))
+
+	l.Info("hidden")
+	l.Warningf("retrying in %ds", 2)
+	l.Errorln("connection", "lost")
+
+	if l.V(1) {
+		t.Errorf("got V(1) enabled, wanted disabled")
+	}
+	logfmtr.SetVerbosity(1)
+	l.Infoln("shown")
+
+	want := "level=0 logger=grpc msg=\"retrying in 2s\" caller=grpclog_test.go:22 grpc.severity=warning\n" +
+		"level=0 logger=grpc msg=\"connection lost\" caller=grpclog_test.go:23 error=<nil> grpc.severity=error\n" +
+		"level=1 logger=grpc msg=shown caller=grpclog_test.go:29 grpc.severity=info\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestLoggerV2Fatal(t *testing.T) {
+	var buf bytes.Buffer
+	var code int
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	opts.ExitFunc = func(c int) { code = c }
+	l := logfmtrgrpc.NewLoggerV2(logfmtr.NewWithOptions(opts).WithName("grpc"))
+
+	l.Fatalf("listen failed: %s", "port in use")
+
+	if code != 1 {
+		t.Errorf("got exit code %d, wanted 1", code)
+	}
+	want := "level=fatal logger=grpc msg=\"listen failed: port in use\" caller=grpclog_test.go:49 error=<nil> grpc.severity=fatal\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+// Package logfmtrgrpc provides gRPC server interceptors that log RPCs using a logr.Logger and an adapter
+// that routes gRPC's internal logging through a logr.Logger. It is a separate module so that the logfmtr
+// module does not depend on gRPC.
+package logfmtrgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns an interceptor that adds a logger derived from logger, including the
+// full method name of the RPC, to the context of each unary RPC using logr.NewContext. When the RPC
+// completes it writes an entry with the message "rpc" including the status code and the duration in
+// milliseconds. RPCs that fail with a code other than OK are written with Error.
+func UnaryServerInterceptor(logger logr.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		l := logger.WithValues("grpc.method", info.FullMethod)
+		resp, err := handler(logr.NewContext(ctx, l), req)
+		logRPC(l, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that adds a logger derived from logger, including the
+// full method name of the RPC, to the context of each streaming RPC using logr.NewContext. When the RPC
+// completes it writes an entry as described by UnaryServerInterceptor.
+func StreamServerInterceptor(logger logr.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		l := logger.WithValues("grpc.method", info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: logr.NewContext(ss.Context(), l)})
+		logRPC(l, start, err)
+		return err
+	}
+}
+
+func logRPC(logger logr.Logger, start time.Time, err error) {
+	code := status.Code(err)
+	duration := float64(time.Since(start).Microseconds()) / 1000
+	if code == codes.OK {
+		logger.Info("rpc", "grpc.code", code.String(), "duration_ms", duration)
+		return
+	}
+	logger.Error(err, "rpc", "grpc.code", code.String(), "duration_ms", duration)
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
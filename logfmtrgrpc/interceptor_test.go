@@ -0,0 +1,67 @@
+package logfmtrgrpc_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtrgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	interceptor := logfmtrgrpc.UnaryServerInterceptor(logfmtr.NewWithOptions(opts))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/europa.Moon/Orbit"}
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		logr.FromContextOrDiscard(ctx).Info("handling")
+		return nil, nil
+	})
+	_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no moon")
+	})
+
+	want := regexp.MustCompile(`^level=0 msg=handling grpc.method=/europa.Moon/Orbit\n` +
+		`level=0 msg=rpc grpc.method=/europa.Moon/Orbit grpc.code=OK duration_ms=[0-9.e-]+\n` +
+		`level=0 msg=rpc error="rpc error: code = NotFound desc = no moon" grpc.method=/europa.Moon/Orbit grpc.code=NotFound duration_ms=[0-9.e-]+\n$`)
+	if got := buf.String(); !want.MatchString(got) {
+		t.Errorf("got %q, wanted match for %q", got, want)
+	}
+}
+
+type testStream struct {
+	grpc.ServerStream
+}
+
+func (testStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	interceptor := logfmtrgrpc.StreamServerInterceptor(logfmtr.NewWithOptions(opts))
+
+	info := &grpc.StreamServerInfo{FullMethod: "/europa.Moon/Watch"}
+	_ = interceptor(nil, testStream{}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		logr.FromContextOrDiscard(ss.Context()).Info("streaming")
+		return nil
+	})
+
+	want := regexp.MustCompile(`^level=0 msg=streaming grpc.method=/europa.Moon/Watch\n` +
+		`level=0 msg=rpc grpc.method=/europa.Moon/Watch grpc.code=OK duration_ms=[0-9.e-]+\n$`)
+	if got := buf.String(); !want.MatchString(got) {
+		t.Errorf("got %q, wanted match for %q", got, want)
+	}
+}
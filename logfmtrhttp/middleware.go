@@ -0,0 +1,188 @@
+// Package logfmtrhttp provides HTTP middleware that adds request scoped loggers to request contexts and
+// optionally writes an access log entry for each request.
+package logfmtrhttp
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultRequestIDHeader is the header used to read and write request ids.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen is the longest request id accepted from a request.
+const maxRequestIDLen = 128
+
+// An Option configures the middleware.
+type Option func(*config)
+
+type config struct {
+	accessLog bool
+	header    string
+}
+
+// AccessLog writes an entry with the message "request" for each request once it has been handled,
+// including the response status, the number of bytes written and the duration in milliseconds. The entry
+// is also written if the handler panics, with a panic field set to true and, if no response had been
+// started, a status of 500. Requests whose connection is hijacked before a response is started are
+// logged with a status of 101.
+func AccessLog() Option {
+	return func(c *config) { c.accessLog = true }
+}
+
+// RequestIDHeader sets the header used to read request ids from requests and write them to responses.
+func RequestIDHeader(name string) Option {
+	return func(c *config) { c.header = name }
+}
+
+// Middleware returns middleware that adds a logger derived from base to the context of each request using
+// logr.NewContext. The logger includes the request id, method and path. The request id is taken from the
+// request's X-Request-ID header, or generated if the header is absent or invalid, and is written to the
+// same header of the response. A valid request id has at most 128 characters, each a letter, digit or one
+// of "-", "_", "." and ":". Handlers can retrieve the logger with logr.FromContextOrDiscard.
+func Middleware(base logr.Logger, opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{header: DefaultRequestIDHeader}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(cfg.header)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(cfg.header, id)
+
+			logger := base.WithValues("request_id", id, "method", r.Method, "path", r.URL.Path)
+			r = r.WithContext(logr.NewContext(r.Context(), logger))
+
+			if !cfg.accessLog {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw := &responseWriter{ResponseWriter: w}
+			completed := false
+			defer func() {
+				kvs := []interface{}{"status", rw.status, "bytes", rw.bytes, "duration_ms", float64(time.Since(start).Microseconds()) / 1000}
+				if !completed {
+					if rw.status == 0 {
+						kvs[1] = http.StatusInternalServerError
+					}
+					kvs = append(kvs, "panic", true)
+				} else if rw.status == 0 {
+					kvs[1] = http.StatusOK
+				}
+				logger.Info("request", kvs...)
+			}()
+			next.ServeHTTP(rw.wrap(), r)
+			completed = true
+		})
+	}
+}
+
+// validRequestID reports whether id is an acceptable request id to take from a request.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// newRequestID returns a random 16 character hex string.
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// responseWriter records the status and number of bytes written in a response.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying writer, allowing http.ResponseController to reach it.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wrap returns w as a writer that implements http.Flusher and http.Hijacker only if the underlying
+// writer does, so that handlers checking for them see the same capabilities as without the middleware.
+func (w *responseWriter) wrap() http.ResponseWriter {
+	_, flusher := w.ResponseWriter.(http.Flusher)
+	_, hijacker := w.ResponseWriter.(http.Hijacker)
+	switch {
+	case flusher && hijacker:
+		return &flushHijackWriter{w}
+	case flusher:
+		return &flushWriter{w}
+	case hijacker:
+		return &hijackWriter{w}
+	default:
+		return w
+	}
+}
+
+func (w *responseWriter) flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (w *responseWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+type flushWriter struct{ *responseWriter }
+
+func (w *flushWriter) Flush() { w.flush() }
+
+type hijackWriter struct{ *responseWriter }
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+type flushHijackWriter struct{ *responseWriter }
+
+func (w *flushHijackWriter) Flush() { w.flush() }
+
+func (w *flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
@@ -0,0 +1,121 @@
+package logfmtrhttp_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtrhttp"
+)
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	base := logfmtr.NewWithOptions(opts).WithName("http")
+
+	h := logfmtrhttp.Middleware(base, logfmtrhttp.AccessLog())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logr.FromContextOrDiscard(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("short and stout"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/pot?x=1", nil)
+	req.Header.Set("X-Request-ID", "r-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Request-ID"); got != "r-123" {
+		t.Errorf("got response request id %q, wanted %q", got, "r-123")
+	}
+	want := regexp.MustCompile(`^level=0 logger=http msg=handling request_id=r-123 method=GET path=/pot\n` +
+		`level=0 logger=http msg=request request_id=r-123 method=GET path=/pot status=418 bytes=15 duration_ms=[0-9.e-]+\n$`)
+	if got := buf.String(); !want.MatchString(got) {
+		t.Errorf("got %q, wanted match for %q", got, want)
+	}
+}
+
+func TestMiddlewareGeneratesRequestID(t *testing.T) {
+	h := logfmtrhttp.Middleware(logr.Discard())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := rec.Header().Get(logfmtrhttp.DefaultRequestIDHeader); !regexp.MustCompile(`^[0-9a-f]{16}$`).MatchString(got) {
+		t.Errorf("got request id %q, wanted 16 hex characters", got)
+	}
+}
+
+func TestMiddlewareInvalidRequestID(t *testing.T) {
+	h := logfmtrhttp.Middleware(logr.Discard())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, id := range []string{"bad id", "id\x00", strings.Repeat("a", 129)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(logfmtrhttp.DefaultRequestIDHeader, id)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(logfmtrhttp.DefaultRequestIDHeader); !regexp.MustCompile(`^[0-9a-f]{16}$`).MatchString(got) {
+			t.Errorf("request id %q: got response request id %q, wanted a generated id", id, got)
+		}
+	}
+}
+
+func TestMiddlewareAccessLogPanic(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	base := logfmtr.NewWithOptions(opts)
+
+	h := logfmtrhttp.Middleware(base, logfmtrhttp.AccessLog())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "r-1")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("got no panic, wanted the handler's panic to be propagated")
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	want := regexp.MustCompile(`^level=0 msg=request request_id=r-1 method=GET path=/ status=500 bytes=0 duration_ms=[0-9.e-]+ panic=true\n$`)
+	if got := buf.String(); !want.MatchString(got) {
+		t.Errorf("got %q, wanted match for %q", got, want)
+	}
+}
+
+func TestMiddlewareAccessLogInterfaces(t *testing.T) {
+	var flusher, hijacker bool
+	h := logfmtrhttp.Middleware(logr.Discard(), logfmtrhttp.AccessLog())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, flusher = w.(http.Flusher)
+		_, hijacker = w.(http.Hijacker)
+	}))
+
+	// a ResponseRecorder can be flushed but not hijacked
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !flusher || hijacker {
+		t.Errorf("recorder: got flusher=%v hijacker=%v, wanted flusher=true hijacker=false", flusher, hijacker)
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if !flusher || !hijacker {
+		t.Errorf("server: got flusher=%v hijacker=%v, wanted both true", flusher, hijacker)
+	}
+}
@@ -0,0 +1,122 @@
+// Package logfmtrmetrics counts the entries written by logfmtr loggers so that log rates can be
+// monitored without parsing log output. Counts can be published with expvar.
+package logfmtrmetrics
+
+import (
+	"expvar"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/iand/logfmtr"
+)
+
+// ErrorLevel is the level used to count entries written by Error.
+const ErrorLevel = "error"
+
+var _ logfmtr.Hook = (*Counter)(nil)
+
+// Counter counts entries by level and logger name. It is a logfmtr.Hook and should be added as the last
+// entry in Options.Hooks so that entries dropped by earlier hooks are not counted.
+type Counter struct {
+	mu           sync.Mutex
+	levels       map[string]uint64
+	loggers      map[string]uint64
+	failedWrites uint64
+	dropSources  []func() uint64
+}
+
+// Snapshot holds the counts recorded by a Counter at a point in time.
+type Snapshot struct {
+	// Levels holds the number of entries written at each level. Levels are V levels formatted as
+	// decimal strings or ErrorLevel for entries written by Error.
+	Levels map[string]uint64 `json:"levels"`
+
+	// Loggers holds the number of entries written by each named logger. Entries written by unnamed
+	// loggers are counted under the empty name.
+	Loggers map[string]uint64 `json:"loggers"`
+
+	// FailedWrites is the number of entries that could not be written by writers wrapped with Counter.Writer.
+	FailedWrites uint64 `json:"failed_writes"`
+
+	// Dropped is the total number of entries dropped by sources added with Counter.AddDropSource.
+	Dropped uint64 `json:"dropped"`
+}
+
+// New returns a new Counter.
+func New() *Counter {
+	return &Counter{
+		levels:  make(map[string]uint64),
+		loggers: make(map[string]uint64),
+	}
+}
+
+// Apply counts the entry. It always returns true.
+func (c *Counter) Apply(e *logfmtr.Entry) bool {
+	level := ErrorLevel
+	if !e.IsError {
+		level = strconv.Itoa(e.Level)
+	}
+	c.mu.Lock()
+	c.levels[level]++
+	c.loggers[e.Name]++
+	c.mu.Unlock()
+	return true
+}
+
+// Writer returns a writer that writes to w and counts any failed writes.
+func (c *Counter) Writer(w io.Writer) io.Writer {
+	return &countingWriter{w: w, c: c}
+}
+
+// AddDropSource adds a function that reports a number of dropped entries, such as the Dropped method
+// of a logfmtr.AsyncWriter. The values reported by all sources are summed in snapshots.
+func (c *Counter) AddDropSource(fn func() uint64) {
+	c.mu.Lock()
+	c.dropSources = append(c.dropSources, fn)
+	c.mu.Unlock()
+}
+
+// Snapshot returns a copy of the current counts.
+func (c *Counter) Snapshot() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Snapshot{
+		Levels:       make(map[string]uint64, len(c.levels)),
+		Loggers:      make(map[string]uint64, len(c.loggers)),
+		FailedWrites: c.failedWrites,
+	}
+	for k, v := range c.levels {
+		s.Levels[k] = v
+	}
+	for k, v := range c.loggers {
+		s.Loggers[k] = v
+	}
+	for _, fn := range c.dropSources {
+		s.Dropped += fn()
+	}
+	return s
+}
+
+// Publish publishes the counter's snapshots as an expvar variable with the given name. Like expvar.Publish
+// it panics if the name is already in use.
+func (c *Counter) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return c.Snapshot()
+	}))
+}
+
+type countingWriter struct {
+	w io.Writer
+	c *Counter
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	if err != nil {
+		cw.c.mu.Lock()
+		cw.c.failedWrites++
+		cw.c.mu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,56 @@
+package logfmtrmetrics_test
+
+import (
+	"errors"
+	"expvar"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtrmetrics"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCounter(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+
+	c := logfmtrmetrics.New()
+	c.AddDropSource(func() uint64 { return 3 })
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = c.Writer(io.Discard)
+	opts.Hooks = []logfmtr.Hook{c}
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello")
+	logger.V(1).Info("details")
+	logger.WithName("europa").Error(nil, "goodbye")
+
+	opts.Writer = c.Writer(failingWriter{})
+	logfmtr.NewWithOptions(opts).WithName("europa").Info("lost")
+
+	s := c.Snapshot()
+	if s.Levels["0"] != 2 || s.Levels["1"] != 1 || s.Levels[logfmtrmetrics.ErrorLevel] != 1 {
+		t.Errorf("unexpected level counts: %v", s.Levels)
+	}
+	if s.Loggers[""] != 2 || s.Loggers["europa"] != 2 {
+		t.Errorf("unexpected logger counts: %v", s.Loggers)
+	}
+	if s.FailedWrites != 1 {
+		t.Errorf("got %d failed writes, wanted 1", s.FailedWrites)
+	}
+	if s.Dropped != 3 {
+		t.Errorf("got %d dropped, wanted 3", s.Dropped)
+	}
+
+	c.Publish("logfmtr_test")
+	if v := expvar.Get("logfmtr_test"); v == nil || !strings.Contains(v.String(), `"failed_writes":1`) {
+		t.Errorf("unexpected published value: %v", v)
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/iand/logfmtr/logfmtrsentry
+
+go 1.19
+
+replace github.com/iand/logfmtr => ../
+
+require (
+	github.com/getsentry/sentry-go v0.29.0
+	github.com/iand/logfmtr v0.3.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
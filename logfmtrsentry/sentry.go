@@ -0,0 +1,190 @@
+// Package logfmtrsentry forwards entries written by Error to Sentry.
+//
+// A Hook is added to the hooks of a logger's options. Each entry written by Error is sent to Sentry as
+// an event holding the message, the error, the key/value pairs of the entry and the stack trace of the
+// call to Error:
+//
+//	err := sentry.Init(sentry.ClientOptions{Dsn: dsn})
+//	if err != nil {
+//		// handle error
+//	}
+//	hook := logfmtrsentry.New(logfmtrsentry.DefaultConfig())
+//	logfmtr.RegisterHook(hook)
+//	opts := logfmtr.DefaultOptions()
+//	opts.Hooks = []logfmtr.Hook{hook}
+//	logger := logfmtr.NewWithOptions(opts)
+//	defer logfmtr.Close()
+//
+// Events are sent in the background by the Sentry client. Register the Hook with logfmtr.RegisterHook
+// so that it is flushed by logfmtr.Flush and logfmtr.Close and events are delivered before the program
+// exits.
+package logfmtrsentry
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/iand/logfmtr"
+)
+
+// maxErrorDepth is the number of errors in a chain of wrapped errors that are reported as exceptions.
+const maxErrorDepth = 10
+
+// ErrFlushTimeout is returned by Flush when events could not be delivered within the flush timeout.
+var ErrFlushTimeout = errors.New("logfmtrsentry: timed out flushing events")
+
+// Config holds the settings of a Hook.
+type Config struct {
+	// Hub is the Sentry hub used to capture events. When nil the current hub is used.
+	Hub *sentry.Hub
+
+	// SampleRate is the fraction of entries written by Error that are sent to Sentry, between 0 and 1.
+	// Sampling is applied before any sampling configured in the Sentry client. When zero or negative
+	// every entry is sent.
+	SampleRate float64
+
+	// FlushTimeout is the longest time Flush waits for events to be delivered. When zero or negative
+	// the default of two seconds is used.
+	FlushTimeout time.Duration
+}
+
+// DefaultConfig returns a Config that sends every entry written by Error using the current hub and
+// waits up to two seconds for events to be delivered when flushed.
+func DefaultConfig() Config {
+	return Config{
+		SampleRate:   1,
+		FlushTimeout: defaultFlushTimeout,
+	}
+}
+
+const defaultFlushTimeout = 2 * time.Second
+
+var _ logfmtr.Hook = (*Hook)(nil)
+
+// Hook sends entries written by Error to Sentry. It never prevents an entry from being written.
+type Hook struct {
+	hub     *sentry.Hub
+	rate    float64
+	timeout time.Duration
+}
+
+// New returns a Hook using the supplied configuration. Zero fields of cfg take the values used by
+// DefaultConfig.
+func New(cfg Config) *Hook {
+	hub := cfg.Hub
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 1
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = defaultFlushTimeout
+	}
+	return &Hook{
+		hub:     hub,
+		rate:    cfg.SampleRate,
+		timeout: cfg.FlushTimeout,
+	}
+}
+
+// Apply sends the entry to Sentry if it was written by Error and is selected by sampling.
+func (h *Hook) Apply(e *logfmtr.Entry) bool {
+	if !e.IsError {
+		return true
+	}
+	if h.rate < 1 && rand.Float64() >= h.rate {
+		return true
+	}
+	h.hub.CaptureEvent(h.event(e))
+	return true
+}
+
+// Flush waits for events that have been captured to be delivered to Sentry. It returns ErrFlushTimeout
+// if they were not delivered within the flush timeout.
+func (h *Hook) Flush() error {
+	if h.hub.Client() == nil {
+		return nil
+	}
+	if !h.hub.Flush(h.timeout) {
+		return ErrFlushTimeout
+	}
+	return nil
+}
+
+// event converts an entry to a Sentry event.
+func (h *Hook) event(e *logfmtr.Entry) *sentry.Event {
+	ev := sentry.NewEvent()
+	ev.Level = sentry.LevelError
+	ev.Message = e.Message
+	ev.Logger = e.Name
+	ev.Timestamp = e.Time
+
+	stack := callerStacktrace()
+	if e.Error != nil {
+		ev.SetException(e.Error, maxErrorDepth)
+		// The most recent error is last. Replace the stack trace Sentry captured for it with one that
+		// starts at the call to Error unless the error carried its own.
+		if sentry.ExtractStacktrace(e.Error) == nil {
+			ev.Exception[len(ev.Exception)-1].Stacktrace = stack
+		}
+	} else {
+		ev.Exception = []sentry.Exception{{Value: e.Message, Stacktrace: stack}}
+	}
+
+	for _, kvs := range [][]interface{}{e.Context, e.Values} {
+		for i := 0; i < len(kvs); i += 2 {
+			var v interface{} = "<missing>"
+			if i+1 < len(kvs) {
+				v = kvs[i+1]
+			}
+			ev.Extra[fmt.Sprint(kvs[i])] = extraValue(v)
+		}
+	}
+	if e.Caller != "" {
+		ev.Extra["caller"] = e.Caller
+	}
+	return ev
+}
+
+// extraValue converts a value to one that can be encoded by Sentry. Values other than basic types are
+// formatted as strings.
+func extraValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case error:
+		return vv.Error()
+	case fmt.Stringer:
+		return vv.String()
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+// libraryModules are the packages whose frames are removed from stack traces so that the trace ends at
+// the call to Error.
+var libraryModules = map[string]bool{
+	"github.com/iand/logfmtr":               true,
+	"github.com/iand/logfmtr/logfmtrsentry": true,
+	"github.com/go-logr/logr":               true,
+}
+
+// callerStacktrace returns the current stack trace without the frames of the logging packages.
+func callerStacktrace() *sentry.Stacktrace {
+	st := sentry.NewStacktrace()
+	if st == nil {
+		return nil
+	}
+	frames := st.Frames[:0]
+	for _, f := range st.Frames {
+		if !libraryModules[f.Module] {
+			frames = append(frames, f)
+		}
+	}
+	st.Frames = frames
+	return st
+}
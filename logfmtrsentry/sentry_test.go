@@ -0,0 +1,176 @@
+package logfmtrsentry_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtrsentry"
+)
+
+// transport records the events sent by a Sentry client.
+type transport struct {
+	mu      sync.Mutex
+	events  []*sentry.Event
+	flushes int
+	timeout bool
+}
+
+func (t *transport) Configure(sentry.ClientOptions) {}
+
+func (t *transport) SendEvent(e *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, e)
+}
+
+func (t *transport) Flush(time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushes++
+	return !t.timeout
+}
+
+func (t *transport) Events() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.events
+}
+
+func newHub(t *testing.T, tr *transport) *sentry.Hub {
+	t.Helper()
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: tr})
+	if err != nil {
+		t.Fatalf("unexpected error creating client: %v", err)
+	}
+	return sentry.NewHub(client, sentry.NewScope())
+}
+
+func newOptions(hook logfmtr.Hook) logfmtr.Options {
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = io.Discard
+	opts.Hooks = []logfmtr.Hook{hook}
+	return opts
+}
+
+func TestHook(t *testing.T) {
+	tr := &transport{}
+	cfg := logfmtrsentry.DefaultConfig()
+	cfg.Hub = newHub(t, tr)
+	logger := logfmtr.NewWithOptions(newOptions(logfmtrsentry.New(cfg))).WithName("europa").WithValues("user", "you")
+
+	logger.Info("hello")
+	logger.Error(fmt.Errorf("connecting: %w", io.ErrUnexpectedEOF), "uh oh", "code", 7, "reason", errors.New("trouble"))
+
+	events := tr.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, wanted 1", len(events))
+	}
+	ev := events[0]
+	if ev.Message != "uh oh" {
+		t.Errorf("got message %q, wanted %q", ev.Message, "uh oh")
+	}
+	if ev.Level != sentry.LevelError {
+		t.Errorf("got level %q, wanted %q", ev.Level, sentry.LevelError)
+	}
+	if ev.Logger != "europa" {
+		t.Errorf("got logger %q, wanted %q", ev.Logger, "europa")
+	}
+	wantExtra := map[string]interface{}{"user": "you", "code": 7, "reason": "trouble"}
+	for k, v := range wantExtra {
+		if ev.Extra[k] != v {
+			t.Errorf("got extra %s=%v, wanted %v", k, ev.Extra[k], v)
+		}
+	}
+
+	if len(ev.Exception) != 2 {
+		t.Fatalf("got %d exceptions, wanted 2", len(ev.Exception))
+	}
+	if got, want := ev.Exception[0].Value, io.ErrUnexpectedEOF.Error(); got != want {
+		t.Errorf("got cause %q, wanted %q", got, want)
+	}
+	last := ev.Exception[1]
+	if got, want := last.Value, "connecting: unexpected EOF"; got != want {
+		t.Errorf("got error %q, wanted %q", got, want)
+	}
+	if last.Stacktrace == nil || len(last.Stacktrace.Frames) == 0 {
+		t.Fatalf("got no stack trace")
+	}
+	top := last.Stacktrace.Frames[len(last.Stacktrace.Frames)-1]
+	if top.Function != "TestHook" {
+		t.Errorf("got top frame %s.%s, wanted TestHook", top.Module, top.Function)
+	}
+}
+
+func TestHookNilError(t *testing.T) {
+	tr := &transport{}
+	cfg := logfmtrsentry.DefaultConfig()
+	cfg.Hub = newHub(t, tr)
+	logger := logfmtr.NewWithOptions(newOptions(logfmtrsentry.New(cfg)))
+
+	logger.Error(nil, "goodbye")
+
+	events := tr.Events()
+	if len(events) != 1 {
+		t.Fatalf("got %d events, wanted 1", len(events))
+	}
+	if len(events[0].Exception) != 1 || events[0].Exception[0].Value != "goodbye" {
+		t.Errorf("got exceptions %+v, wanted one with value goodbye", events[0].Exception)
+	}
+}
+
+func TestHookSampleRate(t *testing.T) {
+	tr := &transport{}
+	cfg := logfmtrsentry.DefaultConfig()
+	cfg.Hub = newHub(t, tr)
+	cfg.SampleRate = 1e-9
+	logger := logfmtr.NewWithOptions(newOptions(logfmtrsentry.New(cfg)))
+
+	for i := 0; i < 10; i++ {
+		logger.Error(nil, "goodbye")
+	}
+	if n := len(tr.Events()); n != 0 {
+		t.Errorf("got %d events, wanted none", n)
+	}
+}
+
+func TestHookZeroConfig(t *testing.T) {
+	tr := &transport{}
+	hook := logfmtrsentry.New(logfmtrsentry.Config{Hub: newHub(t, tr)})
+	logger := logfmtr.NewWithOptions(newOptions(hook))
+
+	logger.Error(nil, "goodbye")
+	if n := len(tr.Events()); n != 1 {
+		t.Errorf("got %d events, wanted 1", n)
+	}
+	if err := hook.Flush(); err != nil {
+		t.Errorf("unexpected error flushing: %v", err)
+	}
+}
+
+func TestHookFlush(t *testing.T) {
+	tr := &transport{}
+	cfg := logfmtrsentry.DefaultConfig()
+	cfg.Hub = newHub(t, tr)
+	hook := logfmtrsentry.New(cfg)
+	defer logfmtr.RegisterHook(hook)()
+	logger := logfmtr.NewWithOptions(newOptions(hook))
+
+	logger.Error(nil, "goodbye")
+	if err := logfmtr.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+	if tr.flushes != 1 {
+		t.Errorf("got %d flushes, wanted 1", tr.flushes)
+	}
+
+	tr.timeout = true
+	if err := hook.Flush(); err != logfmtrsentry.ErrFlushTimeout {
+		t.Errorf("got error %v, wanted %v", err, logfmtrsentry.ErrFlushTimeout)
+	}
+}
@@ -0,0 +1,60 @@
+package logfmtrtest
+
+import (
+	"io"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+)
+
+var _ logfmtr.Encoder = (*Recorder)(nil)
+
+// Recorder records the entries written by loggers so that tests can make assertions about the level,
+// name, message, error and key/value pairs of each entry without matching formatted output. Recorder
+// implements logfmtr.Encoder so it may also be used as the Encoder of a logger created with custom
+// options. A Recorder is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []logfmtr.Entry
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Logger returns a logger that records its entries in the recorder. Entries include the location of
+// the logging call in their Caller field. As with other logfmtr loggers, entries above the global
+// verbosity are not written.
+func (r *Recorder) Logger() logr.Logger {
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = io.Discard
+	opts.AddCaller = true
+	opts.Encoder = r
+	return logfmtr.NewWithOptions(opts)
+}
+
+// EncodeEntry records the entry. Nothing is written to w.
+func (r *Recorder) EncodeEntry(w io.Writer, e logfmtr.Entry) error {
+	e.Context = append([]interface{}(nil), e.Context...)
+	e.Values = append([]interface{}(nil), e.Values...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+// Entries returns a copy of the entries recorded so far, in the order they were written.
+func (r *Recorder) Entries() []logfmtr.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]logfmtr.Entry(nil), r.entries...)
+}
+
+// Reset discards all recorded entries.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
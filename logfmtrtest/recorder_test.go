@@ -0,0 +1,47 @@
+package logfmtrtest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/iand/logfmtr/logfmtrtest"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := logfmtrtest.NewRecorder()
+	logger := rec.Logger().WithName("europa").WithValues("moon", "io")
+
+	errNoMoon := errors.New("no moon")
+	logger.Info("hello", "val", 1)
+	logger.Error(errNoMoon, "goodbye")
+	logger.V(1).Info("hidden")
+
+	entries := rec.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, wanted 2", len(entries))
+	}
+
+	e := entries[0]
+	if e.Level != 0 || e.Name != "europa" || e.Message != "hello" || e.IsError {
+		t.Errorf("got entry %+v, wanted info entry from europa with message hello", e)
+	}
+	if v, ok := e.Lookup("val"); !ok || v != 1 {
+		t.Errorf("got val=%v, wanted 1", v)
+	}
+	if v, ok := e.Lookup("moon"); !ok || v != "io" {
+		t.Errorf("got moon=%v, wanted io", v)
+	}
+	if e.Caller == "" {
+		t.Errorf("got empty caller")
+	}
+
+	e = entries[1]
+	if !e.IsError || e.Error != errNoMoon || e.Message != "goodbye" {
+		t.Errorf("got entry %+v, wanted error entry with message goodbye", e)
+	}
+
+	rec.Reset()
+	if got := len(rec.Entries()); got != 0 {
+		t.Errorf("got %d entries after reset, wanted 0", got)
+	}
+}
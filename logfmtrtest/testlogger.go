@@ -0,0 +1,39 @@
+// Package logfmtrtest provides loggers for use in tests.
+package logfmtrtest
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+)
+
+// NewTestLogger returns a logger that writes each entry to t.Log using the supplied options, so that
+// entries are associated with the test that wrote them and are only shown when the test fails or the
+// test binary is run with -v. The Writer and ErrorWriter fields of opts are replaced. Set AddCaller in
+// opts to include the location of each logging call in the entry. Entries logged after the test has
+// completed are discarded.
+func NewTestLogger(t testing.TB, opts logfmtr.Options) logr.Logger {
+	var done int32
+	t.Cleanup(func() { atomic.StoreInt32(&done, 1) })
+
+	w := &testWriter{t: t, done: &done}
+	opts.Writer = w
+	opts.ErrorWriter = w
+	return logfmtr.NewWithOptions(opts)
+}
+
+// testWriter writes each entry to t.Log until the test has completed.
+type testWriter struct {
+	t    testing.TB
+	done *int32
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(w.done) == 0 {
+		w.t.Log(string(bytes.TrimSuffix(p, []byte{'\n'})))
+	}
+	return len(p), nil
+}
@@ -0,0 +1,59 @@
+package logfmtrtest_test
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtrtest"
+)
+
+// fakeTB records the output of Log and runs cleanup functions on demand.
+type fakeTB struct {
+	testing.TB
+	logs     []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Log(args ...interface{}) {
+	tb.logs = append(tb.logs, fmt.Sprint(args...))
+}
+
+func (tb *fakeTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+func (tb *fakeTB) finish() {
+	for i := len(tb.cleanups) - 1; i >= 0; i-- {
+		tb.cleanups[i]()
+	}
+}
+
+func TestNewTestLogger(t *testing.T) {
+	tb := &fakeTB{}
+	opts := logfmtr.DefaultOptions()
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	logger := logfmtrtest.NewTestLogger(tb, opts).WithName("europa")
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("hello", "val", 1)
+	logger.Error(errors.New("no moon"), "goodbye")
+	tb.finish()
+	logger.Info("after test")
+
+	want := []string{
+		fmt.Sprintf("level=0 logger=europa msg=hello caller=testlogger_test.go:%d val=1", line+1),
+		fmt.Sprintf("level=0 logger=europa msg=goodbye caller=testlogger_test.go:%d error=\"no moon\"", line+2),
+	}
+	if len(tb.logs) != len(want) {
+		t.Fatalf("got %d logs, wanted %d: %q", len(tb.logs), len(want), tb.logs)
+	}
+	for i := range want {
+		if tb.logs[i] != want[i] {
+			t.Errorf("log %d: got %q, wanted %q", i, tb.logs[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestSetLoggerOptions(t *testing.T) {
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+	defer logfmtr.ClearLoggerOptions("http.access")
+
+	var global, access bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &global
+	opts.TimestampFormat = ""
+	opts.DefaultFields = []interface{}{"app", "test"}
+	logfmtr.UseOptions(opts)
+
+	aopts := logfmtr.DefaultOptions()
+	aopts.Writer = &access
+	aopts.Encoder = &logfmtr.JSONEncoder{}
+	logfmtr.SetLoggerOptions("http.access", aopts)
+
+	h := logfmtr.NewNamed("http").WithValues("server", "main")
+	h.Info("listening")
+	h.WithName("access").Info("request", "path", "/")
+	h.WithName("access").WithName("slow").Info("request", "path", "/big")
+	logfmtr.NewNamed("http.access").Info("direct")
+
+	wantGlobal := "level=0 logger=http msg=listening app=test server=main\n"
+	if got := global.String(); got != wantGlobal {
+		t.Errorf("got %q, wanted %q", got, wantGlobal)
+	}
+	wantAccess := `{"level":0,"logger":"http.access","msg":"request","server":"main","path":"/"}` + "\n" +
+		`{"level":0,"logger":"http.access.slow","msg":"request","server":"main","path":"/big"}` + "\n" +
+		`{"level":0,"logger":"http.access","msg":"direct"}` + "\n"
+	if got := access.String(); got != wantAccess {
+		t.Errorf("got %q, wanted %q", got, wantAccess)
+	}
+}
+
+func TestSetLoggerOptionsExplicitOptions(t *testing.T) {
+	defer logfmtr.ClearLoggerOptions("db")
+
+	var explicit, db bytes.Buffer
+	logfmtr.SetLoggerOptions("db", logfmtr.DefaultOptions().With(logfmtr.WithWriter(&db)))
+
+	logger := logfmtr.NewWith(logfmtr.WithWriter(&explicit), logfmtr.WithTimestampFormat(""))
+	logger.WithName("db").Info("query")
+
+	if want := "level=0 logger=db msg=query\n"; explicit.String() != want {
+		t.Errorf("got %q, wanted %q", explicit.String(), want)
+	}
+	if db.Len() != 0 {
+		t.Errorf("got %q written with the options set for the name, wanted nothing", db.String())
+	}
+}
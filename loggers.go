@@ -0,0 +1,87 @@
+package logfmtr
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// loggers holds a *loggerRecord for each name of an instantiated logger.
+var loggers sync.Map
+
+// loggerRecord records the name delimiter and value keys used by instantiated loggers with the same name.
+type loggerRecord struct {
+	delim string
+	keys  sync.Map // keys of values, each holding true
+}
+
+// LoggerInfo describes the instantiated loggers with a particular name.
+type LoggerInfo struct {
+	// Name is the name of the logger.
+	Name string `json:"name"`
+
+	// Verbosity is the log level that applies to the logger, taking into account any per-logger override.
+	Verbosity int `json:"verbosity"`
+
+	// Disabled reports whether the logger has been disabled by DisableLogger.
+	Disabled bool `json:"disabled"`
+
+	// Keys lists the keys of values added to loggers with this name by WithValues or DefaultFields.
+	Keys []string `json:"keys"`
+}
+
+// Loggers returns information about the loggers that have been instantiated, ordered by name. Loggers with
+// the same name are reported once, with the keys of all their values.
+func Loggers() []LoggerInfo {
+	disabled := disabledLoggers.Load().(*loggerSet)
+	infos := []LoggerInfo{}
+	loggers.Range(func(key, value interface{}) bool {
+		name, rec := key.(string), value.(*loggerRecord)
+		c := core{name: name}
+		info := LoggerInfo{
+			Name:      name,
+			Verbosity: c.verbosity(),
+			Disabled:  name != "" && atomic.LoadInt32(&anyDisabled) != 0 && disabled.contains(name, rec.delim),
+			Keys:      []string{},
+		}
+		rec.keys.Range(func(k, _ interface{}) bool {
+			info.Keys = append(info.Keys, k.(string))
+			return true
+		})
+		sort.Strings(info.Keys)
+		infos = append(infos, info)
+		return true
+	})
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// registerLogger records the name of an instantiated core and the keys of its values from index start
+// onwards. Keys that have already been recorded are found without locking, so that loggers derived for
+// each request by WithValues do not contend with each other.
+func registerLogger(c *core, start int) {
+	v, ok := loggers.Load(c.name)
+	if !ok {
+		v, _ = loggers.LoadOrStore(c.name, &loggerRecord{delim: c.nameDelim})
+	}
+	rec := v.(*loggerRecord)
+	for i := start; i < len(c.values); i += 2 {
+		k := rawString(c.values[i])
+		if _, ok := rec.keys.Load(k); !ok {
+			rec.keys.Store(k, true)
+		}
+	}
+}
+
+// registerChild records an instantiated core derived from the parent core pc. Only the values added
+// to the core are recorded unless its name differs from the parent's.
+func registerChild(c, pc *core) {
+	switch {
+	case c.name != pc.name:
+		registerLogger(c, 0)
+	case len(c.values) > len(pc.values):
+		registerLogger(c, len(pc.values))
+	}
+}
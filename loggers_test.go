@@ -0,0 +1,41 @@
+package logfmtr_test
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestLoggers(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+	defer logfmtr.ClearLoggerVerbosity("registry.io")
+	defer logfmtr.EnableLogger("registry.n*")
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = io.Discard
+	base := logfmtr.NewWithOptions(opts).WithName("registry")
+	base.WithName("io").WithValues("file", "a.txt").Info("read")
+	base.WithName("io").WithValues("bytes", 10).Info("read")
+	base.WithName("net").Info("dial")
+	base.WithName("unused")
+
+	logfmtr.SetVerbosity(1)
+	logfmtr.SetLoggerVerbosity("registry.io", 3)
+	logfmtr.DisableLogger("registry.n*")
+
+	var got []logfmtr.LoggerInfo
+	for _, info := range logfmtr.Loggers() {
+		if info.Name == "registry.io" || info.Name == "registry.net" || info.Name == "registry.unused" {
+			got = append(got, info)
+		}
+	}
+	want := []logfmtr.LoggerInfo{
+		{Name: "registry.io", Verbosity: 3, Disabled: false, Keys: []string{"bytes", "file"}},
+		{Name: "registry.net", Verbosity: 1, Disabled: true, Keys: []string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package logfmtr
+
+import (
+	"errors"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// truncateValues returns the entry with each key/value pair value and error longer than max bytes
+// truncated. The key/value slices are copied before they are changed since they may be shared with
+// other entries.
+func truncateValues(e Entry, max int) Entry {
+	truncate := func(v interface{}) (interface{}, bool) { return truncateValue(v, max) }
+	e.Context = mapValues(e.Context, truncate)
+	e.Values = mapValues(e.Values, truncate)
+	if e.Error != nil {
+		if s, ok := truncateString(e.Error.Error(), max); ok {
+			e.Error = errors.New(s)
+		}
+	}
+	copied := false
+	for i, err := range e.Causes {
+		if s, ok := truncateString(err.Error(), max); ok {
+			if !copied {
+				e.Causes = append([]error(nil), e.Causes...)
+				copied = true
+			}
+			e.Causes[i] = errors.New(s)
+		}
+	}
+	return e
+}
+
+// truncateValue returns the string form of v truncated to max bytes and reports whether it was longer
+// than max. Numbers, booleans, times and durations are never truncated.
+func truncateValue(v interface{}, max int) (interface{}, bool) {
+	switch vv := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64, complex64, complex128, time.Time, time.Duration:
+		return "", false
+	case string:
+		return truncateString(vv, max)
+	default:
+		return truncateString(rawString(v), max)
+	}
+}
+
+// truncateString returns s cut to at most max bytes, without splitting a rune, followed by a marker
+// giving the number of bytes removed. It reports false and returns s unchanged if s is not longer
+// than max.
+func truncateString(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	n := max
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "...(truncated, " + strconv.Itoa(len(s)-n) + " bytes)", true
+}
@@ -0,0 +1,36 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestMaxValueLen(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.MaxValueLen = 8
+	opts.ExpandErrors = true
+	logger := logfmtr.NewWithOptions(opts)
+
+	body := strings.Repeat("x", 1000)
+	ctx := []interface{}{"request", "abcdefghij"}
+	logger.WithValues(ctx...).Info("hello", "body", body, "count", 1234567890123, "short", "abc", "bytes", []byte("0123456789"))
+	logger.Error(fmt.Errorf("wrapped: %w", errors.New("disk is full")), "goodbye", "emoji", "abcdefg€")
+
+	want := `level=0 msg=hello request="abcdefgh...(truncated, 2 bytes)" body="xxxxxxxx...(truncated, 992 bytes)" count=1234567890123 short=abc bytes="[48 49 5...(truncated, 23 bytes)"` + "\n" +
+		`level=0 msg=goodbye error="wrapped:...(truncated, 13 bytes)" error.cause="disk is ...(truncated, 4 bytes)" emoji="abcdefg...(truncated, 3 bytes)"` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	if ctx[1] != "abcdefghij" {
+		t.Errorf("context values were modified, got %q", ctx[1])
+	}
+}
@@ -0,0 +1,53 @@
+package logfmtr
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// NilErrorMode selects how the error field is written for entries written by Error with a nil error.
+type NilErrorMode int
+
+const (
+	// NilErrorString writes a nil error as <nil> in logfmt and human friendly output and as null in JSON.
+	NilErrorString NilErrorMode = iota
+
+	// NilErrorOmit omits the error field when the error is nil.
+	NilErrorOmit
+
+	// NilErrorNull writes a nil error as null.
+	NilErrorNull
+)
+
+// String returns the name of the mode.
+func (m NilErrorMode) String() string {
+	switch m {
+	case NilErrorString:
+		return "string"
+	case NilErrorOmit:
+		return "omit"
+	case NilErrorNull:
+		return "null"
+	default:
+		return fmt.Sprintf("NilErrorMode(%d)", int(m))
+	}
+}
+
+// writeError reports whether the error field of the entry should be written.
+func (m NilErrorMode) writeError(e Entry) bool {
+	return e.IsError && (e.Error != nil || m != NilErrorOmit)
+}
+
+// value returns the value written for the error err.
+func (m NilErrorMode) value(err error) interface{} {
+	if err == nil && m == NilErrorNull {
+		return "null"
+	}
+	return err
+}
+
+// nilPointer reports whether v is a nil pointer, whose methods may panic when called.
+func nilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
@@ -0,0 +1,90 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestNilError(t *testing.T) {
+	testCases := []struct {
+		mode     logfmtr.NilErrorMode
+		want     string
+		wantJSON string
+	}{
+		{
+			mode:     logfmtr.NilErrorString,
+			want:     "level=0 msg=failed error=<nil> a=1\n",
+			wantJSON: `{"level":0,"msg":"failed","error":null,"a":1}` + "\n",
+		},
+		{
+			mode:     logfmtr.NilErrorOmit,
+			want:     "level=0 msg=failed a=1\n",
+			wantJSON: `{"level":0,"msg":"failed","a":1}` + "\n",
+		},
+		{
+			mode:     logfmtr.NilErrorNull,
+			want:     "level=0 msg=failed error=null a=1\n",
+			wantJSON: `{"level":0,"msg":"failed","error":null,"a":1}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.NilError = tc.mode
+			logfmtr.NewWithOptions(opts).Error(nil, "failed", "a", 1)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+
+			buf.Reset()
+			opts.Encoder = &logfmtr.JSONEncoder{NilError: tc.mode}
+			logfmtr.NewWithOptions(opts).Error(nil, "failed", "a", 1)
+
+			if got := buf.String(); got != tc.wantJSON {
+				t.Errorf("got json %q, wanted %q", got, tc.wantJSON)
+			}
+		})
+	}
+}
+
+type nilStringer struct{ name string }
+
+func (s *nilStringer) String() string { return s.name }
+
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+
+func TestNilPointerValues(t *testing.T) {
+	var s *nilStringer
+	var err *nilError
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+	logger.Error(err, "failed", "s", s, "err", err)
+
+	want := "level=0 msg=failed error=<nil> s=<nil> err=<nil>\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	buf.Reset()
+	opts.Encoder = &logfmtr.JSONEncoder{}
+	logger = logfmtr.NewWithOptions(opts)
+	logger.Error(err, "failed", "s", s, "err", err)
+
+	wantJSON := `{"level":0,"msg":"failed","error":null,"s":null,"err":null}` + "\n"
+	if got := buf.String(); got != wantJSON {
+		t.Errorf("got json %q, wanted %q", got, wantJSON)
+	}
+}
@@ -0,0 +1,79 @@
+package logfmtr
+
+import "fmt"
+
+// OddArgsMode selects how a list of key/value pairs with an odd number of items is written, which is
+// usually a programming mistake.
+type OddArgsMode int
+
+const (
+	// OddArgsEmptyValue writes the final key with an empty value.
+	OddArgsEmptyValue OddArgsMode = iota
+
+	// OddArgsBadKey writes the final item as the value of the key !BADKEY, matching log/slog.
+	OddArgsBadKey
+
+	// OddArgsError writes the final key with an empty value followed by a logfmtr_error field reporting
+	// the odd number of arguments.
+	OddArgsError
+)
+
+// String returns the name of the mode.
+func (m OddArgsMode) String() string {
+	switch m {
+	case OddArgsEmptyValue:
+		return "empty"
+	case OddArgsBadKey:
+		return "badkey"
+	case OddArgsError:
+		return "error"
+	default:
+		return fmt.Sprintf("OddArgsMode(%d)", int(m))
+	}
+}
+
+const (
+	// badKey is the key given to the final item of an odd number of arguments by OddArgsBadKey.
+	badKey = "!BADKEY"
+
+	// oddArgsErrorKey is the key of the field added by OddArgsError.
+	oddArgsErrorKey = "logfmtr_error"
+)
+
+// fixOddArgs returns the entry with any key/value lists that have an odd number of items completed
+// according to mode.
+func fixOddArgs(e Entry, mode OddArgsMode) Entry {
+	e.Context = fixOddKVs(e.Context, mode)
+	e.Values = fixOddKVs(e.Values, mode)
+	return e
+}
+
+func fixOddKVs(kvs []interface{}, mode OddArgsMode) []interface{} {
+	n := len(kvs)
+	if n%2 == 0 {
+		return kvs
+	}
+	switch mode {
+	case OddArgsBadKey:
+		fixed := make([]interface{}, 0, n+1)
+		fixed = append(fixed, kvs[:n-1]...)
+		return append(fixed, badKey, kvs[n-1])
+	case OddArgsError:
+		return append(kvs[:n:n], "", oddArgsErrorKey, "odd number of arguments")
+	default:
+		return kvs
+	}
+}
+
+// completeOddKVs returns kvs with the final key of an odd number of items given a value according to
+// mode, so that pairs added to the same list later keep their keys and values aligned.
+func completeOddKVs(kvs []interface{}, mode OddArgsMode) []interface{} {
+	n := len(kvs)
+	if n%2 == 0 {
+		return kvs
+	}
+	if mode == OddArgsEmptyValue {
+		return append(kvs[:n:n], "")
+	}
+	return fixOddKVs(kvs, mode)
+}
@@ -0,0 +1,84 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestOddArgs(t *testing.T) {
+	testCases := []struct {
+		mode logfmtr.OddArgsMode
+		want string
+	}{
+		{
+			mode: logfmtr.OddArgsEmptyValue,
+			want: "level=0 msg=hello a=1 b=\nlevel=0 msg=again ctx= c=3\n",
+		},
+		{
+			mode: logfmtr.OddArgsBadKey,
+			want: "level=0 msg=hello a=1 !BADKEY=b\nlevel=0 msg=again !BADKEY=ctx c=3\n",
+		},
+		{
+			mode: logfmtr.OddArgsError,
+			want: "level=0 msg=hello a=1 b= logfmtr_error=\"odd number of arguments\"\n" +
+				"level=0 msg=again ctx= logfmtr_error=\"odd number of arguments\" c=3\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.OddArgs = tc.mode
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("hello", "a", 1, "b")
+			logger.WithValues("ctx").Info("again", "c", 3)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOddArgsChainedWithValues(t *testing.T) {
+	testCases := []struct {
+		mode logfmtr.OddArgsMode
+		want string
+	}{
+		{
+			mode: logfmtr.OddArgsEmptyValue,
+			want: "level=0 msg=hi a= b=1 c=2\n",
+		},
+		{
+			mode: logfmtr.OddArgsBadKey,
+			want: "level=0 msg=hi !BADKEY=a b=1 c=2\n",
+		},
+		{
+			mode: logfmtr.OddArgsError,
+			want: "level=0 msg=hi a= logfmtr_error=\"odd number of arguments\" b=1 c=2\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.mode.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.OddArgs = tc.mode
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.WithValues("a").WithValues("b", 1).Info("hi", "c", 2)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package logfmtr
+
+import (
+	"io"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// An Option changes a field of Options. Options are used with NewWith as an alternative to setting
+// fields of an Options struct.
+type Option func(*Options)
+
+// NewWith returns a new logger that uses DefaultOptions modified by ops, applied in order.
+func NewWith(ops ...Option) logr.Logger {
+	return NewWithOptions(DefaultOptions().With(ops...))
+}
+
+// With returns a copy of the options modified by ops, applied in order.
+func (o Options) With(ops ...Option) Options {
+	for _, op := range ops {
+		op(&o)
+	}
+	return o
+}
+
+// WithWriter sets the writer that logs are written to.
+func WithWriter(w io.Writer) Option {
+	return func(o *Options) { o.Writer = w }
+}
+
+// WithErrorWriter sets the writer that logs written by calls to Error are written to.
+func WithErrorWriter(w io.Writer) Option {
+	return func(o *Options) { o.ErrorWriter = w }
+}
+
+// WithSerializeWrites guards writers with a mutex shared by all loggers that serialize writes to them,
+// so that entries written concurrently to writers whose writes are not atomic cannot interleave.
+func WithSerializeWrites() Option {
+	return func(o *Options) { o.SerializeWrites = true }
+}
+
+// WithOnWriteError sets a function that is called with the error and the encoded entry when writing an
+// entry fails.
+func WithOnWriteError(fn func(err error, entry []byte)) Option {
+	return func(o *Options) { o.OnWriteError = fn }
+}
+
+// WithWriteErrorFallback writes entries to os.Stderr when writing them fails.
+func WithWriteErrorFallback() Option {
+	return func(o *Options) { o.WriteErrorFallback = true }
+}
+
+// WithHumanize changes the log output to a human friendly format.
+func WithHumanize() Option {
+	return func(o *Options) { o.Humanize = true }
+}
+
+// WithColorize changes the log output to a human friendly format with color.
+func WithColorize() Option {
+	return func(o *Options) {
+		o.Humanize = true
+		o.Colorize = true
+	}
+}
+
+// WithColorMode changes the log output to a human friendly format that is colored according to m.
+func WithColorMode(m ColorMode) Option {
+	return func(o *Options) {
+		o.Humanize = true
+		o.ColorMode = m
+	}
+}
+
+// WithOddArgs sets how key/value pairs with an odd number of items are written.
+func WithOddArgs(m OddArgsMode) Option {
+	return func(o *Options) {
+		o.OddArgs = m
+	}
+}
+
+// WithNilError sets how the error field is written for entries written by Error with a nil error.
+func WithNilError(m NilErrorMode) Option {
+	return func(o *Options) {
+		o.NilError = m
+	}
+}
+
+// WithKeyMode sets how keys that are not strings or contain characters that are not permitted are written.
+func WithKeyMode(m KeyMode) Option {
+	return func(o *Options) {
+		o.KeyMode = m
+	}
+}
+
+// WithDuplicateKeys sets how key/value pairs that share a key are written.
+func WithDuplicateKeys(d DuplicateKeys) Option {
+	return func(o *Options) {
+		o.DuplicateKeys = d
+	}
+}
+
+// WithFlattenDepth writes map and struct values as a pair for each of their entries or fields, nested up
+// to depth levels.
+func WithFlattenDepth(depth int) Option {
+	return func(o *Options) {
+		o.FlattenDepth = depth
+	}
+}
+
+// WithComplexValueJSON writes map, slice, array and struct values as compact JSON.
+func WithComplexValueJSON() Option {
+	return func(o *Options) {
+		o.ComplexValueJSON = true
+	}
+}
+
+// WithBytesFormat sets how []byte values of key/value pairs are written.
+func WithBytesFormat(f BytesFormat) Option {
+	return func(o *Options) {
+		o.BytesFormat = f
+	}
+}
+
+// WithDurationFormat sets how time.Duration values of key/value pairs are written.
+func WithDurationFormat(f DurationFormat) Option {
+	return func(o *Options) {
+		o.DurationFormat = f
+	}
+}
+
+// WithTimeFormat sets how time.Time values of key/value pairs are written.
+func WithTimeFormat(f TimeFormat) Option {
+	return func(o *Options) {
+		o.TimeFormat = f
+	}
+}
+
+// WithFloatFormat sets the fmt verb, such as %.6g, used to write floating point values of key/value pairs.
+func WithFloatFormat(format string) Option {
+	return func(o *Options) {
+		o.FloatFormat = format
+	}
+}
+
+// WithValueTransform registers a transform for the values of key/value pairs with the given key,
+// replacing any transform already registered for the key.
+func WithValueTransform(key string, t Transform) Option {
+	return func(o *Options) {
+		transforms := make(map[string]Transform, len(o.ValueTransforms)+1)
+		for k, v := range o.ValueTransforms {
+			transforms[k] = v
+		}
+		transforms[key] = t
+		o.ValueTransforms = transforms
+	}
+}
+
+// WithHashedKeys replaces the values of key/value pairs with the given keys with a salted hash, using
+// HashTransform, so that entries remain correlatable without exposing the values.
+func WithHashedKeys(salt []byte, keys ...string) Option {
+	return func(o *Options) {
+		t := HashTransform(salt)
+		for _, key := range keys {
+			WithValueTransform(key, t)(o)
+		}
+	}
+}
+
+// WithMaxValueLen truncates values of key/value pairs and errors that are longer than n bytes.
+func WithMaxValueLen(n int) Option {
+	return func(o *Options) {
+		o.MaxValueLen = n
+	}
+}
+
+// WithMaxFields limits the number of key/value pairs written with each entry to n, dropping the excess.
+func WithMaxFields(n int) Option {
+	return func(o *Options) {
+		o.MaxFields = n
+	}
+}
+
+// WithMaxEntryBytes limits the size of each encoded entry to n bytes by dropping key/value pairs.
+func WithMaxEntryBytes(n int) Option {
+	return func(o *Options) {
+		o.MaxEntryBytes = n
+	}
+}
+
+// WithLinePrefix writes prefix at the start of each line of output.
+func WithLinePrefix(prefix string) Option {
+	return func(o *Options) {
+		o.LinePrefix = prefix
+	}
+}
+
+// WithLineSuffix writes suffix at the end of each line of output, before the line terminator.
+func WithLineSuffix(suffix string) Option {
+	return func(o *Options) {
+		o.LineSuffix = suffix
+	}
+}
+
+// WithLinePrefixFunc writes the text returned by fn for each entry at the start of each of its lines.
+func WithLinePrefixFunc(fn func(e Entry) string) Option {
+	return func(o *Options) {
+		o.LinePrefixFunc = fn
+	}
+}
+
+// WithLineSuffixFunc writes the text returned by fn for each entry at the end of each of its lines, before
+// the line terminator.
+func WithLineSuffixFunc(fn func(e Entry) string) Option {
+	return func(o *Options) {
+		o.LineSuffixFunc = fn
+	}
+}
+
+// WithTemplate writes each entry using the text/template text in place of logfmt. See TemplateEncoder for
+// the fields and functions available to the template.
+func WithTemplate(text string) Option {
+	return func(o *Options) {
+		o.Template = text
+	}
+}
+
+// WithTimestampFormat sets the format for log timestamps. An empty format disables timestamps.
+func WithTimestampFormat(format string) Option {
+	return func(o *Options) { o.TimestampFormat = format }
+}
+
+// WithHumanTimestampFormat sets the format for timestamps when the output is humanized.
+func WithHumanTimestampFormat(format string) Option {
+	return func(o *Options) { o.HumanTimestampFormat = format }
+}
+
+// WithTimestampMode sets how timestamps are written.
+func WithTimestampMode(mode TimestampMode) Option {
+	return func(o *Options) { o.TimestampMode = mode }
+}
+
+// WithTimeLocation sets the time zone used for timestamps.
+func WithTimeLocation(loc *time.Location) Option {
+	return func(o *Options) { o.TimeLocation = loc }
+}
+
+// WithLocalTime writes timestamps in the local time zone.
+func WithLocalTime() Option {
+	return func(o *Options) { o.UseLocalTime = true }
+}
+
+// WithNameDelim sets the delimiter used when appending names of loggers.
+func WithNameDelim(delim string) Option {
+	return func(o *Options) { o.NameDelim = delim }
+}
+
+// WithKeyPrefixFromName qualifies the keys of key/value pairs added to a named logger with its name, so
+// that a logger named http writes keys such as http.latency.
+func WithKeyPrefixFromName() Option {
+	return func(o *Options) { o.KeyPrefixFromName = true }
+}
+
+// WithCaller adds the file and line number of the caller of the logger to each entry, skipping an
+// additional skip frames when the logger is wrapped by another logger.
+func WithCaller(skip int) Option {
+	return func(o *Options) {
+		o.AddCaller = true
+		o.CallerSkip = skip
+	}
+}
+
+// WithCallerFormat sets how the caller is written and whether the calling function is included.
+// It does not enable AddCaller.
+func WithCallerFormat(format CallerFormat, fn bool) Option {
+	return func(o *Options) {
+		o.CallerFormat = format
+		o.CallerFunc = fn
+	}
+}
+
+// WithStacktrace adds stack traces to entries written by Error when the logger is enabled for at least
+// the given verbosity.
+func WithStacktrace(verbosity int) Option {
+	return func(o *Options) {
+		o.AddStacktrace = true
+		o.StacktraceVerbosity = verbosity
+	}
+}
+
+// WithExpandErrors writes each error in a wrapped error chain as a separate field.
+func WithExpandErrors() Option {
+	return func(o *Options) { o.ExpandErrors = true }
+}
+
+// WithExitFunc sets the function called to exit the program after Fatal has written an entry.
+func WithExitFunc(exit func(code int)) Option {
+	return func(o *Options) { o.ExitFunc = exit }
+}
+
+// WithLevelNames sets the names written in place of numeric levels.
+func WithLevelNames(names map[int]string) Option {
+	return func(o *Options) { o.LevelNames = names }
+}
+
+// WithFieldNames sets the keys used for built-in fields.
+func WithFieldNames(names FieldNames) Option {
+	return func(o *Options) { o.FieldNames = names }
+}
+
+// WithGoroutineID adds the id of the goroutine that wrote each entry.
+func WithGoroutineID() Option {
+	return func(o *Options) { o.AddGoroutineID = true }
+}
+
+// WithPID adds the id of the process to each entry.
+func WithPID() Option {
+	return func(o *Options) { o.AddPID = true }
+}
+
+// WithDefaultFields appends key/value pairs that are written with every entry.
+func WithDefaultFields(kvs ...interface{}) Option {
+	return func(o *Options) {
+		o.DefaultFields = append(o.DefaultFields[:len(o.DefaultFields):len(o.DefaultFields)], kvs...)
+	}
+}
+
+// WithOutput adds an additional destination that every entry is written to using enc. When enc is nil
+// the encoder of the logger's Writer is used.
+func WithOutput(w io.Writer, enc Encoder) Option {
+	return func(o *Options) {
+		o.Outputs = append(o.Outputs[:len(o.Outputs):len(o.Outputs)], Output{Writer: w, Encoder: enc})
+	}
+}
+
+// WithTheme sets the colors of human friendly output.
+func WithTheme(t *Theme) Option {
+	return func(o *Options) { o.Theme = t }
+}
+
+// WithHooks appends hooks that are called with each entry before it is encoded.
+func WithHooks(hooks ...Hook) Option {
+	return func(o *Options) {
+		o.Hooks = append(o.Hooks[:len(o.Hooks):len(o.Hooks)], hooks...)
+	}
+}
+
+// WithContextExtractors appends extractors that derive key/value pairs from a context.
+func WithContextExtractors(extractors ...ContextExtractor) Option {
+	return func(o *Options) {
+		o.ContextExtractors = append(o.ContextExtractors[:len(o.ContextExtractors):len(o.ContextExtractors)], extractors...)
+	}
+}
+
+// WithSampler sets the sampler used to limit repeated entries.
+func WithSampler(s *Sampler) Option {
+	return func(o *Options) { o.Sampler = s }
+}
+
+// WithCoalescer sets the coalescer used to collapse runs of identical entries.
+func WithCoalescer(c *Coalescer) Option {
+	return func(o *Options) { o.Coalescer = c }
+}
+
+// WithFlightRecorder sets the flight recorder used to hold verbose entries until an error is written.
+func WithFlightRecorder(fr *FlightRecorder) Option {
+	return func(o *Options) { o.FlightRecorder = fr }
+}
+
+// WithRateLimiter sets the rate limiter used to limit the rate at which each named logger writes entries.
+func WithRateLimiter(rl *RateLimiter) Option {
+	return func(o *Options) { o.RateLimiter = rl }
+}
+
+// WithEncoder sets the encoder used to write log entries.
+func WithEncoder(enc Encoder) Option {
+	return func(o *Options) { o.Encoder = enc }
+}
+
+// WithClock sets the function that returns the time recorded for each entry.
+func WithClock(clock func() time.Time) Option {
+	return func(o *Options) { o.Clock = clock }
+}
@@ -0,0 +1,46 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestNewWith(t *testing.T) {
+	var buf, out bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithOutput(&out, nil),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithNameDelim("/"),
+		logfmtr.WithDefaultFields("app", "test"),
+		logfmtr.WithLevelNames(map[int]string{0: "info"}),
+	).WithName("europa").WithName("moon")
+
+	logger.Info("hello", "val", 1)
+
+	want := "level=info logger=europa/moon msg=hello app=test val=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if got := out.String(); got != want {
+		t.Errorf("got %q written to output, wanted %q", got, want)
+	}
+}
+
+func TestOptionsWith(t *testing.T) {
+	base := logfmtr.DefaultOptions().With(logfmtr.WithDefaultFields("a", 1))
+	o1 := base.With(logfmtr.WithDefaultFields("b", 2))
+	o2 := base.With(logfmtr.WithDefaultFields("c", 3), logfmtr.WithCaller(1))
+
+	if len(base.DefaultFields) != 2 || base.AddCaller {
+		t.Errorf("base options were modified: %+v", base)
+	}
+	if o1.DefaultFields[2] != "b" || o2.DefaultFields[2] != "c" {
+		t.Errorf("got fields %v and %v, wanted independent fields", o1.DefaultFields, o2.DefaultFields)
+	}
+	if !o2.AddCaller || o2.CallerSkip != 1 {
+		t.Errorf("got AddCaller=%v CallerSkip=%d, wanted true and 1", o2.AddCaller, o2.CallerSkip)
+	}
+}
@@ -0,0 +1,110 @@
+package logfmtr
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate at which entries are written using a token bucket for each logger name.
+// When entries have been dropped, a summary entry reporting the number of entries that were dropped is
+// written once the logger may write again, or before the next entry that is allowed if that is sooner.
+type RateLimiter struct {
+	perSecond float64
+	burst     float64
+	buckets   sync.Map // map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	dropped uint64
+	core    *core       // core that dropped the last entry, used to write the summary
+	timer   *time.Timer // writes the summary when a token is available
+	seq     int         // incremented when timer is replaced so that a stale timer does nothing
+}
+
+// NewRateLimiter returns a RateLimiter that allows each named logger to write perSecond entries per
+// second on average, with bursts of up to burst entries. A burst of less than one is treated as one, so
+// that entries can be written. If perSecond is zero or negative each logger writes at most burst entries.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		perSecond: perSecond,
+		burst:     float64(burst),
+	}
+}
+
+// allow reports whether an entry from the named logger written by c may be written at time now. When the
+// entry is allowed it also returns the number of entries from the logger that were dropped since the last
+// allowed entry and not yet reported.
+func (r *RateLimiter) allow(c *core, name string, now time.Time) (bool, uint64) {
+	v, ok := r.buckets.Load(name)
+	if !ok {
+		v, _ = r.buckets.LoadOrStore(name, &tokenBucket{tokens: r.burst, last: now})
+	}
+	b := v.(*tokenBucket)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * r.perSecond
+		if b.tokens > r.burst {
+			b.tokens = r.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		b.dropped++
+		b.core = c
+		if b.timer == nil && r.perSecond > 0 {
+			wait := time.Duration((1 - b.tokens) / r.perSecond * float64(time.Second))
+			b.seq++
+			seq := b.seq
+			b.timer = time.AfterFunc(wait, func() { r.summarize(b, name, seq) })
+		}
+		return false, 0
+	}
+	b.tokens--
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+		b.seq++
+	}
+	dropped := b.dropped
+	b.dropped = 0
+	b.core = nil
+	return true, dropped
+}
+
+// summarize writes a summary of the entries dropped from the named logger, unless they have already been
+// reported or the timer that called it has been replaced.
+func (r *RateLimiter) summarize(b *tokenBucket, name string, seq int) {
+	b.mu.Lock()
+	if b.seq != seq {
+		b.mu.Unlock()
+		return
+	}
+	b.timer = nil
+	dropped := b.dropped
+	c := b.core
+	b.dropped = 0
+	b.core = nil
+	b.mu.Unlock()
+
+	if dropped > 0 && c != nil {
+		_ = c.encode(rateLimitSummary(c.now(), name, dropped))
+	}
+}
+
+// rateLimitSummary returns an entry reporting that entries from the named logger were dropped.
+func rateLimitSummary(t time.Time, name string, dropped uint64) Entry {
+	return Entry{
+		Time:    t,
+		Name:    name,
+		Message: "rate limit exceeded",
+		Values:  []interface{}{"dropped", dropped},
+	}
+}
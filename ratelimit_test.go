@@ -0,0 +1,84 @@
+package logfmtr_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var buf syncBuffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.RateLimiter = logfmtr.NewRateLimiter(20, 2)
+	logger := logfmtr.NewWithOptions(opts)
+
+	for i := 0; i < 5; i++ {
+		logger.Info("storm", "i", i)
+	}
+	logger.WithName("other").Info("unaffected")
+
+	want := "level=0 msg=storm i=0\n" +
+		"level=0 msg=storm i=1\n" +
+		"level=0 logger=other msg=unaffected\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	// the summary is written once the logger may write again, without waiting for another entry
+	want += "level=0 msg=\"rate limit exceeded\" dropped=3\n"
+	deadline := time.Now().Add(5 * time.Second)
+	for buf.String() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := buf.String(); got != want {
+		t.Fatalf("got %q, wanted %q", got, want)
+	}
+
+	logger.Info("calm")
+	want += "level=0 msg=calm\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestRateLimiterSummaryBeforeAllowedEntry(t *testing.T) {
+	var buf syncBuffer
+	now := time.Unix(1704164645, 0)
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Clock = func() time.Time { return now }
+	// a rate slow enough that the summary timer does not fire during the test
+	opts.RateLimiter = logfmtr.NewRateLimiter(0.001, 1)
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("first")
+	logger.Info("dropped")
+	now = now.Add(time.Hour)
+	logger.Info("second")
+
+	want := "level=0 msg=first\n" +
+		"level=0 msg=\"rate limit exceeded\" dropped=1\n" +
+		"level=0 msg=second\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestRateLimiterZeroBurst(t *testing.T) {
+	var buf syncBuffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.RateLimiter = logfmtr.NewRateLimiter(1, 0)
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("written")
+	if got := buf.String(); !strings.Contains(got, "msg=written") {
+		t.Errorf("got %q, wanted the first entry to be written with a burst of zero", got)
+	}
+}
@@ -0,0 +1,97 @@
+package logfmtr
+
+import (
+	"sync"
+)
+
+// flightRecorderMessage is the message of the marker entry written before recorded entries.
+const flightRecorderMessage = "flight recorder"
+
+// FlightRecorder keeps recent verbose entries in memory and writes them only when an error is logged.
+// Entries with a V level above the logger's verbosity, up to the recorder's maximum level, are held in
+// a ring buffer for each named logger instead of being written. When the logger writes an error entry
+// the buffered entries are written first, preceded by a marker entry with the message "flight recorder"
+// and the number of entries that follow.
+type FlightRecorder struct {
+	maxLevel int
+	size     int
+
+	mu      sync.Mutex
+	buffers map[string]*recordRing // keyed by logger name
+}
+
+// NewFlightRecorder returns a FlightRecorder that records entries with V levels up to and including
+// maxLevel, keeping at most size of the most recent entries for each named logger.
+func NewFlightRecorder(maxLevel int, size int) *FlightRecorder {
+	if size < 1 {
+		size = 1
+	}
+	return &FlightRecorder{
+		maxLevel: maxLevel,
+		size:     size,
+		buffers:  make(map[string]*recordRing),
+	}
+}
+
+// recorded is an entry held by a FlightRecorder along with the core that will write it.
+type recorded struct {
+	c *core
+	e Entry
+}
+
+// recordRing is a fixed size ring buffer of recorded entries.
+type recordRing struct {
+	entries []recorded
+	next    int // index of the slot for the next entry
+	full    bool
+}
+
+// record adds an entry to the ring buffer for the entry's logger.
+func (fr *FlightRecorder) record(c *core, e Entry) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	r, ok := fr.buffers[e.Name]
+	if !ok {
+		r = &recordRing{entries: make([]recorded, fr.size)}
+		fr.buffers[e.Name] = r
+	}
+	r.entries[r.next] = recorded{c: c, e: e}
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// take removes and returns the recorded entries for the named logger, oldest first.
+func (fr *FlightRecorder) take(name string) []recorded {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	r, ok := fr.buffers[name]
+	if !ok {
+		return nil
+	}
+	delete(fr.buffers, name)
+	if !r.full {
+		return r.entries[:r.next]
+	}
+	return append(r.entries[r.next:], r.entries[:r.next]...)
+}
+
+// dump writes the entries recorded for the named logger, preceded by a marker entry.
+func (fr *FlightRecorder) dump(c *core, e Entry) {
+	recs := fr.take(e.Name)
+	if len(recs) == 0 {
+		return
+	}
+	_ = c.output(Entry{
+		Time:    e.Time,
+		Name:    e.Name,
+		Message: flightRecorderMessage,
+		Context: c.values,
+		Values:  []interface{}{"entries", len(recs)},
+	})
+	for _, rec := range recs {
+		_ = rec.c.output(rec.e)
+	}
+}
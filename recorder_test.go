@@ -0,0 +1,47 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.FlightRecorder = logfmtr.NewFlightRecorder(2, 2)
+	logger := logfmtr.NewWithOptions(opts).WithName("europa")
+	other := logfmtr.NewWithOptions(opts).WithName("io")
+
+	logger.Info("starting")
+	logger.V(1).Info("step", "n", 1)
+	logger.V(2).Info("step", "n", 2)
+	logger.V(1).Info("step", "n", 3)
+	logger.V(3).Info("too verbose")
+	other.V(1).Info("unrelated")
+	logger.Error(errors.New("uh oh"), "failed")
+	logger.Error(errors.New("uh oh"), "failed again")
+
+	want := "level=0 logger=europa msg=starting\n" +
+		"level=0 logger=europa msg=\"flight recorder\" entries=2\n" +
+		"level=2 logger=europa msg=step n=2\n" +
+		"level=1 logger=europa msg=step n=3\n" +
+		"level=0 logger=europa msg=failed error=\"uh oh\"\n" +
+		"level=0 logger=europa msg=\"failed again\" error=\"uh oh\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	if !logger.V(2).Enabled() {
+		t.Errorf("got V(2) disabled, wanted enabled for recording")
+	}
+	if logger.V(3).Enabled() {
+		t.Errorf("got V(3) enabled, wanted disabled")
+	}
+}
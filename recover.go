@@ -0,0 +1,56 @@
+package logfmtr
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// recoveredMessage is the message of entries written by Recover and RecoverAndPanic.
+const recoveredMessage = "recovered from panic"
+
+// Recover recovers from a panic and writes it to the logger as an error entry, along with the
+// stack of the panicking goroutine and the given key/value pairs. It must be deferred directly:
+//
+//	defer logfmtr.Recover(logger, "job", id)
+//
+// Panic values that are errors are written as the error of the entry, other values are formatted
+// with fmt. The stack trace is written in the stacktrace field and, when the AddCaller option is
+// set, the caller is the function that panicked.
+func Recover(l logr.Logger, kvs ...interface{}) {
+	if r := recover(); r != nil {
+		logPanic(l, r, kvs)
+	}
+}
+
+// RecoverAndPanic writes a panic to the logger in the same way as Recover and then panics again
+// with the same value. It must be deferred directly.
+func RecoverAndPanic(l logr.Logger, kvs ...interface{}) {
+	if r := recover(); r != nil {
+		logPanic(l, r, kvs)
+		panic(r)
+	}
+}
+
+// logPanic writes an entry for the recovered panic value r.
+func logPanic(l logr.Logger, r interface{}, kvs []interface{}) {
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+	// skip this function and Recover or RecoverAndPanic
+	frames := panicFrames(2)
+	s, ok := l.GetSink().(*sink)
+	if !ok {
+		kvs = append(kvs[:len(kvs):len(kvs)], "stacktrace", formatFrames(frames))
+		l.WithCallDepth(2).Error(err, recoveredMessage, kvs...)
+		return
+	}
+	c := s.getCore()
+	e := c.entry(0, true, recoveredMessage, err, kvs)
+	e.Stacktrace = formatFrames(frames)
+	if c.addCaller && len(frames) > 0 {
+		e.Caller = c.formatCaller(frames[0])
+	}
+	_ = c.emit(e)
+}
@@ -0,0 +1,55 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestRecover(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithCaller(0),
+	)
+
+	func() {
+		defer logfmtr.Recover(logger.WithValues("a", 1), "job", 7)
+		var m map[string]int
+		m["x"] = 1
+	}()
+
+	got := buf.String()
+	want := `level=0 msg="recovered from panic" caller=recover_test.go:23 error="assignment to entry in nil map" ` +
+		`stacktrace="github.com/iand/logfmtr_test.TestRecover.func1\n\t`
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("got %q, wanted prefix %q", got, want)
+	}
+	if !strings.HasSuffix(got, " a=1 job=7\n") {
+		t.Errorf("got %q, wanted suffix %q", got, " a=1 job=7\n")
+	}
+}
+
+func TestRecoverAndPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithTimestampFormat(""),
+	)
+	boom := errors.New("boom")
+
+	defer func() {
+		if r := recover(); r != boom {
+			t.Errorf("got panic value %v, wanted %v", r, boom)
+		}
+		if want := "level=0 msg=\"recovered from panic\" error=boom stacktrace="; !strings.HasPrefix(buf.String(), want) {
+			t.Errorf("got %q, wanted prefix %q", buf.String(), want)
+		}
+	}()
+	defer logfmtr.RecoverAndPanic(logger)
+	panic(boom)
+}
@@ -0,0 +1,97 @@
+package logfmtr
+
+import (
+	"os"
+	"sync"
+)
+
+// A Reopener is a writer that can reopen its destination, such as a file that has been renamed by an
+// external tool like logrotate.
+type Reopener interface {
+	Reopen() error
+}
+
+// FileWriter is a writer that appends to a file and can reopen it, so that a file renamed by logrotate
+// is replaced by a new file at the original path. It is safe for concurrent use and may be used as
+// Options.Writer. See HandleReopen for reopening the file when the program receives SIGHUP.
+type FileWriter struct {
+	path string
+
+	mu         sync.Mutex
+	f          *os.File
+	unregister func() // removes the writer from those flushed by Flush and Close, nil when closed
+}
+
+// NewFileWriter returns a writer that appends to the file at path, creating it if necessary. The file
+// is opened on the first write.
+func NewFileWriter(path string) *FileWriter {
+	return &FileWriter{path: path}
+}
+
+// Write appends p to the file, opening it first if necessary.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		f, err := openLogFile(w.path)
+		if err != nil {
+			return 0, err
+		}
+		w.setFile(f)
+	}
+	return w.f.Write(p)
+}
+
+// Reopen closes the file and opens the file at the same path, creating it if necessary. If the file
+// cannot be opened the current file continues to be used.
+func (w *FileWriter) Reopen() error {
+	f, err := openLogFile(w.path)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old := w.f
+	w.setFile(f)
+	if old == nil {
+		return nil
+	}
+	return old.Close()
+}
+
+// Sync commits the contents of the file to stable storage.
+func (w *FileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	return w.f.Sync()
+}
+
+// Close closes the file. A later write opens it again.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	w.unregister()
+	w.unregister = nil
+	return err
+}
+
+// setFile sets the file written to, registering the writer to be flushed by Flush and Close while it
+// has an open file. w.mu must be held.
+func (w *FileWriter) setFile(f *os.File) {
+	w.f = f
+	if w.unregister == nil {
+		w.unregister = RegisterWriter(w)
+	}
+}
+
+func openLogFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
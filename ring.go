@@ -0,0 +1,111 @@
+package logfmtr
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ Hook = (*RingBuffer)(nil)
+
+// RingBuffer keeps the most recent entries written by the loggers it is added to as a hook, so that
+// the recent logs of a running process can be inspected even when its output is discarded. A
+// RingBuffer is an http.Handler that writes the entries it holds, oldest first:
+//
+//	ring := logfmtr.NewRingBuffer(1000)
+//	opts.Hooks = append(opts.Hooks, ring)
+//	http.Handle("/debug/logs", ring)
+//
+// The format query parameter selects the format of the response: logfmt, the default, json or human.
+// The n query parameter limits the response to the last n entries. Values of entries are held by
+// reference until they are written, so values that are changed after being logged are written as
+// they are when the entries are viewed. A RingBuffer is safe for concurrent use.
+type RingBuffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int // index of the slot for the next entry
+	full    bool
+}
+
+// NewRingBuffer returns a RingBuffer that holds up to size of the most recent entries.
+func NewRingBuffer(size int) *RingBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &RingBuffer{entries: make([]Entry, size)}
+}
+
+// Apply adds the entry to the buffer, replacing the oldest entry if the buffer is full. Lazy values
+// are computed when the entry is added. It never prevents the entry from being written.
+func (r *RingBuffer) Apply(e *Entry) bool {
+	re := resolveLazy(*e)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = re
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+	return true
+}
+
+// Entries returns the entries held by the buffer, oldest first.
+func (r *RingBuffer) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]Entry(nil), r.entries[:r.next]...)
+	}
+	return append(append([]Entry(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+// ServeHTTP writes the entries held by the buffer in the format selected by the request.
+func (r *RingBuffer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var enc Encoder
+	contentType := "text/plain; charset=utf-8"
+	switch format := req.URL.Query().Get("format"); format {
+	case FormatLogfmt, "":
+		enc = &LogfmtEncoder{TimestampFormat: time.RFC3339Nano}
+	case FormatJSON:
+		enc = &JSONEncoder{TimestampFormat: time.RFC3339Nano}
+		contentType = "application/x-ndjson"
+	case FormatHuman:
+		enc = &HumanEncoder{}
+	default:
+		http.Error(w, "unknown format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	entries := r.Entries()
+	if s := req.URL.Query().Get("n"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid n: "+s, http.StatusBadRequest)
+			return
+		}
+		if n < len(entries) {
+			entries = entries[len(entries)-n:]
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if req.Method == http.MethodHead {
+		return
+	}
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		if err := enc.EncodeEntry(bw, e); err != nil {
+			return
+		}
+	}
+	_ = bw.Flush()
+}
@@ -0,0 +1,67 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestRingBuffer(t *testing.T) {
+	ring := logfmtr.NewRingBuffer(2)
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(ioutil.Discard),
+		logfmtr.WithHooks(ring),
+		logfmtr.WithClock(func() time.Time { return time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC) }),
+	)
+
+	logger.Info("one")
+	logger.Info("two", "a", 1)
+	logger.Error(nil, "three", "b", logfmtr.Lazy(func() interface{} { return 2 }))
+
+	testCases := []struct {
+		query string
+		code  int
+		want  string
+	}{
+		{
+			query: "",
+			code:  http.StatusOK,
+			want: "level=0 ts=2021-09-01T12:00:00Z msg=two a=1\n" +
+				"level=0 ts=2021-09-01T12:00:00Z msg=three error=<nil> b=2\n",
+		},
+		{
+			query: "?format=json&n=1",
+			code:  http.StatusOK,
+			want:  `{"level":0,"ts":"2021-09-01T12:00:00Z","msg":"three","error":null,"b":2}` + "\n",
+		},
+		{
+			query: "?format=xml",
+			code:  http.StatusBadRequest,
+			want:  "unknown format: xml\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ring.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/logs"+tc.query, nil))
+			if rec.Code != tc.code {
+				t.Errorf("got status %d, wanted %d", rec.Code, tc.code)
+			}
+			if got := rec.Body.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	ring.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/logs?format=human", nil))
+	if !bytes.Contains(rec.Body.Bytes(), []byte("three")) || !bytes.Contains(rec.Body.Bytes(), []byte("b=2")) {
+		t.Errorf("got human output %q, wanted it to contain the last entry", rec.Body.String())
+	}
+}
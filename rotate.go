@@ -0,0 +1,327 @@
+package logfmtr
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the format of the timestamp added to the names of rotated files.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateConfig configures when a rotating file writer starts a new file and which old files are kept.
+type RotateConfig struct {
+	// MaxSize is the size in bytes at which the file is rotated. Zero disables rotation by size.
+	MaxSize int64
+
+	// MaxInterval is the length of time after which the file is rotated, measured from when the
+	// file was opened. Zero disables rotation by time.
+	MaxInterval time.Duration
+
+	// MaxAge is the maximum length of time that rotated files are kept. Zero keeps files regardless of age.
+	MaxAge time.Duration
+
+	// MaxBackups is the maximum number of rotated files that are kept. Zero keeps all files.
+	MaxBackups int
+
+	// Compress indicates that rotated files should be compressed using gzip.
+	Compress bool
+}
+
+// NewRotatingFileWriter returns a writer that appends to the file at path, rotating it according to cfg.
+// Rotated files are renamed by adding a timestamp before the file extension, for example app.log is
+// renamed to app-2021-09-01T12-00-00.000.log. If a file rotated in the same millisecond already has that
+// name a counter is added, as in app-2021-09-01T12-00-00.000-1.log. The file is opened on the first write. The writer is
+// safe for concurrent use and may be used as Options.Writer. Writes after Close return os.ErrClosed.
+func NewRotatingFileWriter(path string, cfg RotateConfig) io.WriteCloser {
+	return &rotatingFileWriter{
+		path: path,
+		cfg:  cfg,
+	}
+}
+
+type rotatingFileWriter struct {
+	path string
+	cfg  RotateConfig
+
+	mu         sync.Mutex
+	f          *os.File
+	size       int64
+	opened     time.Time
+	unregister func() // removes the writer from those closed by Close, nil when closed
+	closed     bool
+
+	mill sync.Mutex     // serialises removal and compression of rotated files
+	wg   sync.WaitGroup // tracks running removal and compression
+}
+
+// Write writes p to the current file, rotating it first if the write would exceed the maximum size
+// or the file has been open longer than the maximum interval. Writes after Close return os.ErrClosed.
+func (w *rotatingFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.f == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.needsRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file and waits for any removal or compression of rotated files to finish.
+func (w *rotatingFileWriter) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	var err error
+	if w.f != nil {
+		err = w.f.Close()
+		w.f = nil
+	}
+	if w.unregister != nil {
+		w.unregister()
+		w.unregister = nil
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
+
+func (w *rotatingFileWriter) needsRotate(n int) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.cfg.MaxSize > 0 && w.size+int64(n) > w.cfg.MaxSize {
+		return true
+	}
+	if w.cfg.MaxInterval > 0 && time.Since(w.opened) >= w.cfg.MaxInterval {
+		return true
+	}
+	return false
+}
+
+// open opens or creates the file, appending to any existing content.
+func (w *rotatingFileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+		return fmt.Errorf("create log directory: %w", err)
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	w.f = f
+	w.size = info.Size()
+	w.opened = time.Now()
+	if w.unregister == nil {
+		w.unregister = RegisterWriter(w)
+	}
+	return nil
+}
+
+// rotate closes the current file, renames it with a timestamp and opens a new file.
+func (w *rotatingFileWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	w.f = nil
+	name, err := w.backupName(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(w.path, name); err != nil {
+		return fmt.Errorf("rename log file: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	if w.cfg.MaxAge > 0 || w.cfg.MaxBackups > 0 || w.cfg.Compress {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.millBackups()
+		}()
+	}
+	return nil
+}
+
+// backupName returns the name a file rotated at time t is renamed to, adding a counter if a backup with
+// the same timestamp, compressed or not, already exists.
+func (w *rotatingFileWriter) backupName(t time.Time) (string, error) {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext) + "-" + t.UTC().Format(backupTimeFormat)
+	name := base + ext
+	for i := 1; ; i++ {
+		exists, err := fileExists(name)
+		if err == nil && !exists {
+			exists, err = fileExists(name + ".gz")
+		}
+		if err != nil {
+			return "", fmt.Errorf("check backup name: %w", err)
+		}
+		if !exists {
+			return name, nil
+		}
+		name = base + "-" + strconv.Itoa(i) + ext
+	}
+}
+
+// fileExists reports whether a file exists at path, returning any error other than one reporting that
+// the file does not exist.
+func fileExists(path string) (bool, error) {
+	_, err := os.Lstat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+type backupFile struct {
+	path string
+	t    time.Time
+	seq  int // counter distinguishing files rotated in the same millisecond
+}
+
+// parseBackupTime parses the timestamp and optional counter from the name of a rotated file with its
+// prefix and extension removed.
+func parseBackupTime(s string) (time.Time, int, bool) {
+	if t, err := time.Parse(backupTimeFormat, s); err == nil {
+		return t, 0, true
+	}
+	i := strings.LastIndexByte(s, '-')
+	if i < 0 {
+		return time.Time{}, 0, false
+	}
+	seq, err := strconv.Atoi(s[i+1:])
+	if err != nil || seq < 1 {
+		return time.Time{}, 0, false
+	}
+	t, err := time.Parse(backupTimeFormat, s[:i])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return t, seq, true
+}
+
+// backups returns the rotated files, newest first.
+func (w *rotatingFileWriter) backups() ([]backupFile, error) {
+	dir := filepath.Dir(w.path)
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []backupFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimPrefix(name, prefix)
+		ts = strings.TrimSuffix(ts, ".gz")
+		if !strings.HasSuffix(ts, ext) {
+			continue
+		}
+		t, seq, ok := parseBackupTime(strings.TrimSuffix(ts, ext))
+		if !ok {
+			continue
+		}
+		files = append(files, backupFile{path: filepath.Join(dir, name), t: t, seq: seq})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].t.Equal(files[j].t) {
+			return files[i].seq > files[j].seq
+		}
+		return files[i].t.After(files[j].t)
+	})
+	return files, nil
+}
+
+// millBackups removes rotated files that exceed the configured limits and compresses the remainder.
+func (w *rotatingFileWriter) millBackups() {
+	w.mill.Lock()
+	defer w.mill.Unlock()
+
+	files, err := w.backups()
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-w.cfg.MaxAge)
+	for i, bf := range files {
+		if (w.cfg.MaxBackups > 0 && i >= w.cfg.MaxBackups) || (w.cfg.MaxAge > 0 && bf.t.Before(cutoff)) {
+			_ = os.Remove(bf.path)
+			continue
+		}
+		if w.cfg.Compress && !strings.HasSuffix(bf.path, ".gz") {
+			_ = compressFile(bf.path)
+		}
+	}
+}
+
+// compressFile writes a gzip compressed copy of the file with a .gz suffix and removes the original.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
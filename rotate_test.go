@@ -0,0 +1,113 @@
+package logfmtr_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestRotatingFileWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w := logfmtr.NewRotatingFileWriter(path, logfmtr.RotateConfig{
+		MaxSize:    100,
+		MaxBackups: 2,
+		Compress:   true,
+	})
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	logger := logfmtr.NewWithOptions(opts)
+	for i := 0; i < 5; i++ {
+		logger.Info("the sun is shining", "i", i)
+		// ensure rotated files have distinct timestamps
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing writer: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error reading dir: %v", err)
+	}
+
+	var current, backups int
+	for _, e := range entries {
+		switch {
+		case e.Name() == "app.log":
+			current++
+		case strings.HasPrefix(e.Name(), "app-") && strings.HasSuffix(e.Name(), ".log.gz"):
+			backups++
+		default:
+			t.Errorf("unexpected file: %s", e.Name())
+		}
+	}
+	if current != 1 {
+		t.Errorf("got %d current files, wanted 1", current)
+	}
+	if backups != 2 {
+		t.Errorf("got %d backups, wanted 2", backups)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading log file: %v", err)
+	}
+	if !strings.Contains(string(data), "i=4") {
+		t.Errorf("current file did not contain last entry: %q", data)
+	}
+}
+
+func TestRotatingFileWriterSameMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w := logfmtr.NewRotatingFileWriter(path, logfmtr.RotateConfig{MaxSize: 10})
+	// each entry is larger than MaxSize so every write after the first rotates the file, many of them
+	// within the same millisecond
+	for i := 0; i < 20; i++ {
+		if _, err := w.Write([]byte("entry " + strings.Repeat("x", i) + "\n")); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing writer: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error reading dir: %v", err)
+	}
+	if len(entries) != 20 {
+		t.Errorf("got %d files, wanted 20 with no backup overwritten", len(entries))
+	}
+}
+
+func TestRotatingFileWriterWriteAfterClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w := logfmtr.NewRotatingFileWriter(path, logfmtr.RotateConfig{})
+	if _, err := w.Write([]byte("one\n")); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing writer: %v", err)
+	}
+
+	if _, err := w.Write([]byte("two\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("got error %v writing after close, wanted %v", err, os.ErrClosed)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error reading log file: %v", err)
+	}
+	if string(data) != "one\n" {
+		t.Errorf("got %q, wanted %q", data, "one\n")
+	}
+}
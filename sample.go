@@ -0,0 +1,90 @@
+package logfmtr
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// samplerSize is the number of counters used by a Sampler. Entries are assigned to counters by hashing
+// so distinct messages may occasionally share a counter.
+const samplerSize = 4096
+
+var _ Hook = (*Sampler)(nil)
+
+// Sampler limits the number of entries with the same level, logger name and message that are written
+// in each interval. The first entries in each interval are written, after which only every Nth entry is
+// written. A Sampler may be shared by many loggers so that they are sampled together.
+type Sampler struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+	counters   [samplerSize]sampleCounter
+	dropped    uint64 // accessed atomically
+}
+
+type sampleCounter struct {
+	resetAt int64 // unix nanoseconds, accessed atomically
+	count   uint64
+}
+
+// NewSampler returns a Sampler that writes the first entries for each level, logger name and message
+// in every tick interval and then every thereafter'th entry for the rest of the interval. If thereafter
+// is zero then no more entries are written in the interval once the first have been written.
+func NewSampler(tick time.Duration, first, thereafter int) *Sampler {
+	return &Sampler{
+		tick:       tick,
+		first:      uint64(first),
+		thereafter: uint64(thereafter),
+	}
+}
+
+// Apply reports whether the entry should be written.
+func (s *Sampler) Apply(e *Entry) bool {
+	c := &s.counters[sampleKey(e)%samplerSize]
+	n := c.inc(e.Time.UnixNano(), int64(s.tick))
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	return false
+}
+
+// Dropped returns the number of entries that have been dropped by the sampler.
+func (s *Sampler) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// inc increments the counter, resetting it first if the current interval has ended, and returns the new count.
+func (c *sampleCounter) inc(now int64, tick int64) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if now < resetAt {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	if atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+tick) {
+		atomic.StoreUint64(&c.count, 1)
+		return 1
+	}
+	return atomic.AddUint64(&c.count, 1)
+}
+
+// sampleKey hashes the level, logger name and message of an entry using FNV-1a.
+func sampleKey(e *Entry) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	if e.IsError {
+		h = (h ^ 0xff) * prime32
+	} else {
+		h = (h ^ uint32(e.Level)) * prime32
+	}
+	for i := 0; i < len(e.Name); i++ {
+		h = (h ^ uint32(e.Name[i])) * prime32
+	}
+	h *= prime32 // separates the name from the message
+	for i := 0; i < len(e.Message); i++ {
+		h = (h ^ uint32(e.Message[i])) * prime32
+	}
+	return h
+}
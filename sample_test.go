@@ -0,0 +1,54 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestSampler(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Sampler = logfmtr.NewSampler(time.Minute, 3, 10)
+	logger := logfmtr.NewWithOptions(opts)
+
+	for i := 0; i < 25; i++ {
+		logger.Info("repeated", "i", i)
+	}
+	logger.Info("different")
+
+	want := "level=0 msg=repeated i=0\n" +
+		"level=0 msg=repeated i=1\n" +
+		"level=0 msg=repeated i=2\n" +
+		"level=0 msg=repeated i=12\n" +
+		"level=0 msg=repeated i=22\n" +
+		"level=0 msg=different\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+	if got := opts.Sampler.Dropped(); got != 20 {
+		t.Errorf("got %d dropped, wanted 20", got)
+	}
+}
+
+func TestSamplerReset(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Sampler = logfmtr.NewSampler(10*time.Millisecond, 1, 0)
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("repeated")
+	logger.Info("repeated")
+	time.Sleep(20 * time.Millisecond)
+	logger.Info("repeated")
+
+	if got := strings.Count(buf.String(), "msg=repeated"); got != 2 {
+		t.Errorf("got %d entries, wanted 2", got)
+	}
+}
@@ -0,0 +1,67 @@
+package logfmtr
+
+import (
+	"io"
+	"reflect"
+	"sync"
+)
+
+// sharedLocks holds the mutexes shared by loggers that use the options set by UseOptions and
+// SetLoggerOptions. It is never reset, so loggers created before and after those options change
+// still share one mutex for each writer.
+var sharedLocks writerLocks
+
+// writerLocks holds a lockedWriter for each writer written to by a set of loggers, so that the loggers
+// share a mutex for each writer.
+type writerLocks struct {
+	mu sync.Mutex
+	m  map[io.Writer]*lockedWriter
+}
+
+// lockedWriter serializes calls to Write and WriteLevel with a mutex.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// wrap returns a writer that serializes writes to w with the mutex held for w, creating it if needed.
+func (l *writerLocks) wrap(w io.Writer) io.Writer {
+	if !reflect.TypeOf(w).Comparable() {
+		return &lockedWriter{w: w}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lw, ok := l.m[w]
+	if !ok {
+		if l.m == nil {
+			l.m = map[io.Writer]*lockedWriter{}
+		}
+		lw = &lockedWriter{w: w}
+		l.m[w] = lw
+	}
+	return lw
+}
+
+// unwrapWriter returns the writer wrapped by a writer returned by writerLocks.wrap, or w itself.
+func unwrapWriter(w io.Writer) io.Writer {
+	if lw, ok := w.(*lockedWriter); ok {
+		return lw.w
+	}
+	return w
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// WriteLevel calls WriteLevel on the wrapped writer if it is a LevelWriter, otherwise Write.
+func (l *lockedWriter) WriteLevel(level int, isError bool, p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lw, ok := l.w.(LevelWriter); ok {
+		return lw.WriteLevel(level, isError, p)
+	}
+	return l.w.Write(p)
+}
@@ -0,0 +1,96 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+)
+
+// chunkedWriter writes each byte separately, so that concurrent writes interleave unless serialized.
+type chunkedWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *chunkedWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		w.mu.Lock()
+		w.buf.WriteByte(c)
+		w.mu.Unlock()
+		runtime.Gosched()
+	}
+	return len(p), nil
+}
+
+func TestSerializeWrites(t *testing.T) {
+	w := &chunkedWriter{}
+	base := logfmtr.NewWith(
+		logfmtr.WithWriter(w),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithSerializeWrites(),
+	)
+	checkSerialized(t, w, []logr.Logger{base, base.WithName("other")})
+}
+
+func TestSerializeWritesGlobalOptions(t *testing.T) {
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+
+	w := &chunkedWriter{}
+	logfmtr.UseOptions(logfmtr.DefaultOptions().With(
+		logfmtr.WithWriter(w),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithSerializeWrites(),
+	))
+	checkSerialized(t, w, []logr.Logger{logfmtr.New(), logfmtr.NewNamed("other")})
+}
+
+func TestSerializeWritesAcrossOptionChanges(t *testing.T) {
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+
+	w := &chunkedWriter{}
+	opts := logfmtr.DefaultOptions().With(
+		logfmtr.WithWriter(w),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithSerializeWrites(),
+	)
+	logfmtr.UseOptions(opts)
+	before := logfmtr.New()
+	before.Info("hello", "j", -1) // instantiate the logger with the current options
+
+	logfmtr.UseOptions(opts)
+	after := logfmtr.NewNamed("other")
+	w.buf.Reset()
+
+	checkSerialized(t, w, []logr.Logger{before, after})
+}
+
+// checkSerialized logs concurrently to the loggers and checks that no entries written to w interleave.
+func checkSerialized(t *testing.T, w *chunkedWriter, loggers []logr.Logger) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(logger logr.Logger) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.Info("hello", "j", j)
+			}
+		}(loggers[i%len(loggers)])
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\n"), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("got %d lines, wanted 200", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "level=0 msg=hello j=") && !strings.HasPrefix(line, "level=0 logger=other msg=hello j=") {
+			t.Fatalf("got interleaved line %q", line)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package logfmtr
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+)
+
+// Severities written in place of the level of entries written by Fatal and Panic.
+const (
+	SeverityFatal = "fatal"
+	SeverityPanic = "panic"
+)
+
+// Fatal writes an error entry with the level fatal, flushes the logger's writers and all registered
+// writers and then exits
+// the program with status 1. Loggers created by this package exit using their ExitFunc option, if set.
+// Other loggers write an ordinary error entry before exiting.
+func Fatal(l logr.Logger, err error, msg string, kvs ...interface{}) {
+	exit := writeSevere(l, SeverityFatal, err, msg, kvs)
+	exit(1)
+}
+
+// Panic writes an error entry with the level panic, flushes the logger's writers and all registered
+// writers and then panics.
+// The panic value is an error combining the message with err, or the message itself if err is nil.
+func Panic(l logr.Logger, err error, msg string, kvs ...interface{}) {
+	writeSevere(l, SeverityPanic, err, msg, kvs)
+	if err == nil {
+		panic(msg)
+	}
+	panic(fmt.Errorf("%s: %w", msg, err))
+}
+
+// writeSevere writes an error entry with the given severity and flushes the writers. It returns the
+// function the logger uses to exit.
+func writeSevere(l logr.Logger, severity string, err error, msg string, kvs []interface{}) func(int) {
+	exit := os.Exit
+	var c *core
+	if s, ok := l.GetSink().(*sink); ok {
+		c = s.getCore()
+		if c.exit != nil {
+			exit = c.exit
+		}
+		l = l.WithSink(s.withSeverity(severity))
+	}
+	// skip this function and Fatal or Panic
+	l.WithCallDepth(2).Error(err, msg, kvs...)
+	_ = Flush()
+	if c != nil {
+		c.flush()
+	}
+	return exit
+}
+
+// withSeverity returns a sink that writes entries with the given severity.
+func (l *sink) withSeverity(severity string) *sink {
+	return l.child(func(c *core) {
+		c.severity = severity
+	})
+}
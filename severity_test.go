@@ -0,0 +1,61 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestFatal(t *testing.T) {
+	var buf bytes.Buffer
+	code := -1
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithCaller(0),
+		logfmtr.WithExitFunc(func(c int) { code = c }),
+	)
+
+	logfmtr.Fatal(logger.WithValues("a", 1), errors.New("boom"), "cannot start", "b", 2)
+	logger.Info("after")
+
+	if code != 1 {
+		t.Errorf("got exit code %d, wanted 1", code)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if want := "level=fatal msg=\"cannot start\" caller=severity_test.go:"; !strings.HasPrefix(lines[0], want) {
+		t.Errorf("got %q, wanted prefix %q", lines[0], want)
+	}
+	if want := " error=boom a=1 b=2"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("got %q, wanted suffix %q", lines[0], want)
+	}
+	if want := "level=0 msg=after caller=severity_test.go:"; !strings.HasPrefix(lines[1], want) {
+		t.Errorf("got %q, wanted prefix %q", lines[1], want)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithEncoder(&logfmtr.JSONEncoder{}),
+	)
+
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || err.Error() != "invariant violated: boom" {
+			t.Errorf("got panic value %v, wanted invariant violated: boom", r)
+		}
+		want := `{"level":"panic","msg":"invariant violated","error":"boom","a":1}` + "\n"
+		if got := buf.String(); got != want {
+			t.Errorf("got %q, wanted %q", got, want)
+		}
+	}()
+	logfmtr.Panic(logger, errors.New("boom"), "invariant violated", "a", 1)
+	t.Errorf("Panic returned")
+}
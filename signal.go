@@ -0,0 +1,76 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package logfmtr
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals starts changing the global verbosity in response to signals: SIGUSR1 increases the
+// verbosity by one and SIGUSR2 decreases it by one, to a minimum of zero. Each change is logged by a
+// logger named "logfmtr" using the options set by UseOptions. The returned function stops handling
+// the signals and may be called more than once. On platforms without these signals HandleSignals does
+// nothing.
+func HandleSignals() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := make(chan struct{})
+	go func() {
+		logger := NewNamed("logfmtr")
+		for {
+			select {
+			case sig := <-ch:
+				delta := 1
+				if sig == syscall.SIGUSR2 {
+					delta = -1
+				}
+				v := adjustVerbosity(delta)
+				logger.Info("verbosity changed", "verbosity", v, "signal", sig.String())
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// HandleReopen starts reopening the destination of r, such as a FileWriter, when the program receives
+// SIGHUP, which logrotate can be configured to send after renaming a log file. Errors reopening are
+// logged by a logger named "logfmtr" using the options set by UseOptions. The returned function stops
+// handling the signal and may be called more than once. On platforms without SIGHUP HandleReopen does
+// nothing.
+func HandleReopen(r Reopener) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		logger := NewNamed("logfmtr")
+		for {
+			select {
+			case <-ch:
+				if err := r.Reopen(); err != nil {
+					logger.Error(err, "failed to reopen log file")
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package logfmtr
+
+// HandleSignals does nothing on this platform. On Unix platforms it changes the global verbosity
+// in response to SIGUSR1 and SIGUSR2.
+func HandleSignals() (stop func()) {
+	return func() {}
+}
+
+// HandleReopen does nothing on this platform. On Unix platforms it reopens the destination of r when
+// the program receives SIGHUP.
+func HandleReopen(r Reopener) (stop func()) {
+	return func() {}
+}
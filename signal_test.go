@@ -0,0 +1,110 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package logfmtr_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestHandleSignals(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+
+	var buf syncBuffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logfmtr.UseOptions(opts)
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+
+	stop := logfmtr.HandleSignals()
+	defer stop()
+
+	waitVerbosity := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for logfmtr.Verbosity() != want && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got := logfmtr.Verbosity(); got != want {
+			t.Fatalf("got verbosity %d, wanted %d", got, want)
+		}
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("unexpected error sending signal: %v", err)
+	}
+	waitVerbosity(1)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("unexpected error sending signal: %v", err)
+	}
+	waitVerbosity(0)
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("unexpected error sending signal: %v", err)
+	}
+
+	want := "level=0 logger=logfmtr msg=\"verbosity changed\" verbosity=1 signal=\"user defined signal 1\"\n"
+	deadline := time.Now().Add(time.Second)
+	for strings.Count(buf.String(), "\n") < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, want) || !strings.Contains(got, "verbosity=0 ") {
+		t.Errorf("got %q, wanted prefix %q", got, want)
+	}
+	waitVerbosity(0)
+
+	// stopping more than once is harmless
+	stop()
+	stop()
+}
+
+func TestHandleReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w := logfmtr.NewFileWriter(path)
+	defer w.Close()
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(w),
+		logfmtr.WithTimestampFormat(""),
+	)
+
+	stop := logfmtr.HandleReopen(w)
+	defer stop()
+
+	logger.Info("before")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("unexpected error renaming file: %v", err)
+	}
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("unexpected error sending signal: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	logger.Info("after")
+
+	for name, want := range map[string]string{
+		path + ".1": "level=0 msg=before\n",
+		path:        "level=0 msg=after\n",
+	} {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatalf("unexpected error reading file: %v", err)
+		}
+		if got := string(data); got != want {
+			t.Errorf("got %q in %s, wanted %q", got, filepath.Base(name), want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+//go:build go1.21
+// +build go1.21
+
+package logfmtr
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"github.com/go-logr/logr"
+)
+
+var (
+	_ slog.Handler  = (*slogHandler)(nil)
+	_ logr.SlogSink = (*sink)(nil)
+)
+
+// NewSlogHandler returns a slog.Handler that writes in logfmt using the supplied options. Panics if
+// no writer is supplied in the options. Attributes within groups are written with keys prefixed
+// by the group names, separated by dots. Records at error level or above with an attribute whose
+// value is an error are written as errors, with that value in the error field.
+func NewSlogHandler(opts Options) slog.Handler {
+	c := &core{}
+	c.applyOptions(opts)
+	return &slogHandler{core: c}
+}
+
+type slogHandler struct {
+	core *core
+}
+
+// Enabled reports whether the handler writes records at the given level. Records at error level or
+// above are always written.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelError || h.core.enabled(levelFromSlog(level))
+}
+
+// Handle writes the record.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.core.handle(ctx, r)
+}
+
+// WithAttrs returns a handler that includes the given attributes with every record.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	c := *h.core
+	c.appendValues(c.attrValues(attrs))
+	return &slogHandler{core: &c}
+}
+
+// WithGroup returns a handler that qualifies the keys of all subsequent attributes with the group name.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h.core
+	c.appendGroup(name)
+	return &slogHandler{core: &c}
+}
+
+// Handle writes a slog record. It allows logr.ToSlogHandler to pass records directly to the sink.
+func (l *sink) Handle(ctx context.Context, r slog.Record) error {
+	return l.getCore().handle(ctx, r)
+}
+
+// WithAttrs returns a logger that includes the given attributes with every entry.
+func (l *sink) WithAttrs(attrs []slog.Attr) logr.SlogSink {
+	return l.child(func(c *core) {
+		c.appendValues(c.attrValues(attrs))
+	})
+}
+
+// WithGroup returns a logger that qualifies the keys of all subsequent slog attributes with the group name.
+func (l *sink) WithGroup(name string) logr.SlogSink {
+	return l.withGroup(name)
+}
+
+func (c *core) handle(ctx context.Context, r slog.Record) error {
+	kvs := make([]interface{}, 0, 2*r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, c.groups, a)
+		return true
+	})
+
+	e := Entry{
+		Time:    r.Time,
+		Name:    c.name,
+		Message: r.Message,
+		Context: c.values,
+		Values:  kvs,
+	}
+	if e.Time.IsZero() || c.clock != nil {
+		e.Time = c.now()
+	}
+	if r.Level >= slog.LevelError {
+		e.Error, e.Values, e.IsError = takeError(kvs)
+	}
+	if !e.IsError {
+		e.Level = levelFromSlog(r.Level)
+	}
+	if c.addGoid {
+		e.Context = append(e.Context[:len(e.Context):len(e.Context)], "goid", goroutineID())
+	}
+	if c.hasExtractors() {
+		e.Context = append(e.Context[:len(e.Context):len(e.Context)], c.contextValues(ctx)...)
+	}
+	if c.addCaller && r.PC != 0 {
+		e.Caller = c.frameCaller(r.PC)
+	}
+	return c.emit(e)
+}
+
+// attrValues converts attributes to key/value pairs, qualifying keys with the core's current groups.
+func (c *core) attrValues(attrs []slog.Attr) []interface{} {
+	kvs := make([]interface{}, 0, 2*len(attrs))
+	for _, a := range attrs {
+		kvs = appendAttr(kvs, c.groups, a)
+	}
+	return kvs
+}
+
+// appendAttr appends the key/value pairs represented by an attribute, expanding groups and resolving
+// any slog.LogValuer values.
+func appendAttr(kvs []interface{}, groups []string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			kvs = appendAttr(kvs, groups, ga)
+		}
+		return kvs
+	}
+	if len(groups) == 0 {
+		return append(kvs, a.Key, a.Value.Any())
+	}
+	return append(kvs, groupKey{groups: groups, key: a.Key}, a.Value.Any())
+}
+
+// takeError returns the first error value of the key/value pairs outside any group and the pairs
+// without it. It reports false if there is no such value.
+func takeError(kvs []interface{}) (error, []interface{}, bool) {
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if _, ok := kvs[i].(string); !ok {
+			continue
+		}
+		if err, ok := kvs[i+1].(error); ok {
+			rest := append(kvs[:i:i], kvs[i+2:]...)
+			return err, rest, true
+		}
+	}
+	return nil, kvs, false
+}
+
+// levelFromSlog converts a slog level to a V level. Levels at or above slog.LevelInfo map to V level 0
+// and each level below that maps to an increasingly verbose V level.
+func levelFromSlog(level slog.Level) int {
+	if level >= slog.LevelInfo {
+		return 0
+	}
+	return int(slog.LevelInfo - level)
+}
+
+// frameCaller formats the location of a program counter.
+func (c *core) frameCaller(pc uintptr) string {
+	frames := runtime.CallersFrames([]uintptr{pc})
+	f, _ := frames.Next()
+	if f.File == "" {
+		return "unknown"
+	}
+	return c.formatCaller(f)
+}
@@ -0,0 +1,88 @@
+//go:build go1.21
+// +build go1.21
+
+package logfmtr_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+)
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logger := slog.New(logfmtr.NewSlogHandler(opts)).With("user", "you").WithGroup("req")
+
+	logger.Info("hello world", "id", 7, slog.Group("client", "addr", "127.0.0.1"))
+	logger.WithGroup("empty").Warn("warning")
+	logger.Error("goodbye", "code", -1)
+	logger.Debug("you should NOT see this")
+	slog.New(logfmtr.NewSlogHandler(opts)).Error("failed", "code", 2, "err", errors.New("boom"))
+
+	want := "level=0 msg=\"hello world\" user=you req.id=7 req.client.addr=127.0.0.1\n" +
+		"level=0 msg=warning user=you\n" +
+		"level=0 msg=goodbye user=you req.code=-1\n" +
+		"level=0 msg=failed error=boom code=2\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestToSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	base := logfmtr.NewWithOptions(opts).WithName("europa")
+
+	logger := slog.New(logr.ToSlogHandler(base)).With("user", "you").WithGroup("req")
+	logger.Info("hello world", "id", 7)
+	logger.Error("goodbye", "code", -1)
+
+	want := "level=0 logger=europa msg=\"hello world\" user=you req.id=7\n" +
+		"level=0 logger=europa msg=goodbye user=you req.code=-1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestSlogHandlerContext(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.ContextExtractors = []logfmtr.ContextExtractor{logfmtr.TraceExtractor(spanIDs)}
+	logger := slog.New(logfmtr.NewSlogHandler(opts))
+
+	ctx := context.WithValue(context.Background(), spanKey{}, span{traceID: "4bf92f3577b34da6a3ce929d0e0e4736", spanID: "00f067aa0ba902b7"})
+	logger.InfoContext(ctx, "hello", "val", 1)
+
+	want := "level=0 msg=hello trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 val=1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestSlogHandlerJSONGroups(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.Encoder = &logfmtr.JSONEncoder{}
+	logger := slog.New(logfmtr.NewSlogHandler(opts)).With("user", "you").WithGroup("req").With("id", 7)
+
+	logger.Info("hello world", slog.Group("client", "addr", "127.0.0.1"), "method", "GET")
+
+	want := `{"level":0,"msg":"hello world","user":"you","req":{"id":7,"client":{"addr":"127.0.0.1"},"method":"GET"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
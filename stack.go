@@ -0,0 +1,82 @@
+package logfmtr
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxStackDepth is the maximum number of frames captured in a stack trace.
+const maxStackDepth = 64
+
+// stacktrace returns the formatted call stack of the caller of the logger. skip is the number of frames
+// between the caller of this function and the logr package.
+func (c *core) stacktrace(skip int) string {
+	var pcs [maxStackDepth]uintptr
+	// skip runtime.Callers and this function in addition to the requested frames
+	n := runtime.Callers(c.runtimeInfo.CallDepth+skip+c.callerSkip+c.callDepth+2, pcs[:])
+	return formatStack(pcs[:n])
+}
+
+// formatStack formats program counters as lines of function names each followed by an indented file and
+// line number, in the style of a goroutine trace.
+func formatStack(pcs []uintptr) string {
+	var frames []runtime.Frame
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		frames = append(frames, f)
+		if !more {
+			break
+		}
+	}
+	return formatFrames(frames)
+}
+
+// formatFrames formats frames in the same way as formatStack.
+func formatFrames(frames []runtime.Frame) string {
+	var b strings.Builder
+	for _, f := range frames {
+		if f.File == "" || f.File == "<autogenerated>" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(f.Function)
+		b.WriteString("\n\t")
+		b.WriteString(f.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(f.Line))
+	}
+	return b.String()
+}
+
+// panicFrames returns the frames of the call stack of a panicking goroutine, starting at the function
+// that panicked and omitting the runtime functions that raised the panic. skip is the number of frames
+// between the caller of this function and the deferred function that recovered the panic. The whole
+// stack is returned if the goroutine is not panicking.
+func panicFrames(skip int) []runtime.Frame {
+	var pcs [maxStackDepth]uintptr
+	// skip runtime.Callers and this function in addition to the requested frames
+	n := runtime.Callers(skip+2, pcs[:])
+	var frames []runtime.Frame
+	panicking := false
+	iter := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := iter.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			frames = frames[:0]
+			panicking = true
+		case panicking && len(frames) == 0 && strings.HasPrefix(f.Function, "runtime."):
+			// runtime errors such as nil pointer dereferences are raised by functions such as runtime.sigpanic
+		default:
+			frames = append(frames, f)
+		}
+		if !more {
+			break
+		}
+	}
+	return frames
+}
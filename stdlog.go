@@ -0,0 +1,90 @@
+package logfmtr
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// stdLoggerDepth is the number of frames between a caller of a standard library logger's print methods
+// and the logr.Logger used by stdWriter: the print method, the logger's internal output method and
+// stdWriter.Write.
+const stdLoggerDepth = 3
+
+// NewStdLogger returns a standard library logger that writes each message it is given to l as an info
+// entry at the given V level. It is intended for APIs that require a *log.Logger such as the ErrorLog
+// field of http.Server. The returned logger has no prefix or flags; the message is passed to l without
+// its trailing newline. Changing the prefix or flags of the returned logger will add them to the
+// message. When AddCaller is set the caller is reported as the location of the call to the print method.
+func NewStdLogger(l logr.Logger, level int) *log.Logger {
+	return log.New(&stdWriter{logger: l.V(level).WithCallDepth(stdLoggerDepth)}, "", 0)
+}
+
+// stdWriter logs each write made by a standard library logger as a single entry.
+type stdWriter struct {
+	logger logr.Logger
+}
+
+func (w *stdWriter) Write(p []byte) (int, error) {
+	w.logger.Info(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// WriterFor returns a writer that splits the bytes written to it into lines and logs each non-empty
+// line to l as an info entry at the given V level. It can be used to capture the output of an
+// exec.Cmd or of libraries that write to an io.Writer, or passed to log.SetOutput. Trailing carriage
+// returns are removed from lines. An incomplete final line is held until a newline is written or the
+// writer is closed, when it is logged. The writer is safe for concurrent use.
+func WriterFor(l logr.Logger, level int) io.WriteCloser {
+	return &lineWriter{logger: l.V(level)}
+}
+
+// lineWriter logs each line written to it as a separate entry.
+type lineWriter struct {
+	logger logr.Logger
+	mu     sync.Mutex
+	buf    []byte // incomplete line
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := len(p)
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			break
+		}
+		if len(w.buf) > 0 {
+			w.buf = append(w.buf, p[:i]...)
+			w.logLine(w.buf)
+			w.buf = w.buf[:0]
+		} else {
+			w.logLine(p[:i])
+		}
+		p = p[i+1:]
+	}
+	w.buf = append(w.buf, p...)
+	return n, nil
+}
+
+// Close logs any incomplete line held by the writer.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.logLine(w.buf)
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (w *lineWriter) logLine(line []byte) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if len(line) == 0 {
+		return
+	}
+	w.logger.Info(string(line))
+}
@@ -0,0 +1,60 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	opts.AddCaller = true
+	logger := logfmtr.NewWithOptions(opts).WithName("http")
+
+	std := logfmtr.NewStdLogger(logger, 1)
+	_, _, line, _ := runtime.Caller(0)
+	std.Printf("http: TLS handshake error from %s: EOF", "10.0.0.1:5000")
+	std.Println("multiple", "words")
+	logfmtr.NewStdLogger(logger, 2).Print("hidden")
+
+	want := fmt.Sprintf("level=1 logger=http msg=\"http: TLS handshake error from 10.0.0.1:5000: EOF\" caller=stdlog_test.go:%d\n", line+1) +
+		fmt.Sprintf("level=1 logger=http msg=\"multiple words\" caller=stdlog_test.go:%d\n", line+2)
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestWriterFor(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts).WithName("cmd")
+
+	w := logfmtr.WriterFor(logger, 0)
+	fmt.Fprint(w, "first line\nsecond ")
+	fmt.Fprint(w, "line\r\n\n")
+	fmt.Fprint(w, "partial")
+
+	want := "level=0 logger=cmd msg=\"first line\"\n" +
+		"level=0 logger=cmd msg=\"second line\"\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing writer: %v", err)
+	}
+	want += "level=0 logger=cmd msg=partial\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
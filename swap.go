@@ -0,0 +1,73 @@
+package logfmtr
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// SwappableWriter is a writer whose destination can be replaced while loggers are writing to it. Using
+// a SwappableWriter as Options.Writer allows the destination of loggers that have already been
+// instantiated to be changed, which UseOptions cannot do. It is safe for concurrent use.
+type SwappableWriter struct {
+	mu sync.Mutex   // serialises calls to Swap
+	w  atomic.Value // holds a writerHolder
+}
+
+// writerHolder wraps a writer so that writers of different types can be stored in an atomic.Value.
+type writerHolder struct {
+	w io.Writer
+}
+
+// NewSwappableWriter returns a SwappableWriter that writes to w.
+func NewSwappableWriter(w io.Writer) *SwappableWriter {
+	s := &SwappableWriter{}
+	s.w.Store(writerHolder{w: w})
+	return s
+}
+
+// Swap replaces the destination with w and returns the previous destination, which may still be in
+// use by writes that started before the swap.
+func (s *SwappableWriter) Swap(w io.Writer) io.Writer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.Writer()
+	s.w.Store(writerHolder{w: w})
+	return old
+}
+
+// Writer returns the current destination.
+func (s *SwappableWriter) Writer() io.Writer {
+	return s.w.Load().(writerHolder).w
+}
+
+// Write writes p to the current destination.
+func (s *SwappableWriter) Write(p []byte) (int, error) {
+	return s.Writer().Write(p)
+}
+
+// WriteLevel writes p to the current destination, using WriteLevel if it is a LevelWriter.
+func (s *SwappableWriter) WriteLevel(level int, isError bool, p []byte) (int, error) {
+	w := s.Writer()
+	if lw, ok := w.(LevelWriter); ok {
+		return lw.WriteLevel(level, isError, p)
+	}
+	return w.Write(p)
+}
+
+// Flush flushes the current destination if it has a Flush or Sync method.
+func (s *SwappableWriter) Flush() error {
+	return flushWriter(s.Writer())
+}
+
+// Close closes the current destination if it implements io.Closer, unless it is os.Stdout or os.Stderr.
+func (s *SwappableWriter) Close() error {
+	w := s.Writer()
+	if isStdStream(w) {
+		return nil
+	}
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
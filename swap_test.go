@@ -0,0 +1,30 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestSwappableWriter(t *testing.T) {
+	var first, second bytes.Buffer
+	w := logfmtr.NewSwappableWriter(&first)
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(w),
+		logfmtr.WithTimestampFormat(""),
+	).WithName("app")
+
+	logger.Info("one")
+	if old := w.Swap(&second); old != &first {
+		t.Errorf("got previous writer %v, wanted first buffer", old)
+	}
+	logger.Info("two")
+
+	if got, want := first.String(), "level=0 logger=app msg=one\n"; got != want {
+		t.Errorf("got first %q, wanted %q", got, want)
+	}
+	if got, want := second.String(), "level=0 logger=app msg=two\n"; got != want {
+		t.Errorf("got second %q, wanted %q", got, want)
+	}
+}
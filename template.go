@@ -0,0 +1,141 @@
+package logfmtr
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var _ Encoder = (*TemplateEncoder)(nil)
+
+// errNoTemplate is returned by a TemplateEncoder that was not created by NewTemplateEncoder.
+var errNoTemplate = errors.New("logfmtr: TemplateEncoder has no template")
+
+// TemplateEncoder writes each entry by executing a text/template, for output that must match a bespoke
+// line format without writing an Encoder. The template is executed with a TemplateData for each entry.
+// A newline is added to the output unless the template ends with one. As well as the standard template
+// functions, templates may use:
+//
+//	quote  quotes and escapes a value if it would not otherwise be a valid logfmt value
+//	pad    pads a value with spaces to a minimum width, for example {{pad 5 .Level}}
+//	upper  converts a value to upper case
+//	lower  converts a value to lower case
+type TemplateEncoder struct {
+	// TimestampFormat sets the format of TemplateData.TS. When empty TS is empty.
+	TimestampFormat string
+
+	// Location is the time zone used for timestamps. When nil timestamps are written in UTC.
+	Location *time.Location
+
+	// LevelNames maps V levels to the names used for TemplateData.Level. When set, entries written by
+	// Error are given the level name "error". Levels without a name are written as numbers.
+	LevelNames map[int]string
+
+	tmpl *template.Template
+}
+
+// NewTemplateEncoder returns an encoder that writes entries using the template text, for example
+//
+//	{{.TS}} [{{pad 5 (upper .Level)}}] {{.Name}}: {{.Msg}} {{.KVs}}
+//
+// It returns an error if the template cannot be parsed.
+func NewTemplateEncoder(text string) (*TemplateEncoder, error) {
+	tmpl, err := template.New("entry").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateEncoder{tmpl: tmpl}, nil
+}
+
+// TemplateData holds the fields of an entry that are available to the template of a TemplateEncoder.
+type TemplateData struct {
+	// Level is the name of the entry's level or, when it has none, its V level as a number.
+	Level string
+
+	// Name is the name of the logger writing the entry.
+	Name string
+
+	// TS is the time of the entry formatted using the encoder's TimestampFormat.
+	TS string
+
+	// Msg is the log message.
+	Msg string
+
+	// Caller is the file and line number of the origin of the entry, if known.
+	Caller string
+
+	// KVs holds the error, the stack trace and the key/value pairs of the entry written as space
+	// delimited logfmt pairs.
+	KVs string
+
+	// Entry is the entry being written, giving access to all of its fields.
+	Entry Entry
+}
+
+// Value returns the value of the entry's key/value pair with the given key, or nil if it has none.
+func (d TemplateData) Value(key string) interface{} {
+	v, _ := d.Entry.Lookup(key)
+	return v
+}
+
+// templateFuncs are the functions available to the templates of TemplateEncoders.
+var templateFuncs = template.FuncMap{
+	"quote": func(v interface{}) string { return string(appendValue(nil, v)) },
+	"pad":   func(width int, v interface{}) string { return string(appendPadded(nil, rawString(v), width)) },
+	"upper": func(v interface{}) string { return strings.ToUpper(rawString(v)) },
+	"lower": func(v interface{}) string { return strings.ToLower(rawString(v)) },
+}
+
+// EncodeEntry writes the entry by executing the encoder's template.
+func (enc *TemplateEncoder) EncodeEntry(w io.Writer, e Entry) error {
+	if enc.tmpl == nil {
+		return errNoTemplate
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := enc.tmpl.Execute(buf, enc.data(e)); err != nil {
+		return err
+	}
+	if len(buf.b) == 0 || buf.b[len(buf.b)-1] != '\n' {
+		buf.b = append(buf.b, '\n')
+	}
+	_, err := w.Write(buf.b)
+	return err
+}
+
+// data returns the template data for the entry.
+func (enc *TemplateEncoder) data(e Entry) TemplateData {
+	d := TemplateData{
+		Name:   e.Name,
+		Msg:    e.Message,
+		Caller: e.Caller,
+		Entry:  e,
+	}
+	if name, ok := levelName(enc.LevelNames, e); ok {
+		d.Level = name
+	} else {
+		d.Level = strconv.Itoa(e.Level)
+	}
+	if enc.TimestampFormat != "" {
+		d.TS = inLocation(e.Time, enc.Location).Format(enc.TimestampFormat)
+	}
+
+	var b []byte
+	if e.IsError {
+		b = appendKV(b, "error", e.Error, KeyReplace, nil)
+		b = appendCauses(b, "error", e.Causes, nil)
+	}
+	if e.Stacktrace != "" {
+		b = appendKV(b, "stacktrace", e.Stacktrace, KeyReplace, nil)
+	}
+	b = appendKVs(b, e.Context, KeyReplace, nil)
+	b = appendKVs(b, e.Values, KeyReplace, nil)
+	if len(b) > 0 {
+		// each pair is written with a leading space
+		d.KVs = string(b[1:])
+	}
+	return d
+}
@@ -0,0 +1,70 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestTemplate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "legacy",
+			template: `{{.TS}} [{{pad 5 (upper .Level)}}] {{.Name}}: {{.Msg}} {{.KVs}}`,
+			want: "2024-01-02 03:04:05 [INFO ] europa: hello user=you val=1\n" +
+				"2024-01-02 03:04:05 [ERROR] europa: goodbye error=\"disk full\" user=you\n",
+		},
+		{
+			name:     "value lookup",
+			template: "{{.Level}}|{{.Msg}}|{{with .Value \"val\"}}{{.}}{{end}}\n",
+			want:     "info|hello|1\nerror|goodbye|\n",
+		},
+		{
+			name:     "quoted message",
+			template: `msg={{quote .Msg}} logger={{lower .Entry.Name}}`,
+			want:     "msg=hello logger=europa\nmsg=goodbye logger=europa\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = "2006-01-02 15:04:05"
+			opts.LevelNames = map[int]string{0: "info"}
+			opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+			opts.Template = tc.template
+			logger, err := logfmtr.NewWithOptionsE(opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			logger = logger.WithName("europa").WithValues("user", "you")
+
+			logger.Info("hello", "val", 1)
+			logger.Error(errors.New("disk full"), "goodbye")
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTemplateInvalid(t *testing.T) {
+	if _, err := logfmtr.NewTemplateEncoder("{{.Msg"); err == nil {
+		t.Errorf("got no error for unterminated action, wanted error")
+	}
+
+	var enc logfmtr.TemplateEncoder
+	if err := enc.EncodeEntry(&bytes.Buffer{}, logfmtr.Entry{}); err == nil {
+		t.Errorf("got no error for encoder without template, wanted error")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package logfmtr
+
+import "os"
+
+// fileTerminalWidth returns zero since the width of a terminal cannot be found on this platform.
+func fileTerminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,113 @@
+package logfmtr
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Color is an ANSI escape sequence that sets the color or style of text written by a HumanEncoder.
+// The empty Color leaves text unchanged.
+type Color string
+
+// Colors of the standard 16 color palette. Terminals usually allow users to change how these are
+// displayed, so they are the most likely to suit both dark and light backgrounds.
+const (
+	Black   Color = "\x1b[30m"
+	Red     Color = "\x1b[31m"
+	Green   Color = "\x1b[32m"
+	Yellow  Color = "\x1b[33m"
+	Blue    Color = "\x1b[34m"
+	Magenta Color = "\x1b[35m"
+	Cyan    Color = "\x1b[36m"
+	White   Color = "\x1b[37m"
+	Gray    Color = "\x1b[90m"
+)
+
+// colorReset restores the default color and style.
+const colorReset = "\x1b[0m"
+
+// Color256 returns the color with index n in the 256 color palette supported by most terminals.
+func Color256(n uint8) Color {
+	return Color("\x1b[38;5;" + strconv.Itoa(int(n)) + "m")
+}
+
+// RGB returns a 24-bit color for terminals that support true color.
+func RGB(r, g, b uint8) Color {
+	return Color("\x1b[38;2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b)) + "m")
+}
+
+// Bold returns c with bold text. Bold of the empty Color is bold text in the default color.
+func Bold(c Color) Color {
+	if c == "" {
+		return "\x1b[1m"
+	}
+	if strings.HasPrefix(string(c), "\x1b[") && strings.HasSuffix(string(c), "m") {
+		return "\x1b[1;" + c[2:]
+	}
+	return "\x1b[1m" + c
+}
+
+// Theme sets the colors used by a HumanEncoder when Colorize is set. Parts of an entry with an empty
+// Color are not colored.
+type Theme struct {
+	// ErrorLevel is the color of the level of entries written by Error.
+	ErrorLevel Color
+
+	// InfoLevel is the color of the level of other entries, unless Levels has a color for their V level.
+	InfoLevel Color
+
+	// Levels maps V levels to the colors used for the levels of entries that were not written by Error.
+	Levels map[int]Color
+
+	// Timestamp is the color of the timestamp.
+	Timestamp Color
+
+	// Message is the color of the message.
+	Message Color
+
+	// LoggerKey and CallerKey are the colors of the logger and caller keys.
+	LoggerKey Color
+	CallerKey Color
+
+	// ErrorKey is the color of the keys of the error, its causes and the stack trace.
+	ErrorKey Color
+
+	// Key is the color of all other keys.
+	Key Color
+}
+
+// DefaultTheme returns the theme used by a HumanEncoder when none is set.
+func DefaultTheme() *Theme {
+	return &Theme{
+		ErrorLevel: Bold(Red),
+		InfoLevel:  Bold(Green),
+		LoggerKey:  Bold(Blue),
+		CallerKey:  Bold(Blue),
+		ErrorKey:   Bold(Red),
+		Key:        Bold(Yellow),
+	}
+}
+
+// defaultTheme is used by HumanEncoders that do not have a theme.
+var defaultTheme = DefaultTheme()
+
+// level returns the color of the level of the entry.
+func (t *Theme) level(e Entry) Color {
+	if e.IsError {
+		return t.ErrorLevel
+	}
+	if c, ok := t.Levels[e.Level]; ok {
+		return c
+	}
+	return t.InfoLevel
+}
+
+// appendColored appends s in color c, resetting the color afterwards.
+func appendColored(b []byte, c Color, s string) []byte {
+	if c == "" {
+		return append(b, s...)
+	}
+	b = append(b, c...)
+	b = append(b, s...)
+	return append(b, colorReset...)
+}
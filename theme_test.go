@@ -0,0 +1,57 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestColors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		color logfmtr.Color
+		want  logfmtr.Color
+	}{
+		{name: "bold", color: logfmtr.Bold(logfmtr.Red), want: "\x1b[1;31m"},
+		{name: "bold default", color: logfmtr.Bold(""), want: "\x1b[1m"},
+		{name: "256", color: logfmtr.Color256(208), want: "\x1b[38;5;208m"},
+		{name: "rgb", color: logfmtr.RGB(255, 128, 0), want: "\x1b[38;2;255;128;0m"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.color != tc.want {
+				t.Errorf("got %q, wanted %q", tc.color, tc.want)
+			}
+		})
+	}
+}
+
+func TestTheme(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Humanize = true
+	opts.Colorize = true
+	opts.HumanTimestampFormat = "15:04"
+	opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	opts.Theme = &logfmtr.Theme{
+		InfoLevel: logfmtr.Green,
+		Levels:    map[int]logfmtr.Color{1: logfmtr.Gray},
+		Timestamp: logfmtr.Color256(244),
+		Key:       logfmtr.Cyan,
+	}
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+	logger := logfmtr.NewWithOptions(opts).WithName("europa")
+
+	logger.Info("hello", "val", 1)
+	logger.V(1).Info("details")
+
+	want := "0 \x1b[32minfo \x1b[0m | \x1b[38;5;244m03:04\x1b[0m | hello                          logger=europa \x1b[36mval\x1b[0m=1\n" +
+		"1 \x1b[90minfo \x1b[0m | \x1b[38;5;244m03:04\x1b[0m | details                        logger=europa\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
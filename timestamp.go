@@ -0,0 +1,133 @@
+package logfmtr
+
+import (
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// TimestampMode selects how timestamps are written.
+type TimestampMode int
+
+const (
+	// TimestampFormatted writes timestamps using the timestamp format, for example RFC3339.
+	TimestampFormatted TimestampMode = iota
+
+	// TimestampEpochSeconds writes the number of seconds since the Unix epoch with millisecond
+	// precision, for example 1699999999.123.
+	TimestampEpochSeconds
+
+	// TimestampEpochMillis writes the number of milliseconds since the Unix epoch.
+	TimestampEpochMillis
+
+	// TimestampEpochNanos writes the number of nanoseconds since the Unix epoch.
+	TimestampEpochNanos
+
+	// TimestampElapsed writes the number of seconds since the program started with microsecond
+	// precision, measured using the monotonic clock.
+	TimestampElapsed
+)
+
+// HumanTimeMode selects how the time of an entry is shown in human friendly output.
+type HumanTimeMode int
+
+const (
+	// HumanTimeAbsolute shows the time of the entry using the human timestamp format.
+	HumanTimeAbsolute HumanTimeMode = iota
+
+	// HumanTimeDelta shows the time since the previous entry written by the encoder, for example
+	// +12.3ms. The first entry shows the time since the program started.
+	HumanTimeDelta
+
+	// HumanTimeElapsed shows the time since the program started, for example +1.52s.
+	HumanTimeElapsed
+
+	// HumanTimeAbsoluteDelta shows the time of the entry followed by the time since the previous entry.
+	HumanTimeAbsoluteDelta
+)
+
+// deltaWidth is the width that durations are padded to so that columns stay aligned.
+const deltaWidth = 8
+
+// start is the time the program started, used by TimestampElapsed.
+var start = time.Now()
+
+// appendTimestamp appends t as a number according to mode. Modes that are not numeric are written as
+// nanoseconds since the Unix epoch.
+func appendTimestamp(b []byte, t time.Time, mode TimestampMode) []byte {
+	switch mode {
+	case TimestampEpochSeconds:
+		ms := t.UnixNano() / int64(time.Millisecond)
+		return appendFixed(b, ms, 1000, 3)
+	case TimestampEpochMillis:
+		return strconv.AppendInt(b, t.UnixNano()/int64(time.Millisecond), 10)
+	case TimestampElapsed:
+		us := t.Sub(start).Microseconds()
+		return appendFixed(b, us, 1000000, 6)
+	default:
+		return strconv.AppendInt(b, t.UnixNano(), 10)
+	}
+}
+
+// appendDelta appends a duration with a sign and about three significant digits, such as +12.3ms,
+// padded to deltaWidth.
+func appendDelta(b []byte, d time.Duration) []byte {
+	n := len(b)
+	if d < 0 {
+		b = append(b, '-')
+		d = -d
+	} else {
+		b = append(b, '+')
+	}
+	var unit string
+	var v float64
+	switch {
+	case d < time.Microsecond:
+		b = strconv.AppendInt(b, int64(d), 10)
+		b = append(b, "ns"...)
+		return padTo(b, n, deltaWidth)
+	case d < time.Millisecond:
+		v, unit = float64(d)/float64(time.Microsecond), "µs"
+	case d < time.Second:
+		v, unit = float64(d)/float64(time.Millisecond), "ms"
+	case d < time.Minute:
+		v, unit = d.Seconds(), "s"
+	default:
+		b = append(b, d.Round(time.Second).String()...)
+		return padTo(b, n, deltaWidth)
+	}
+	prec := 0
+	switch {
+	case v < 10:
+		prec = 2
+	case v < 100:
+		prec = 1
+	}
+	b = strconv.AppendFloat(b, v, 'f', prec, 64)
+	b = append(b, unit...)
+	return padTo(b, n, deltaWidth)
+}
+
+// padTo pads b with spaces so that b[start:] is at least width runes wide.
+func padTo(b []byte, start int, width int) []byte {
+	for n := utf8.RuneCount(b[start:]); n < width; n++ {
+		b = append(b, ' ')
+	}
+	return b
+}
+
+// appendFixed appends n divided by scale as a decimal with the given number of fractional digits,
+// where scale is 10 to the power of digits.
+func appendFixed(b []byte, n int64, scale int64, digits int) []byte {
+	if n < 0 {
+		b = append(b, '-')
+		n = -n
+	}
+	b = strconv.AppendInt(b, n/scale, 10)
+	b = append(b, '.')
+	frac := strconv.FormatInt(n%scale, 10)
+	for i := len(frac); i < digits; i++ {
+		b = append(b, '0')
+	}
+	return append(b, frac...)
+}
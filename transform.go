@@ -0,0 +1,73 @@
+package logfmtr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// A Transform returns the value to be written in place of the value of a key/value pair. Transforms
+// are registered by key in the ValueTransforms option so that conventions such as hashing email
+// addresses or rounding latencies are applied to every entry rather than at each call site.
+type Transform func(v interface{}) interface{}
+
+// transformValues returns the entry with the values of key/value pairs that have a transform for their
+// key replaced by the result of the transform. The key/value slices are copied before they are changed
+// since they may be shared with other entries.
+func transformValues(e Entry, transforms map[string]Transform) Entry {
+	e.Context = transformKVs(e.Context, transforms)
+	e.Values = transformKVs(e.Values, transforms)
+	return e
+}
+
+func transformKVs(kvs []interface{}, transforms map[string]Transform) []interface{} {
+	copied := false
+	for i := 0; i+1 < len(kvs); i += 2 {
+		t := transformFor(kvs[i], transforms)
+		if t == nil {
+			continue
+		}
+		if !copied {
+			kvs = append([]interface{}(nil), kvs...)
+			copied = true
+		}
+		kvs[i+1] = t(kvs[i+1])
+	}
+	return kvs
+}
+
+// transformFor returns the transform for a key, or nil if there is none. Keys within groups use the
+// transform for their full name if there is one, otherwise the transform for the key alone.
+func transformFor(k interface{}, transforms map[string]Transform) Transform {
+	name, ok := keyName(k)
+	if !ok {
+		return nil
+	}
+	if t, ok := transforms[name]; ok {
+		return t
+	}
+	if gk, ok := k.(groupKey); ok {
+		return transforms[gk.key.(string)]
+	}
+	return nil
+}
+
+// hashLen is the number of bytes of the salted hash written by HashTransform.
+const hashLen = 8
+
+// HashTransform returns a transform that replaces values with a salted hash, so that entries
+// containing the same identifier, such as a user id or email address, can be correlated without the
+// identifier being written. Values are converted to strings in the same way as they are written and
+// hashed using HMAC-SHA256 keyed with salt, of which the first 8 bytes are written in hex. Nil values
+// are written unchanged. The salt should be kept secret since values that are easy to guess can be
+// recovered from their hashes by anyone who knows it.
+func HashTransform(salt []byte) Transform {
+	return func(v interface{}) interface{} {
+		if v == nil || nilPointer(v) {
+			return v
+		}
+		mac := hmac.New(sha256.New, salt)
+		mac.Write([]byte(rawString(v)))
+		return hex.EncodeToString(mac.Sum(nil)[:hashLen])
+	}
+}
@@ -0,0 +1,82 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestValueTransforms(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithKeyPrefixFromName(),
+		logfmtr.WithValueTransform("method", func(v interface{}) interface{} {
+			if s, ok := v.(string); ok {
+				return strings.ToUpper(s)
+			}
+			return v
+		}),
+		logfmtr.WithValueTransform("latency", func(v interface{}) interface{} {
+			if d, ok := v.(time.Duration); ok {
+				return d.Round(time.Millisecond)
+			}
+			return v
+		}),
+		logfmtr.WithValueTransform("http.user", func(v interface{}) interface{} { return "redacted" }),
+	)
+
+	logger.WithValues("method", "get").Info("request", "latency", 1234567*time.Microsecond, "user", "you")
+	logger.WithName("http").Info("request", "method", "post", "user", "you", "odd")
+
+	want := "level=0 msg=request method=GET latency=1.235s user=you\n" +
+		"level=0 logger=http msg=request http.method=POST http.user=redacted odd=\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func TestHashTransform(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(&buf),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithHashedKeys([]byte("salt"), "user", "email"),
+	)
+
+	logger.Info("login", "user", "alice", "email", nil, "n", 1)
+	logger.Info("logout", "user", "alice")
+	logger.Info("login", "user", "bob")
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, wanted 3: %q", len(lines), buf.String())
+	}
+	if strings.Contains(buf.String(), "alice") || strings.Contains(buf.String(), "bob") {
+		t.Errorf("got raw identifier in %q", buf.String())
+	}
+	token := func(line string) string {
+		for _, f := range strings.Fields(line) {
+			if strings.HasPrefix(f, "user=") {
+				return strings.TrimPrefix(f, "user=")
+			}
+		}
+		return ""
+	}
+	if len(token(lines[0])) != 16 {
+		t.Errorf("got token %q, wanted 16 hex digits", token(lines[0]))
+	}
+	if token(lines[0]) != token(lines[1]) {
+		t.Errorf("got different tokens %q and %q for the same user", token(lines[0]), token(lines[1]))
+	}
+	if token(lines[0]) == token(lines[2]) {
+		t.Errorf("got the same token %q for different users", token(lines[0]))
+	}
+	if !strings.HasSuffix(lines[0], " email=<nil> n=1") {
+		t.Errorf("got %q, wanted nil email and n unchanged", lines[0])
+	}
+}
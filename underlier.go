@@ -0,0 +1,82 @@
+package logfmtr
+
+import "github.com/go-logr/logr"
+
+var _ Underlier = (*sink)(nil)
+
+// Underlier is implemented by the LogSink of loggers created by this package, following the pattern
+// recommended by logr for giving access to the underlying implementation:
+//
+//	if u, ok := logger.GetSink().(logfmtr.Underlier); ok {
+//		info := u.GetUnderlying()
+//		...
+//	}
+//
+// It is distinct from logr.Underlier, which is implemented by sinks that wrap a slog.Handler.
+type Underlier interface {
+	GetUnderlying() SinkInfo
+}
+
+// SinkInfo gives read-only access to the configuration of a logger created by this package. It
+// describes the logger as it is when GetUnderlying is called, instantiating the logger if necessary.
+type SinkInfo struct {
+	c *core
+}
+
+// GetUnderlying returns the configuration of the logger.
+func (l *sink) GetUnderlying() SinkInfo {
+	return SinkInfo{c: l.getCore()}
+}
+
+// Describe returns the configuration of a logger created by this package. It reports false for other
+// loggers.
+func Describe(l logr.Logger) (SinkInfo, bool) {
+	u, ok := l.GetSink().(Underlier)
+	if !ok {
+		return SinkInfo{}, false
+	}
+	return u.GetUnderlying(), true
+}
+
+// Options returns the options used by the logger, including any set for its name by SetLoggerOptions.
+// Slices and maps in the options are shared with the logger and must not be modified.
+func (s SinkInfo) Options() Options {
+	if s.c == nil || s.c.opts == nil {
+		return Options{}
+	}
+	return *s.c.opts
+}
+
+// Name returns the name of the logger, with the names added by WithName separated by the NameDelim
+// option.
+func (s SinkInfo) Name() string {
+	if s.c == nil {
+		return ""
+	}
+	return s.c.name
+}
+
+// Values returns a copy of the key/value pairs added to the logger by WithValues, not including those
+// set by the DefaultFields and AddPID options. Keys within groups are written as the group names and
+// key separated by dots.
+func (s SinkInfo) Values() []interface{} {
+	if s.c == nil {
+		return nil
+	}
+	kvs := append([]interface{}(nil), s.c.values[s.c.defaults:]...)
+	for i := 0; i < len(kvs); i += 2 {
+		if gk, ok := kvs[i].(groupKey); ok {
+			kvs[i] = gk.String()
+		}
+	}
+	return kvs
+}
+
+// Groups returns the names of the groups, added by WithGroup, that qualify the keys of key/value pairs
+// subsequently added to the logger.
+func (s SinkInfo) Groups() []string {
+	if s.c == nil {
+		return nil
+	}
+	return append([]string(nil), s.c.groups...)
+}
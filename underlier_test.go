@@ -0,0 +1,41 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/iand/logfmtr"
+)
+
+func TestUnderlier(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.DefaultFields = []interface{}{"service", "api"}
+	logger := logfmtr.NewWithOptions(opts).WithName("http").WithValues("a", 1)
+	logger = logfmtr.WithGroup(logger, "req").WithValues("id", 7)
+
+	u, ok := logger.GetSink().(logfmtr.Underlier)
+	if !ok {
+		t.Fatalf("sink does not implement Underlier")
+	}
+	info := u.GetUnderlying()
+	if got := info.Name(); got != "http" {
+		t.Errorf("got name %q, wanted %q", got, "http")
+	}
+	if got, want := info.Values(), []interface{}{"a", 1, "req.id", 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got values %v, wanted %v", got, want)
+	}
+	if got, want := info.Groups(), []string{"req"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got groups %v, wanted %v", got, want)
+	}
+	if got := info.Options(); got.Writer != &buf || got.NameDelim != opts.NameDelim {
+		t.Errorf("got options %+v, wanted the options used to create the logger", got)
+	}
+
+	if _, ok := logfmtr.Describe(logr.Discard()); ok {
+		t.Errorf("got info for a logger not created by logfmtr")
+	}
+}
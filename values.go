@@ -0,0 +1,255 @@
+package logfmtr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DurationFormat selects how time.Duration values of key/value pairs are written.
+type DurationFormat int
+
+const (
+	// DurationString writes durations using their String method, such as 1.5s.
+	DurationString DurationFormat = iota
+
+	// DurationSeconds writes durations as a number of seconds, such as 1.5.
+	DurationSeconds
+
+	// DurationMillis writes durations as a number of milliseconds, such as 1500.
+	DurationMillis
+
+	// DurationNanos writes durations as a whole number of nanoseconds, such as 1500000000.
+	DurationNanos
+)
+
+// String returns the name of the format.
+func (f DurationFormat) String() string {
+	switch f {
+	case DurationString:
+		return "string"
+	case DurationSeconds:
+		return "seconds"
+	case DurationMillis:
+		return "millis"
+	case DurationNanos:
+		return "nanos"
+	default:
+		return fmt.Sprintf("DurationFormat(%d)", int(f))
+	}
+}
+
+// TimeFormat selects how time.Time values of key/value pairs are written.
+type TimeFormat int
+
+const (
+	// TimeDefault writes times using their String method in logfmt and human output, such as
+	// "2024-01-02 03:04:05 +0000 UTC", and in RFC 3339 format in JSON.
+	TimeDefault TimeFormat = iota
+
+	// TimeRFC3339 writes times in RFC 3339 format with nanosecond precision, such as
+	// 2024-01-02T03:04:05.5Z, in the time zone used for timestamps.
+	TimeRFC3339
+
+	// TimeTimestamp writes times using the same format and time zone as the timestamp of the entry.
+	// RFC 3339 format is used if the timestamp format is empty.
+	TimeTimestamp
+
+	// TimeEpochSeconds writes times as a whole number of seconds since the Unix epoch.
+	TimeEpochSeconds
+
+	// TimeEpochMillis writes times as a whole number of milliseconds since the Unix epoch.
+	TimeEpochMillis
+)
+
+// String returns the name of the format.
+func (f TimeFormat) String() string {
+	switch f {
+	case TimeDefault:
+		return "default"
+	case TimeRFC3339:
+		return "rfc3339"
+	case TimeTimestamp:
+		return "timestamp"
+	case TimeEpochSeconds:
+		return "epochseconds"
+	case TimeEpochMillis:
+		return "epochmillis"
+	default:
+		return fmt.Sprintf("TimeFormat(%d)", int(f))
+	}
+}
+
+// number is a floating point value formatted using a FloatFormat. It is written without quotes.
+type number string
+
+func (n number) String() string {
+	return string(n)
+}
+
+// rawJSON is a value encoded as compact JSON. It is written as a string in logfmt and human output and
+// as JSON by a JSONEncoder.
+type rawJSON string
+
+func (j rawJSON) String() string {
+	return string(j)
+}
+
+// complexJSON returns v encoded as compact JSON if it is a map, slice, array or struct, or a pointer to
+// one, and reports whether it was encoded. Values with a String or Error method are only encoded if they
+// also have a MarshalJSON method. Byte slices and times are not encoded since they have their own formats.
+func complexJSON(v interface{}) (rawJSON, bool) {
+	switch v.(type) {
+	case nil, []byte, time.Time, *time.Time:
+		return "", false
+	case json.Marshaler:
+		// encoded below even if it also has a String method
+	case fmt.Stringer, error:
+		return "", false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+	default:
+		return "", false
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return rawJSON(data), true
+}
+
+// validateFloatFormat reports an error if format is not a single fmt verb for floating point numbers,
+// such as %.6g or %.2f. Flags that add spaces are not permitted since the result would need quoting.
+func validateFloatFormat(format string) error {
+	if len(format) < 2 || format[0] != '%' {
+		return errors.New("must start with %")
+	}
+	if !strings.ContainsRune("eEfFgG", rune(format[len(format)-1])) {
+		return fmt.Errorf("must end with one of the verbs e, E, f, F, g or G, got %q", format)
+	}
+	for _, r := range format[1 : len(format)-1] {
+		if !strings.ContainsRune("+#0.123456789", r) {
+			return fmt.Errorf("unsupported character %q in %q", r, format)
+		}
+	}
+	return nil
+}
+
+// valueFormatter rewrites the values of key/value pairs that have a format other than the default.
+type valueFormatter struct {
+	bytes      BytesFormat
+	duration   DurationFormat
+	time       TimeFormat
+	float      string
+	json       bool
+	timeLayout string
+	location   *time.Location
+}
+
+// newValueFormatter returns a formatter for the value formats set by the options, or nil if they are
+// all the default.
+func newValueFormatter(o Options) *valueFormatter {
+	if o.BytesFormat == BytesDefault && o.DurationFormat == DurationString && o.TimeFormat == TimeDefault && o.FloatFormat == "" && !o.ComplexValueJSON {
+		return nil
+	}
+	f := &valueFormatter{
+		bytes:      o.BytesFormat,
+		duration:   o.DurationFormat,
+		time:       o.TimeFormat,
+		float:      o.FloatFormat,
+		json:       o.ComplexValueJSON,
+		timeLayout: time.RFC3339Nano,
+		location:   o.location(),
+	}
+	if o.TimeFormat == TimeTimestamp && o.TimestampFormat != "" {
+		f.timeLayout = o.TimestampFormat
+	}
+	return f
+}
+
+// apply returns the entry with the values of its key/value pairs formatted.
+func (f *valueFormatter) apply(e Entry) Entry {
+	e.Context = mapValues(e.Context, f.format)
+	e.Values = mapValues(e.Values, f.format)
+	return e
+}
+
+// format returns the formatted form of v and reports whether it differs from v.
+func (f *valueFormatter) format(v interface{}) (interface{}, bool) {
+	switch vv := v.(type) {
+	case float64:
+		if f.float != "" {
+			return f.formatFloat(vv), true
+		}
+	case float32:
+		if f.float != "" {
+			return number(fmt.Sprintf(f.float, vv)), true
+		}
+	case []byte:
+		if f.bytes != BytesDefault {
+			return f.bytes.format(vv), true
+		}
+	case time.Duration:
+		switch f.duration {
+		case DurationSeconds:
+			return f.formatFloat(vv.Seconds()), true
+		case DurationMillis:
+			return f.formatFloat(float64(vv) / float64(time.Millisecond)), true
+		case DurationNanos:
+			return int64(vv), true
+		}
+	case time.Time:
+		switch f.time {
+		case TimeRFC3339, TimeTimestamp:
+			return inLocation(vv, f.location).Format(f.timeLayout), true
+		case TimeEpochSeconds:
+			return vv.Unix(), true
+		case TimeEpochMillis:
+			return vv.UnixNano() / int64(time.Millisecond), true
+		}
+	default:
+		if f.json {
+			if j, ok := complexJSON(v); ok {
+				return j, true
+			}
+		}
+	}
+	return v, false
+}
+
+// formatFloat returns v formatted using the float format, or v if there is none.
+func (f *valueFormatter) formatFloat(v float64) interface{} {
+	if f.float == "" {
+		return v
+	}
+	return number(fmt.Sprintf(f.float, v))
+}
+
+// mapValues returns kvs with each value replaced by the result of fn when fn reports that it changed.
+// The slice is copied before it is changed since it may be shared with other entries.
+func mapValues(kvs []interface{}, fn func(v interface{}) (interface{}, bool)) []interface{} {
+	copied := false
+	for i := 1; i < len(kvs); i += 2 {
+		v, ok := fn(kvs[i])
+		if !ok {
+			continue
+		}
+		if !copied {
+			kvs = append([]interface{}(nil), kvs...)
+			copied = true
+		}
+		kvs[i] = v
+	}
+	return kvs
+}
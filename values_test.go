@@ -0,0 +1,180 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestDurationFormat(t *testing.T) {
+	testCases := []struct {
+		format logfmtr.DurationFormat
+		want   string
+	}{
+		{format: logfmtr.DurationString, want: "level=0 msg=done took=1.5s\n"},
+		{format: logfmtr.DurationSeconds, want: "level=0 msg=done took=1.5\n"},
+		{format: logfmtr.DurationMillis, want: "level=0 msg=done took=1500\n"},
+		{format: logfmtr.DurationNanos, want: "level=0 msg=done took=1500000000\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.DurationFormat = tc.format
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("done", "took", 1500*time.Millisecond)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	when := time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC)
+
+	testCases := []struct {
+		format logfmtr.TimeFormat
+		json   bool
+		want   string
+	}{
+		{format: logfmtr.TimeDefault, want: `level=0 msg=done at="2024-01-02 03:04:05.5 +0000 UTC"` + "\n"},
+		{format: logfmtr.TimeRFC3339, want: `level=0 msg=done at=2024-01-02T03:04:05.5Z` + "\n"},
+		{format: logfmtr.TimeTimestamp, want: `level=0 msg=done at="Jan  2 03:04:05"` + "\n"},
+		{format: logfmtr.TimeEpochSeconds, want: `level=0 msg=done at=1704164645` + "\n"},
+		{format: logfmtr.TimeEpochMillis, want: `level=0 msg=done at=1704164645500` + "\n"},
+		{format: logfmtr.TimeEpochMillis, json: true, want: `{"level":0,"msg":"done","at":1704164645500}` + "\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.TimeFormat = tc.format
+			if tc.format == logfmtr.TimeTimestamp {
+				// the timestamp of the entry is not written, but its format is used for time values
+				opts.Encoder = &logfmtr.LogfmtEncoder{}
+				opts.TimestampFormat = time.Stamp
+			}
+			if tc.json {
+				opts.Encoder = &logfmtr.JSONEncoder{}
+			}
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("done", "at", when)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFloatFormat(t *testing.T) {
+	testCases := []struct {
+		format string
+		json   bool
+		want   string
+	}{
+		{format: "", want: "level=0 msg=done ratio=0.333333333333 f32=1.25 took=1.5 count=3\n"},
+		{format: "%.2f", want: "level=0 msg=done ratio=0.33 f32=1.25 took=1.50 count=3\n"},
+		{format: "%.3g", want: "level=0 msg=done ratio=0.333 f32=1.25 took=1.5 count=3\n"},
+		{format: "%+.1e", want: "level=0 msg=done ratio=+3.3e-01 f32=+1.2e+00 took=+1.5e+00 count=3\n"},
+		{format: "%.2f", json: true, want: `{"level":0,"msg":"done","ratio":0.33,"f32":1.25,"took":1.50,"count":3}` + "\n"},
+		{format: "%+.1f", json: true, want: `{"level":0,"msg":"done","ratio":"+0.3","f32":"+1.2","took":"+1.5","count":3}` + "\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.FloatFormat = tc.format
+			opts.DurationFormat = logfmtr.DurationSeconds
+			if tc.json {
+				opts.Encoder = &logfmtr.JSONEncoder{}
+			}
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("done", "ratio", 0.333333333333, "f32", float32(1.25), "took", 1500*time.Millisecond, "count", 3)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// point has a MarshalJSON method that takes precedence over its String method.
+type point struct{ X, Y int }
+
+func (p point) String() string               { return "point" }
+func (p point) MarshalJSON() ([]byte, error) { return []byte(`[1,2]`), nil }
+
+func TestComplexValueJSON(t *testing.T) {
+	testCases := []struct {
+		name string
+		json bool
+		fn   func(*logfmtr.Options)
+		want string
+	}{
+		{
+			name: "logfmt",
+			want: `level=0 msg=hello reasons=[0.1,0.11,3.14] m="{\"a\":1}" s="{\"Name\":\"x\"}" p=[1,2] ip=127.0.0.1 err=failed b="[104 105]" at="2024-01-02 00:00:00 +0000 UTC"` + "\n",
+		},
+		{
+			name: "json",
+			json: true,
+			want: `{"level":0,"msg":"hello","reasons":[0.1,0.11,3.14],"m":{"a":1},"s":{"Name":"x"},"p":[1,2],"ip":"127.0.0.1","err":"failed","b":"aGk=","at":"2024-01-02T00:00:00Z"}` + "\n",
+		},
+		{
+			name: "flattened",
+			fn:   func(o *logfmtr.Options) { o.FlattenDepth = 1 },
+			want: `level=0 msg=hello reasons=[0.1,0.11,3.14] m.a=1 s.Name=x p=[1,2] ip=127.0.0.1 err=failed b="[104 105]" at="2024-01-02 00:00:00 +0000 UTC"` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := logfmtr.DefaultOptions()
+			opts.Writer = &buf
+			opts.TimestampFormat = ""
+			opts.ComplexValueJSON = true
+			if tc.json {
+				opts.Encoder = &logfmtr.JSONEncoder{}
+			}
+			if tc.fn != nil {
+				tc.fn(&opts)
+			}
+			logger := logfmtr.NewWithOptions(opts)
+
+			logger.Info("hello",
+				"reasons", []float64{0.1, 0.11, 3.14},
+				"m", map[string]int{"a": 1},
+				"s", &struct{ Name string }{"x"},
+				"p", point{},
+				"ip", net.IPv4(127, 0, 0, 1),
+				"err", errors.New("failed"),
+				"b", []byte("hi"),
+				"at", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			)
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package logfmtr
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	watchersMu        sync.Mutex // synchronises access to the registered watchers
+	watcherID         int
+	optionsWatchers   []optionsWatcher
+	verbosityWatchers []verbosityWatcher
+
+	optionsChangeMu   sync.Mutex // held while the global options are changed and watchers are called, so changes are seen in order
+	verbosityChangeMu sync.Mutex // held while the global verbosity is changed and watchers are called
+)
+
+type optionsWatcher struct {
+	id int
+	fn func(old, new Options)
+}
+
+type verbosityWatcher struct {
+	id int
+	fn func(old, new int)
+}
+
+// CurrentOptions returns a copy of the options set by UseOptions, or the default options if UseOptions has
+// not been called. Slices and maps in the options are shared and must not be modified.
+func CurrentOptions() Options {
+	goptionsmu.Lock()
+	defer goptionsmu.Unlock()
+	return goptions
+}
+
+// OnOptionsChange registers fn to be called with the previous and new options each time the options set by
+// UseOptions are changed, including by FormatFlag. It is called by the goroutine that changed the options
+// after the change has been made and must not change the options itself. OnOptionsChange returns a function
+// that removes the registration.
+func OnOptionsChange(fn func(old, new Options)) (remove func()) {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	watcherID++
+	id := watcherID
+	optionsWatchers = append(optionsWatchers[:len(optionsWatchers):len(optionsWatchers)], optionsWatcher{id: id, fn: fn})
+	return func() {
+		watchersMu.Lock()
+		defer watchersMu.Unlock()
+		for i, w := range optionsWatchers {
+			if w.id == id {
+				optionsWatchers = append(optionsWatchers[:i:i], optionsWatchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// OnVerbosityChange registers fn to be called with the previous and new global verbosity each time it is
+// changed, such as by SetVerbosity, VerbosityFlag or HandleSignals. It is called by the goroutine that
+// changed the verbosity and must not change the verbosity itself. OnVerbosityChange returns a function that
+// removes the registration.
+func OnVerbosityChange(fn func(old, new int)) (remove func()) {
+	watchersMu.Lock()
+	defer watchersMu.Unlock()
+	watcherID++
+	id := watcherID
+	verbosityWatchers = append(verbosityWatchers[:len(verbosityWatchers):len(verbosityWatchers)], verbosityWatcher{id: id, fn: fn})
+	return func() {
+		watchersMu.Lock()
+		defer watchersMu.Unlock()
+		for i, w := range verbosityWatchers {
+			if w.id == id {
+				verbosityWatchers = append(verbosityWatchers[:i:i], verbosityWatchers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// changeOptions applies change to the global options and then calls the functions registered by
+// OnOptionsChange. Live loggers are rebuilt with the new options. The watchers are not called if change
+// returns an error.
+func changeOptions(change func(o *Options) error) error {
+	optionsChangeMu.Lock()
+	defer optionsChangeMu.Unlock()
+
+	goptionsmu.Lock()
+	old := goptions
+	err := change(&goptions)
+	next := goptions
+	if err == nil {
+		atomic.AddUint32(&optionsGen, 1)
+	}
+	goptionsmu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	watchersMu.Lock()
+	watchers := optionsWatchers
+	watchersMu.Unlock()
+	for _, w := range watchers {
+		w.fn(old, next)
+	}
+	return nil
+}
+
+// changeVerbosity sets the global verbosity to the value returned by change, which is passed the current
+// verbosity, and then calls the functions registered by OnVerbosityChange if it differs. It returns the
+// previous verbosity.
+func changeVerbosity(change func(v int32) int32) int {
+	verbosityChangeMu.Lock()
+	defer verbosityChangeMu.Unlock()
+
+	old := atomic.LoadInt32(&gv)
+	v := change(old)
+	atomic.StoreInt32(&gv, v)
+	if v == old {
+		return int(old)
+	}
+
+	watchersMu.Lock()
+	watchers := verbosityWatchers
+	watchersMu.Unlock()
+	for _, w := range watchers {
+		w.fn(int(old), int(v))
+	}
+	return int(old)
+}
+
+// Diff returns the names of the fields of o whose values differ from those of other, in the order they are
+// declared. Functions, pointers and the values of interfaces holding pointers, such as writers, are
+// compared by identity; other values are compared by content.
+func (o Options) Diff(other Options) []string {
+	a, b := reflect.ValueOf(o), reflect.ValueOf(other)
+	var fields []string
+	for i := 0; i < a.NumField(); i++ {
+		if !sameValue(a.Field(i), b.Field(i)) {
+			fields = append(fields, a.Type().Field(i).Name)
+		}
+	}
+	return fields
+}
+
+// sameValue reports whether a and b, which have the same type, hold the same value.
+func sameValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Func, reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return a.Elem().Type() == b.Elem().Type() && sameValue(a.Elem(), b.Elem())
+	case reflect.Slice:
+		if a.IsNil() != b.IsNil() {
+			return false
+		}
+		fallthrough
+	case reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !sameValue(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			bv := b.MapIndex(iter.Key())
+			if !bv.IsValid() || !sameValue(iter.Value(), bv) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !sameValue(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	default:
+		return true
+	}
+}
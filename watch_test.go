@@ -0,0 +1,82 @@
+package logfmtr_test
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+func TestOnOptionsChange(t *testing.T) {
+	defer logfmtr.UseOptions(logfmtr.DefaultOptions())
+	logfmtr.UseOptions(logfmtr.DefaultOptions())
+
+	var diffs [][]string
+	remove := logfmtr.OnOptionsChange(func(old, new logfmtr.Options) {
+		diffs = append(diffs, old.Diff(new))
+		if diff := logfmtr.CurrentOptions().Diff(new); diff != nil {
+			t.Errorf("current options differ from new options in %v", diff)
+		}
+	})
+
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.AddCaller = true
+	logfmtr.UseOptions(opts)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(logfmtr.FormatFlag(), "log-format", "")
+	if err := fs.Parse([]string{"-log-format=human"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	remove()
+	logfmtr.UseOptions(logfmtr.DefaultOptions())
+
+	want := [][]string{{"Writer", "AddCaller"}, {"Humanize"}}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("got changes %v, wanted %v", diffs, want)
+	}
+}
+
+func TestOnVerbosityChange(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(0))
+
+	var changes [][2]int
+	remove := logfmtr.OnVerbosityChange(func(old, new int) {
+		changes = append(changes, [2]int{old, new})
+	})
+	logfmtr.SetVerbosity(2)
+	logfmtr.SetVerbosity(2)
+	logfmtr.SetVerbosity(1)
+	remove()
+	logfmtr.SetVerbosity(3)
+
+	want := [][2]int{{0, 2}, {2, 1}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got changes %v, wanted %v", changes, want)
+	}
+	if got := logfmtr.Verbosity(); got != 3 {
+		t.Errorf("got verbosity %d, wanted 3", got)
+	}
+}
+
+func TestOptionsDiff(t *testing.T) {
+	a := logfmtr.DefaultOptions()
+	if got := a.Diff(logfmtr.DefaultOptions()); got != nil {
+		t.Errorf("got differences %v between default options, wanted none", got)
+	}
+
+	b := a.With(logfmtr.WithLevelNames(map[int]string{0: "info"}), logfmtr.WithDefaultFields("service", "api"))
+	if got, want := a.Diff(b), []string{"LevelNames", "DefaultFields"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got differences %v, wanted %v", got, want)
+	}
+
+	c := b.With(logfmtr.WithLevelNames(map[int]string{0: "info"}), logfmtr.WithWriter(&bytes.Buffer{}))
+	if got, want := b.Diff(c), []string{"Writer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got differences %v, wanted %v", got, want)
+	}
+}
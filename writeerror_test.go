@@ -0,0 +1,67 @@
+package logfmtr_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/iand/logfmtr"
+)
+
+var errDiskFull = errors.New("disk full")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errDiskFull
+}
+
+func TestOnWriteError(t *testing.T) {
+	var gotErr error
+	var gotEntry string
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(failingWriter{}),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithOnWriteError(func(err error, entry []byte) {
+			gotErr = err
+			gotEntry = string(entry)
+		}),
+	)
+	logger.Info("hello", "a", 1)
+
+	if gotErr != errDiskFull {
+		t.Errorf("got error %v, wanted %v", gotErr, errDiskFull)
+	}
+	if want := "level=0 msg=hello a=1\n"; gotEntry != want {
+		t.Errorf("got entry %q, wanted %q", gotEntry, want)
+	}
+}
+
+func TestWriteErrorFallback(t *testing.T) {
+	f, err := ioutil.TempFile("", "stderr")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	stderr := os.Stderr
+	os.Stderr = f
+	defer func() { os.Stderr = stderr }()
+
+	logger := logfmtr.NewWith(
+		logfmtr.WithWriter(failingWriter{}),
+		logfmtr.WithTimestampFormat(""),
+		logfmtr.WithWriteErrorFallback(),
+	)
+	logger.Info("hello", "a", 1)
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := string(data), "level=0 msg=hello a=1\n"; got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package writers
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+// DefaultBufferedSize is the buffer size used by NewBuffered when the size given is not positive.
+const DefaultBufferedSize = 64 << 10
+
+// BufferedWriter holds entries in memory and writes them to an underlying writer in batches, reducing
+// the number of writes made when logging to files. Entries are never split between writes. A
+// BufferedWriter is safe for concurrent use.
+type BufferedWriter struct {
+	w    io.Writer
+	size int
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu     sync.Mutex // guards the fields below
+	buf    []byte
+	closed bool
+}
+
+// NewBuffered returns a writer that buffers up to size bytes of entries before writing them to w. The
+// buffer is also written every flushEvery, unless flushEvery is zero, and when the writer is flushed,
+// for example by logfmtr.Flush when it has been registered with logfmtr.RegisterWriter, or closed. An
+// entry larger than the buffer is written directly after any buffered entries. Entries still buffered
+// when the program exits are lost, so the writer should be closed, or registered and logfmtr.Close
+// called, before exiting.
+func NewBuffered(w io.Writer, size int, flushEvery time.Duration) *BufferedWriter {
+	if size <= 0 {
+		size = DefaultBufferedSize
+	}
+	b := &BufferedWriter{
+		w:    w,
+		size: size,
+		done: make(chan struct{}),
+		buf:  make([]byte, 0, size),
+	}
+	if flushEvery > 0 {
+		b.wg.Add(1)
+		go b.flushPeriodically(flushEvery)
+	}
+	return b
+}
+
+// Write adds p to the buffer, first writing the buffered entries to the underlying writer if p would
+// not fit. If the buffered entries cannot be written they are discarded and the error is returned.
+func (b *BufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+	if len(b.buf)+len(p) > b.size {
+		if err := b.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	if len(p) >= b.size {
+		return b.w.Write(p)
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+// Flush writes the buffered entries to the underlying writer and flushes it if it has a Flush or Sync
+// method.
+func (b *BufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.flushBuffer(); err != nil {
+		return err
+	}
+	return flushWriter(b.w)
+}
+
+// Close writes the buffered entries to the underlying writer, stops writing them periodically and
+// closes the underlying writer if it has a Close method, unless it is standard output or standard
+// error. Subsequent writes return logfmtr.ErrWriterClosed.
+func (b *BufferedWriter) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	err := b.flushBuffer()
+	b.mu.Unlock()
+	b.wg.Wait()
+	if cerr := closeWriter(b.w); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// flushBuffer writes the buffered entries to the underlying writer in a single write. The buffer is
+// emptied even if the write fails. b.mu must be held.
+func (b *BufferedWriter) flushBuffer() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+	_, err := b.w.Write(b.buf)
+	b.buf = b.buf[:0]
+	return err
+}
+
+func (b *BufferedWriter) flushPeriodically(every time.Duration) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.mu.Lock()
+			_ = b.flushBuffer()
+			b.mu.Unlock()
+		case <-b.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package writers_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers"
+)
+
+// countingWriter records each write made to it.
+type countingWriter struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func (w *countingWriter) Writes() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.writes...)
+}
+
+func TestBuffered(t *testing.T) {
+	cw := &countingWriter{}
+	w := writers.NewBuffered(cw, 64, 0)
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("one")
+	logger.Info("two")
+	if got := cw.Writes(); len(got) != 0 {
+		t.Fatalf("got writes %q before buffer was full, wanted none", got)
+	}
+
+	logger.Info("three", "padding", strings.Repeat("x", 20))
+	logger.Info(strings.Repeat("y", 70))
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	want := []string{
+		"level=0 msg=one\nlevel=0 msg=two\n",
+		"level=0 msg=three padding=xxxxxxxxxxxxxxxxxxxx\n",
+		"level=0 msg=" + strings.Repeat("y", 70) + "\n",
+	}
+	got := cw.Writes()
+	if len(got) != len(want) {
+		t.Fatalf("got writes %q, wanted %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got write %d %q, wanted %q", i, got[i], want[i])
+		}
+	}
+
+	if _, err := w.Write([]byte("late\n")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
+
+func TestBufferedFlushEvery(t *testing.T) {
+	cw := &countingWriter{}
+	w := writers.NewBuffered(cw, 0, 10*time.Millisecond)
+	defer w.Close()
+
+	if _, err := w.Write([]byte("level=0 msg=hello\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(cw.Writes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got, want := cw.Writes(), []string{"level=0 msg=hello\n"}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got writes %q, wanted %q", got, want)
+	}
+}
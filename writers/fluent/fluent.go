@@ -0,0 +1,427 @@
+// Package fluent sends log entries to Fluentd or Fluent Bit using the forward protocol.
+//
+// An Encoder writes each entry as a forward protocol message whose tag is derived from the logger name
+// and whose record holds the fields of the entry. A Writer sends messages over TCP, buffering them while
+// it reconnects in the background when the connection fails, and optionally waiting for the server to
+// acknowledge each message:
+//
+//	w, err := fluent.Dial("localhost:24224", fluent.DefaultConfig())
+//	if err != nil {
+//		// handle error
+//	}
+//	opts := logfmtr.DefaultOptions()
+//	opts.Writer = w
+//	opts.Encoder = &fluent.Encoder{Tag: "myapp"}
+//	logger := logfmtr.NewWithOptions(opts)
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+// DefaultTag is the tag used when no tag is configured.
+const DefaultTag = "logfmtr"
+
+// messageHeader is the first byte of a forward protocol message without options, an array of three elements.
+const messageHeader = 0x93
+
+var _ logfmtr.Encoder = (*Encoder)(nil)
+
+// Encoder writes entries as forward protocol messages. The tag of each message is the Encoder's Tag
+// followed by a dot and the logger name, if the logger has a name. The record holds the level, logger
+// name, message, caller, error and stacktrace of the entry under the keys level, logger, msg, caller, error
+// and stacktrace, followed by its key/value pairs. Numbers and booleans keep their type and other values are
+// written as strings.
+type Encoder struct {
+	// Tag is the prefix of the tag of each message. When empty DefaultTag is used.
+	Tag string
+}
+
+// EncodeEntry writes the entry as a single forward protocol message.
+func (enc *Encoder) EncodeEntry(w io.Writer, e logfmtr.Entry) error {
+	tag := enc.Tag
+	if tag == "" {
+		tag = DefaultTag
+	}
+	if e.Name != "" {
+		tag += "." + e.Name
+	}
+
+	n := 2 + (len(e.Context)+1)/2 + (len(e.Values)+1)/2
+	if e.Name != "" {
+		n++
+	}
+	if e.Caller != "" {
+		n++
+	}
+	if e.IsError {
+		n++
+	}
+	if e.Stacktrace != "" {
+		n++
+	}
+
+	b := make([]byte, 0, 256)
+	b = append(b, messageHeader)
+	b = appendString(b, tag)
+	b = appendEventTime(b, e.Time)
+	b = appendMapHeader(b, n)
+	b = appendString(b, "level")
+	b = appendInt(b, int64(e.Level))
+	if e.Name != "" {
+		b = appendString(b, "logger")
+		b = appendString(b, e.Name)
+	}
+	b = appendString(b, "msg")
+	b = appendString(b, e.Message)
+	if e.Caller != "" {
+		b = appendString(b, "caller")
+		b = appendString(b, e.Caller)
+	}
+	if e.IsError {
+		b = appendString(b, "error")
+		b = appendString(b, fmt.Sprint(e.Error))
+	}
+	if e.Stacktrace != "" {
+		b = appendString(b, "stacktrace")
+		b = appendString(b, e.Stacktrace)
+	}
+	b = appendKVs(b, e.Context)
+	b = appendKVs(b, e.Values)
+	_, err := w.Write(b)
+	return err
+}
+
+func appendKVs(b []byte, kvs []interface{}) []byte {
+	for i := 0; i < len(kvs); i += 2 {
+		b = appendString(b, fmt.Sprint(kvs[i]))
+		if i+1 < len(kvs) {
+			b = appendValue(b, kvs[i+1])
+		} else {
+			b = appendString(b, "")
+		}
+	}
+	return b
+}
+
+// Config holds the settings of a Writer.
+type Config struct {
+	// Tag is the tag of messages created for writes that are not forward protocol messages, such as lines
+	// written by logfmtr's default encoders. When empty DefaultTag is used.
+	Tag string
+
+	// RequireAck makes each write wait for the server to acknowledge the message. Messages that are not
+	// acknowledged are sent again on a new connection, so the server may receive a message more than once.
+	RequireAck bool
+
+	// Timeout limits the time taken to connect, to send each message and to receive each acknowledgement.
+	// When zero there is no limit.
+	Timeout time.Duration
+
+	// MinBackoff is the delay before the first attempt to reconnect after a connection fails. When zero
+	// or negative the default of 100 milliseconds is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the longest delay between attempts to reconnect. The delay doubles after each
+	// failed attempt until it reaches MaxBackoff. When zero or negative the default of 30 seconds is
+	// used. It is raised to MinBackoff if it is smaller.
+	MaxBackoff time.Duration
+
+	// BufferSize is the maximum number of bytes of messages held in memory while disconnected. When the
+	// buffer is full the oldest messages are dropped to make room. When zero or negative the default of
+	// 1MiB is used.
+	BufferSize int
+}
+
+// DefaultConfig returns a Config that uses DefaultTag, does not wait for acknowledgements, times out
+// after ten seconds, reconnects with delays between 100 milliseconds and 30 seconds and buffers up to
+// 1MiB of messages while disconnected.
+func DefaultConfig() Config {
+	return Config{
+		Tag:        DefaultTag,
+		Timeout:    10 * time.Second,
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+		BufferSize: defaultBufferSize,
+	}
+}
+
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+	defaultBufferSize = 1 << 20
+)
+
+// withDefaults returns the config with the defaults applied to settings that must be set.
+func (cfg Config) withDefaults() Config {
+	if cfg.Tag == "" {
+		cfg.Tag = DefaultTag
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = cfg.MinBackoff
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	return cfg
+}
+
+// Writer sends messages to a Fluentd or Fluent Bit server using the forward protocol. Each call to Write
+// sends one message. Writes that are forward protocol messages, such as those written by an Encoder, are
+// sent unchanged. Other writes are sent in a message with the Config's tag and a record holding the
+// write, without any trailing newline, under the key message. If sending fails the Writer reconnects in
+// the background, waiting longer after each failed attempt, and buffers messages in memory until it is
+// connected again. It is safe for concurrent use.
+type Writer struct {
+	cfg  Config
+	addr string
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	mu           sync.Mutex // guards the fields below
+	conn         net.Conn
+	r            *bufio.Reader
+	buf          []message
+	buffered     int
+	dropped      uint64
+	reconnecting bool
+	closed       bool
+}
+
+// message is a forward protocol message waiting to be sent.
+type message struct {
+	msg   []byte
+	chunk string // the chunk option of the message, empty unless an acknowledgement is required
+}
+
+// Dial connects to the forward protocol server at addr using TCP. It returns an error if the first
+// connection cannot be established.
+func Dial(addr string, cfg Config) (*Writer, error) {
+	w := &Writer{
+		cfg:  cfg.withDefaults(),
+		addr: addr,
+		done: make(chan struct{}),
+	}
+	conn, err := w.dial()
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	w.r = bufio.NewReader(conn)
+	return w, nil
+}
+
+// dial establishes a new connection to the server.
+func (w *Writer) dial() (net.Conn, error) {
+	return net.DialTimeout("tcp", w.addr, w.cfg.Timeout)
+}
+
+// Write sends p as a single message. If the writer is disconnected, or sending fails, the message is
+// buffered and sent when the writer reconnects. Write only returns an error if the writer has been
+// closed or a chunk ID cannot be generated.
+func (w *Writer) Write(p []byte) (int, error) {
+	var msg []byte
+	if len(p) > 0 && p[0] == messageHeader {
+		msg = append(msg, p...)
+	} else {
+		msg = append(msg, messageHeader)
+		msg = appendString(msg, w.cfg.Tag)
+		msg = appendEventTime(msg, time.Now())
+		msg = appendMapHeader(msg, 1)
+		msg = appendString(msg, "message")
+		msg = appendString(msg, string(bytes.TrimRight(p, "\n")))
+	}
+
+	var chunk string
+	if w.cfg.RequireAck {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return 0, err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+		// add the options element requesting an acknowledgement
+		msg[0] = messageHeader + 1
+		msg = appendMapHeader(msg, 1)
+		msg = appendString(msg, "chunk")
+		msg = appendString(msg, chunk)
+	}
+
+	m := message{msg: msg, chunk: chunk}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+	if w.conn != nil {
+		if err := w.send(w.conn, w.r, m); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		w.r = nil
+		w.startReconnect()
+	}
+	w.enqueue(m)
+	return len(p), nil
+}
+
+// enqueue adds a message to the buffer, dropping the oldest messages if the buffer is full. w.mu must be
+// held.
+func (w *Writer) enqueue(m message) {
+	if len(m.msg) > w.cfg.BufferSize {
+		w.dropped++
+		return
+	}
+	for w.buffered+len(m.msg) > w.cfg.BufferSize {
+		w.buffered -= len(w.buf[0].msg)
+		w.buf[0] = message{}
+		w.buf = w.buf[1:]
+		w.dropped++
+	}
+	w.buf = append(w.buf, m)
+	w.buffered += len(m.msg)
+}
+
+// startReconnect starts a goroutine that reconnects to the server unless one is already running. w.mu
+// must be held.
+func (w *Writer) startReconnect() {
+	if w.reconnecting {
+		return
+	}
+	w.reconnecting = true
+	w.wg.Add(1)
+	go w.reconnect()
+}
+
+// reconnect attempts to connect to the server, waiting longer after each failure, until it succeeds in
+// connecting and sending any buffered messages or the writer is closed.
+func (w *Writer) reconnect() {
+	defer w.wg.Done()
+	backoff := w.cfg.MinBackoff
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-t.C:
+		}
+
+		if conn, err := w.dial(); err == nil && w.resume(conn) {
+			return
+		}
+
+		backoff *= 2
+		if backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+		t.Reset(backoff)
+	}
+}
+
+// resume sends buffered messages over a new connection and then makes it the writer's connection.
+// Messages are sent without holding the writer's lock, so messages written meanwhile are buffered behind
+// them. It reports false, closing the connection, if a message could not be sent.
+func (w *Writer) resume(conn net.Conn) bool {
+	r := bufio.NewReader(conn)
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			conn.Close()
+			return true
+		}
+		if len(w.buf) == 0 {
+			w.conn = conn
+			w.r = r
+			w.reconnecting = false
+			w.mu.Unlock()
+			return true
+		}
+		m := w.buf[0]
+		w.buf[0] = message{}
+		w.buf = w.buf[1:]
+		w.buffered -= len(m.msg)
+		w.mu.Unlock()
+
+		if err := w.send(conn, r, m); err != nil {
+			conn.Close()
+			w.mu.Lock()
+			if !w.closed {
+				// keep the message at the front so it is sent first on the next connection
+				w.buf = append([]message{m}, w.buf...)
+				w.buffered += len(m.msg)
+			}
+			w.mu.Unlock()
+			return false
+		}
+	}
+}
+
+// send writes a message to conn and, if the message requires it, waits for it to be acknowledged by
+// reading from r.
+func (w *Writer) send(conn net.Conn, r *bufio.Reader, m message) error {
+	if w.cfg.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(w.cfg.Timeout))
+	}
+	if _, err := conn.Write(m.msg); err != nil {
+		return err
+	}
+	if m.chunk == "" {
+		return nil
+	}
+	resp, err := readStringMap(r)
+	if err != nil {
+		return err
+	}
+	if resp["ack"] != m.chunk {
+		return fmt.Errorf("fluent: unexpected acknowledgement %q", resp["ack"])
+	}
+	return nil
+}
+
+// Dropped returns the number of messages dropped because the buffer was full.
+func (w *Writer) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Close closes the connection to the server and stops any attempt to reconnect. Messages that are still
+// buffered are discarded. Subsequent writes return logfmtr.ErrWriterClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	var err error
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+		w.r = nil
+	}
+	w.buf = nil
+	w.buffered = 0
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
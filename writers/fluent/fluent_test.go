@@ -0,0 +1,183 @@
+package fluent_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers/fluent"
+)
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Encoder = &fluent.Encoder{Tag: "app"}
+	opts.Clock = func() time.Time { return time.Unix(1704164645, 678) }
+	logger := logfmtr.NewWithOptions(opts).WithName("europa")
+
+	logger.Error(errors.New("uh oh"), "bye", "val", 1, "ok", true)
+
+	want := []byte{0x93, 0xaa}
+	want = append(want, "app.europa"...)
+	want = append(want, 0xd7, 0x00, 0x65, 0x93, 0x7d, 0x25, 0x00, 0x00, 0x02, 0xa6)
+	want = append(want, 0x86, 0xa5)
+	want = append(want, "level"...)
+	want = append(want, 0x00, 0xa6)
+	want = append(want, "logger"...)
+	want = append(want, 0xa6)
+	want = append(want, "europa"...)
+	want = append(want, 0xa3)
+	want = append(want, "msg"...)
+	want = append(want, 0xa3)
+	want = append(want, "bye"...)
+	want = append(want, 0xa5)
+	want = append(want, "error"...)
+	want = append(want, 0xa5)
+	want = append(want, "uh oh"...)
+	want = append(want, 0xa3)
+	want = append(want, "val"...)
+	want = append(want, 0x01, 0xa2)
+	want = append(want, "ok"...)
+	want = append(want, 0xc3)
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("got % x, wanted % x", got, want)
+	}
+}
+
+// acceptMessages accepts connections on ln and passes the bytes of each message to handle, which
+// returns the response to send. Connections are closed after the first message when closeFirst is set.
+func acceptMessages(ln net.Listener, size int, closeFirst bool, handle func([]byte) []byte) {
+	first := true
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn, close bool) {
+			defer conn.Close()
+			for {
+				msg := make([]byte, size)
+				if _, err := io.ReadFull(conn, msg); err != nil {
+					return
+				}
+				if close {
+					return
+				}
+				conn.Write(handle(msg))
+			}
+		}(conn, first && closeFirst)
+		first = false
+	}
+}
+
+func TestWriterAck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 10)
+	// a message with a tag of "app", a record of {"message": "hello"} and a chunk option
+	size := 1 + 4 + 10 + 1 + 8 + 6 + 1 + 6 + 25
+	go acceptMessages(ln, size, true, func(msg []byte) []byte {
+		received <- msg
+		chunk := msg[len(msg)-24:]
+		resp := []byte{0x81, 0xa3, 'a', 'c', 'k', 0xb8}
+		return append(resp, chunk...)
+	})
+
+	cfg := fluent.DefaultConfig()
+	cfg.Tag = "app"
+	cfg.RequireAck = true
+	cfg.Timeout = 5 * time.Second
+	w, err := fluent.Dial(ln.Addr().String(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+	defer w.Close()
+
+	// the first connection is closed without an acknowledgement so the message is sent again
+	if _, err := w.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	msg := <-received
+	if msg[0] != 0x94 {
+		t.Errorf("got array header %x, wanted 94", msg[0])
+	}
+	record := []byte{0x81, 0xa7, 'm', 'e', 's', 's', 'a', 'g', 'e', 0xa5, 'h', 'e', 'l', 'l', 'o'}
+	if !bytes.Contains(msg, record) {
+		t.Errorf("got message % x, wanted it to contain record % x", msg, record)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("closed")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
+
+func TestWriterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	cfg := fluent.DefaultConfig()
+	cfg.Tag = "app"
+	cfg.MinBackoff = 5 * time.Millisecond
+	cfg.MaxBackoff = 20 * time.Millisecond
+	w, err := fluent.Dial(addr, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+	defer w.Close()
+
+	// stop the server so that writes fail and the writer reconnects in the background
+	(<-accepted).Close()
+	ln.Close()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte("hello\n")); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("writes took %s while disconnected, wanted them not to block", d)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("unable to listen again on %s: %v", addr, err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 10)
+	// a message with a tag of "app" and a record of {"message": "hello"}
+	size := 1 + 4 + 10 + 1 + 8 + 6 + 1 + 6
+	go acceptMessages(ln, size, false, func(msg []byte) []byte {
+		received <- msg
+		return nil
+	})
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a buffered message to be sent")
+	}
+}
@@ -0,0 +1,247 @@
+package fluent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// This file contains the subset of MessagePack needed to speak the forward protocol.
+
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return appendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(b, 0xde), uint16(n))
+	default:
+		return appendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = appendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = appendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return appendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return appendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return appendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 128:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return appendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return appendUint32(append(b, 0xce), uint32(v))
+	default:
+		return appendUint64(append(b, 0xcf), v)
+	}
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	return appendUint64(append(b, 0xcb), math.Float64bits(f))
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func appendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+// appendEventTime appends t using the EventTime extension type of the forward protocol, which holds
+// seconds and nanoseconds.
+func appendEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = appendUint32(b, uint32(t.Unix()))
+	return appendUint32(b, uint32(t.Nanosecond()))
+}
+
+// appendValue appends a value using the closest MessagePack type. Values that have no corresponding
+// type are written as strings.
+func appendValue(b []byte, v interface{}) []byte {
+	switch vv := v.(type) {
+	case nil:
+		return appendNil(b)
+	case string:
+		return appendString(b, vv)
+	case bool:
+		return appendBool(b, vv)
+	case int:
+		return appendInt(b, int64(vv))
+	case int8:
+		return appendInt(b, int64(vv))
+	case int16:
+		return appendInt(b, int64(vv))
+	case int32:
+		return appendInt(b, int64(vv))
+	case int64:
+		return appendInt(b, vv)
+	case uint:
+		return appendUint(b, uint64(vv))
+	case uint8:
+		return appendUint(b, uint64(vv))
+	case uint16:
+		return appendUint(b, uint64(vv))
+	case uint32:
+		return appendUint(b, uint64(vv))
+	case uint64:
+		return appendUint(b, vv)
+	case float32:
+		return appendFloat(b, float64(vv))
+	case float64:
+		return appendFloat(b, vv)
+	case time.Time:
+		return appendString(b, vv.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendString(b, vv.String())
+	}
+	return appendString(b, fmt.Sprint(v))
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+var errUnexpectedType = errors.New("fluent: unexpected type in response")
+
+// readStringMap reads a map of strings to strings, such as the acknowledgement sent by a forward server.
+func readStringMap(r *bufio.Reader) (map[string]string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case c&0xf0 == 0x80:
+		n = int(c & 0x0f)
+	case c == 0xde:
+		v, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		n = int(v)
+	case c == 0xdf:
+		v, err := readUint(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		n = int(v)
+	default:
+		return nil, errUnexpectedType
+	}
+	m := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		k, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n uint64
+	switch {
+	case c&0xe0 == 0xa0:
+		n = uint64(c & 0x1f)
+	case c == 0xd9, c == 0xc4:
+		n, err = readUint(r, 1)
+	case c == 0xda, c == 0xc5:
+		n, err = readUint(r, 2)
+	case c == 0xdb, c == 0xc6:
+		n, err = readUint(r, 4)
+	default:
+		return "", errUnexpectedType
+	}
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	var buf [4]byte
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range buf[:size] {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
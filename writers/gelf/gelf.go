@@ -0,0 +1,311 @@
+// Package gelf writes log entries in the Graylog Extended Log Format and sends them to a Graylog server
+// over UDP, splitting large messages into chunks.
+//
+//	w, err := gelf.Dial("graylog.example.com:12201", gelf.DefaultConfig())
+//	if err != nil {
+//		// handle error
+//	}
+//	opts := logfmtr.DefaultOptions()
+//	opts.Writer = w
+//	opts.Encoder = &gelf.Encoder{}
+//	logger := logfmtr.NewWithOptions(opts)
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/iand/logfmtr"
+)
+
+// Syslog severities used for the level field.
+const (
+	levelErr   = 3
+	levelInfo  = 6
+	levelDebug = 7
+)
+
+// invalidFieldChars matches characters not permitted in the names of additional fields.
+var invalidFieldChars = regexp.MustCompile(`[^\w.\-]`)
+
+var _ logfmtr.Encoder = (*Encoder)(nil)
+
+// Encoder writes entries as GELF 1.1 JSON messages terminated by a newline. The message is written to the
+// short_message field and the level is err for entries written by Error, info for entries at V level
+// zero and debug for entries at higher V levels. The stacktrace, if any, is written to the full_message
+// field. The logger name, caller and error are written to the additional fields _logger, _caller and
+// _error. Each key/value pair is written as an additional field named by the key prefixed with an
+// underscore, with any characters other than letters, digits, underscores, dashes and dots replaced by
+// underscores. Numeric values are written as numbers and all other values as strings.
+type Encoder struct {
+	// Host is written to the host field. When empty the name reported by os.Hostname is used.
+	Host string
+}
+
+// EncodeEntry writes the entry as a single GELF message.
+func (enc *Encoder) EncodeEntry(w io.Writer, e logfmtr.Entry) error {
+	host := enc.Host
+	if host == "" {
+		host = hostname()
+	}
+	b := make([]byte, 0, 256)
+	b = append(b, `{"version":"1.1","host":`...)
+	b = appendString(b, host)
+	b = append(b, `,"short_message":`...)
+	b = appendString(b, e.Message)
+	if e.Stacktrace != "" {
+		b = append(b, `,"full_message":`...)
+		b = appendString(b, e.Stacktrace)
+	}
+	if !e.Time.IsZero() {
+		b = append(b, `,"timestamp":`...)
+		b = strconv.AppendFloat(b, float64(e.Time.UnixNano()/1e6)/1e3, 'f', 3, 64)
+	}
+	b = append(b, `,"level":`...)
+	b = strconv.AppendInt(b, int64(level(e)), 10)
+	if e.Name != "" {
+		b = appendField(b, "logger", e.Name)
+	}
+	if e.Caller != "" {
+		b = appendField(b, "caller", e.Caller)
+	}
+	if e.IsError {
+		b = appendField(b, "error", fmt.Sprint(e.Error))
+	}
+	b = appendKVs(b, e.Context)
+	b = appendKVs(b, e.Values)
+	b = append(b, "}\n"...)
+	_, err := w.Write(b)
+	return err
+}
+
+func appendKVs(b []byte, kvs []interface{}) []byte {
+	for i := 0; i < len(kvs); i += 2 {
+		var v interface{} = ""
+		if i+1 < len(kvs) {
+			v = kvs[i+1]
+		}
+		b = appendField(b, fmt.Sprint(kvs[i]), v)
+	}
+	return b
+}
+
+// appendField appends an additional field. The field name _id is reserved so a key of id is written
+// as _id_.
+func appendField(b []byte, key string, v interface{}) []byte {
+	key = invalidFieldChars.ReplaceAllString(key, "_")
+	if key == "id" {
+		key = "id_"
+	}
+	b = append(b, ',')
+	b = appendString(b, "_"+key)
+	b = append(b, ':')
+	switch vv := v.(type) {
+	case int:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int8:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int16:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int32:
+		return strconv.AppendInt(b, int64(vv), 10)
+	case int64:
+		return strconv.AppendInt(b, vv, 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint8:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint16:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(vv), 10)
+	case uint64:
+		return strconv.AppendUint(b, vv, 10)
+	case float32:
+		if data, err := json.Marshal(vv); err == nil {
+			return append(b, data...)
+		}
+	case float64:
+		if data, err := json.Marshal(vv); err == nil {
+			return append(b, data...)
+		}
+	case string:
+		return appendString(b, vv)
+	}
+	return appendString(b, fmt.Sprint(v))
+}
+
+func appendString(b []byte, s string) []byte {
+	data, _ := json.Marshal(s)
+	return append(b, data...)
+}
+
+// level returns the syslog severity of an entry.
+func level(e logfmtr.Entry) int {
+	switch {
+	case e.IsError:
+		return levelErr
+	case e.Level > 0:
+		return levelDebug
+	default:
+		return levelInfo
+	}
+}
+
+var (
+	hostnameOnce sync.Once
+	hostnameVal  string
+)
+
+func hostname() string {
+	hostnameOnce.Do(func() {
+		hostnameVal, _ = os.Hostname()
+		if hostnameVal == "" {
+			hostnameVal = "unknown"
+		}
+	})
+	return hostnameVal
+}
+
+// ErrMessageTooLarge is returned when a message needs more chunks than GELF permits.
+var ErrMessageTooLarge = errors.New("gelf: message too large")
+
+const (
+	// maxChunks is the maximum number of chunks a message may be split into.
+	maxChunks = 128
+
+	// chunkHeaderSize is the size of the header of each chunk: two magic bytes, an eight byte message
+	// id, the sequence number and the sequence count.
+	chunkHeaderSize = 12
+)
+
+// Config holds the settings of a Writer.
+type Config struct {
+	// ChunkSize is the maximum size of each UDP datagram. Messages larger than this are split into chunks.
+	ChunkSize int
+
+	// Compress compresses each message with gzip before it is sent.
+	Compress bool
+}
+
+// DefaultConfig returns a Config with a chunk size suitable for networks with a standard MTU and
+// compression enabled.
+func DefaultConfig() Config {
+	return Config{
+		ChunkSize: 1420,
+		Compress:  true,
+	}
+}
+
+// Writer sends messages to a Graylog GELF UDP input. Each call to Write sends one message, which should
+// be a GELF JSON object such as one written by an Encoder. It is safe for concurrent use.
+type Writer struct {
+	cfg    Config
+	idBase uint64
+
+	mu     sync.Mutex // guards seq, conn and closed
+	seq    uint64     // number of chunked messages sent, used to derive message ids
+	conn   net.Conn
+	closed bool
+}
+
+// Dial returns a Writer that sends messages to the Graylog server at addr over UDP.
+func Dial(addr string, cfg Config) (*Writer, error) {
+	if cfg.ChunkSize <= chunkHeaderSize {
+		return nil, fmt.Errorf("gelf: chunk size must be greater than %d", chunkHeaderSize)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	var seed [8]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Writer{
+		cfg:    cfg,
+		idBase: binary.BigEndian.Uint64(seed[:]),
+		conn:   conn,
+	}, nil
+}
+
+// Write sends p as a single message, splitting it into chunks if it is larger than the chunk size.
+// Any trailing newline is removed.
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+	if w.cfg.Compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(msg); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		msg = buf.Bytes()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+	if len(msg) <= w.cfg.ChunkSize {
+		if _, err := w.conn.Write(msg); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	size := w.cfg.ChunkSize - chunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > maxChunks {
+		return 0, ErrMessageTooLarge
+	}
+	w.seq++
+	id := w.idBase + w.seq
+	chunk := make([]byte, 0, w.cfg.ChunkSize)
+	for i := 0; i < count; i++ {
+		chunk = append(chunk[:0], 0x1e, 0x0f)
+		chunk = appendUint64(chunk, id)
+		chunk = append(chunk, byte(i), byte(count))
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		chunk = append(chunk, msg[i*size:end]...)
+		if _, err := w.conn.Write(chunk); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// Close closes the connection. Subsequent writes return logfmtr.ErrWriterClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.conn.Close()
+}
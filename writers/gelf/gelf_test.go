@@ -0,0 +1,123 @@
+package gelf_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers/gelf"
+)
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = &buf
+	opts.Encoder = &gelf.Encoder{Host: "europa"}
+	opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 678000000, time.UTC) }
+	logger := logfmtr.NewWithOptions(opts).WithName("moons").WithValues("id", "io")
+
+	logger.Info("hello", "val", 1, "ratio", 0.5, "user name", "you")
+	logger.Error(errors.New("uh oh"), "goodbye")
+
+	want := `{"version":"1.1","host":"europa","short_message":"hello","timestamp":1704164645.678,"level":6,"_logger":"moons","_id_":"io","_val":1,"_ratio":0.5,"_user_name":"you"}` + "\n" +
+		`{"version":"1.1","host":"europa","short_message":"goodbye","timestamp":1704164645.678,"level":3,"_logger":"moons","_error":"uh oh","_id_":"io"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc
+}
+
+func read(t *testing.T, pc net.PacketConn) []byte {
+	t.Helper()
+	buf := make([]byte, 65536)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	return buf[:n]
+}
+
+func TestWriterChunks(t *testing.T) {
+	pc := listen(t)
+	w, err := gelf.Dial(pc.LocalAddr().String(), gelf.Config{ChunkSize: 32})
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+	defer w.Close()
+
+	msg := `{"version":"1.1","host":"europa","short_message":"hello"}`
+	if _, err := w.Write([]byte(msg + "\n")); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	var got []byte
+	var id []byte
+	count := (len(msg) + 19) / 20
+	for i := 0; i < count; i++ {
+		chunk := read(t, pc)
+		if chunk[0] != 0x1e || chunk[1] != 0x0f {
+			t.Fatalf("chunk %d: got magic bytes %x, wanted 1e0f", i, chunk[:2])
+		}
+		if id == nil {
+			id = chunk[2:10]
+		} else if !bytes.Equal(id, chunk[2:10]) {
+			t.Errorf("chunk %d: got message id %x, wanted %x", i, chunk[2:10], id)
+		}
+		if int(chunk[10]) != i || int(chunk[11]) != count {
+			t.Errorf("chunk %d: got sequence %d of %d, wanted %d of %d", i, chunk[10], chunk[11], i, count)
+		}
+		got = append(got, chunk[12:]...)
+	}
+	if string(got) != msg {
+		t.Errorf("got %q, wanted %q", got, msg)
+	}
+
+	if _, err := w.Write([]byte(strings.Repeat("x", 128*20+1))); err != gelf.ErrMessageTooLarge {
+		t.Errorf("got error %v, wanted %v", err, gelf.ErrMessageTooLarge)
+	}
+}
+
+func TestWriterCompress(t *testing.T) {
+	pc := listen(t)
+	w, err := gelf.Dial(pc.LocalAddr().String(), gelf.DefaultConfig())
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+
+	msg := `{"version":"1.1","host":"europa","short_message":"hello"}`
+	if _, err := w.Write([]byte(msg)); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(read(t, pc)))
+	if err != nil {
+		t.Fatalf("unexpected error reading gzip header: %v", err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("unexpected error decompressing: %v", err)
+	}
+	if string(got) != msg {
+		t.Errorf("got %q, wanted %q", got, msg)
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte(msg)); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build linux
+// +build linux
+
+package journald
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendFile writes a message that is too large for a datagram to an unlinked file in /dev/shm and
+// passes its descriptor to the journal over conn.
+func sendFile(conn net.Conn, p []byte) error {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("journald: message too large")
+	}
+	f, err := ioutil.TempFile("/dev/shm", "logfmtr-journal.")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(p); err != nil {
+		return err
+	}
+	rc, err := uc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	var sendErr error
+	// WriteMsgUnix cannot be used on a connected datagram socket, so send on the descriptor directly
+	err = rc.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return sendErr != syscall.EAGAIN
+	})
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package journald_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr/writers/journald"
+)
+
+func TestWriterLargeMessage(t *testing.T) {
+	if _, err := os.Stat("/dev/shm"); err != nil {
+		t.Skipf("/dev/shm is not available: %v", err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	defer conn.Close()
+
+	w, err := journald.Dial(path)
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+	defer w.Close()
+
+	msg := []byte("MESSAGE=" + string(bytes.Repeat([]byte("x"), 4<<20)) + "\n")
+	if _, err := w.Write(msg); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, oobn, _, _, err := conn.ReadMsgUnix(make([]byte, 16), oob)
+	if err != nil {
+		t.Fatalf("unexpected error reading: %v", err)
+	}
+	cmsgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(cmsgs) != 1 {
+		t.Fatalf("got control messages %v (error %v), wanted one", cmsgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&cmsgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("got descriptors %v (error %v), wanted one", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal message")
+	defer f.Close()
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatalf("unexpected error seeking: %v", err)
+	}
+	got, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatalf("unexpected error reading message file: %v", err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("got a message of %d bytes, wanted %d", len(got), len(msg))
+	}
+}
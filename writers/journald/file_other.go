@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package journald
+
+import (
+	"errors"
+	"net"
+)
+
+// sendFile reports that a message too large for a datagram cannot be sent, since passing it in a file
+// is only supported on Linux.
+func sendFile(conn net.Conn, p []byte) error {
+	return errors.New("journald: message too large")
+}
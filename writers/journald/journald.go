@@ -0,0 +1,226 @@
+// Package journald sends log entries to the systemd journal using its native protocol, so that each
+// key/value pair of an entry is stored as a separate journal field.
+//
+// WithJournal configures a logger to use the journal when it is available and to fall back to writing
+// to standard error otherwise:
+//
+//	logger := logfmtr.NewWith(journald.WithJournal("myapp"))
+package journald
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/iand/logfmtr"
+)
+
+// DefaultSocket is the path of the journal's native protocol socket.
+const DefaultSocket = "/run/systemd/journal/socket"
+
+// maxFieldName is the maximum length of a journal field name.
+const maxFieldName = 64
+
+// Syslog priorities used for the PRIORITY field.
+const (
+	priorityErr   = 3
+	priorityInfo  = 6
+	priorityDebug = 7
+)
+
+// Enabled reports whether the journal socket is available.
+func Enabled() bool {
+	w, err := Dial("")
+	if err != nil {
+		return false
+	}
+	w.Close()
+	return true
+}
+
+// WithJournal returns an option that sends entries to the journal using an Encoder with the given
+// identifier when the journal is available. Otherwise the writer is set to standard error and entries are
+// written using the logger's existing encoding. The journal is dialed once, when WithJournal is called,
+// and the connection is shared by every logger the option is applied to.
+func WithJournal(identifier string) logfmtr.Option {
+	w, err := Dial("")
+	return func(o *logfmtr.Options) {
+		if err != nil {
+			o.Writer = os.Stderr
+			o.ErrorWriter = nil
+			return
+		}
+		o.Writer = w
+		o.ErrorWriter = nil
+		o.Humanize = false
+		o.Colorize = false
+		o.Encoder = &Encoder{Identifier: identifier}
+	}
+}
+
+// Writer sends messages to the journal. Each call to Write sends one message, which must be encoded
+// using the journal native protocol such as by an Encoder. On Linux, messages too large to send as a
+// single datagram are written to an unlinked temporary file in /dev/shm whose descriptor is passed to
+// the journal instead, as journal clients do. On other systems such messages are lost and Write
+// returns an error. It is safe for concurrent use.
+type Writer struct {
+	mu     sync.Mutex // guards conn and closed
+	conn   net.Conn
+	closed bool
+}
+
+// Dial connects to the journal socket at path, or at DefaultSocket if path is empty.
+func Dial(path string) (*Writer, error) {
+	if path == "" {
+		path = DefaultSocket
+	}
+	conn, err := net.Dial("unixgram", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{conn: conn}, nil
+}
+
+// Write sends p as a single message.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+	n, err := w.conn.Write(p)
+	if err != nil && (errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)) {
+		if err := sendFile(w.conn, p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return n, err
+}
+
+// Close closes the connection to the journal. Subsequent writes return logfmtr.ErrWriterClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.conn.Close()
+}
+
+var _ logfmtr.Encoder = (*Encoder)(nil)
+
+// Encoder writes entries using the journal native protocol. The message is written to the MESSAGE
+// field and the PRIORITY field is set to err for entries written by Error, info for entries at V level
+// zero and debug for entries at higher V levels. The logger name is written to the LOGGER field, the
+// caller to the CODE_FILE and CODE_LINE fields and the error to the ERROR field. Each key/value pair is
+// written to a field named by converting the key to upper case and replacing any characters other than
+// letters, digits and underscores with underscores.
+type Encoder struct {
+	// Identifier is written to the SYSLOG_IDENTIFIER field. It is omitted when empty.
+	Identifier string
+}
+
+// EncodeEntry writes the entry as a single journal message.
+func (enc *Encoder) EncodeEntry(w io.Writer, e logfmtr.Entry) error {
+	b := make([]byte, 0, 256)
+	b = appendField(b, "MESSAGE", e.Message)
+	b = appendField(b, "PRIORITY", strconv.Itoa(priority(e)))
+	if enc.Identifier != "" {
+		b = appendField(b, "SYSLOG_IDENTIFIER", enc.Identifier)
+	}
+	if e.Name != "" {
+		b = appendField(b, "LOGGER", e.Name)
+	}
+	if e.Caller != "" {
+		file, line := e.Caller, ""
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file, line = file[:i], file[i+1:]
+		}
+		b = appendField(b, "CODE_FILE", file)
+		if line != "" {
+			b = appendField(b, "CODE_LINE", line)
+		}
+	}
+	if e.IsError {
+		b = appendField(b, "ERROR", fmt.Sprint(e.Error))
+	}
+	if e.Stacktrace != "" {
+		b = appendField(b, "STACKTRACE", e.Stacktrace)
+	}
+	b = appendKVs(b, e.Context)
+	b = appendKVs(b, e.Values)
+	_, err := w.Write(b)
+	return err
+}
+
+func appendKVs(b []byte, kvs []interface{}) []byte {
+	for i := 0; i < len(kvs); i += 2 {
+		var v interface{} = ""
+		if i+1 < len(kvs) {
+			v = kvs[i+1]
+		}
+		b = appendField(b, fieldName(fmt.Sprint(kvs[i])), fmt.Sprint(v))
+	}
+	return b
+}
+
+// appendField appends a field in the journal native protocol. Values containing newlines are written
+// with an explicit length.
+func appendField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if strings.IndexByte(value, '\n') < 0 {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b = append(b, size[:]...)
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// fieldName converts a key to a valid journal field name. Field names consist of upper case letters,
+// digits and underscores and must begin with a letter.
+func fieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if name == "" {
+		name = "FIELD"
+	}
+	if len(name) > maxFieldName {
+		name = name[:maxFieldName]
+	}
+	return name
+}
+
+// priority returns the syslog priority of an entry.
+func priority(e logfmtr.Entry) int {
+	switch {
+	case e.IsError:
+		return priorityErr
+	case e.Level > 0:
+		return priorityDebug
+	default:
+		return priorityInfo
+	}
+}
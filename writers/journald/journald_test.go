@@ -0,0 +1,63 @@
+package journald_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers/journald"
+)
+
+func TestEncoder(t *testing.T) {
+	dir, err := os.MkdirTemp("", "journald")
+	if err != nil {
+		t.Fatalf("unexpected error creating directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "socket")
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	defer pc.Close()
+
+	w, err := journald.Dial(path)
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+	defer w.Close()
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.Encoder = &journald.Encoder{Identifier: "app"}
+	logger := logfmtr.NewWithOptions(opts).WithName("europa").WithValues("user-id", 7)
+
+	logger.Info("hello", "val", 1)
+	logger.Error(errors.New("uh oh"), "goodbye", "detail", "two\nlines")
+
+	want := []string{
+		"MESSAGE=hello\nPRIORITY=6\nSYSLOG_IDENTIFIER=app\nLOGGER=europa\nUSER_ID=7\nVAL=1\n",
+		"MESSAGE=goodbye\nPRIORITY=3\nSYSLOG_IDENTIFIER=app\nLOGGER=europa\nERROR=uh oh\nUSER_ID=7\nDETAIL\n\x09\x00\x00\x00\x00\x00\x00\x00two\nlines\n",
+	}
+	buf := make([]byte, 1024)
+	for _, wanted := range want {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		}
+		if got := string(buf[:n]); got != wanted {
+			t.Errorf("got %q, wanted %q", got, wanted)
+		}
+	}
+
+	w.Close()
+	if _, err := w.Write([]byte("MESSAGE=after close\n")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/iand/logfmtr/writers/kafkaw
+
+go 1.19
+
+replace github.com/iand/logfmtr => ../../
+
+require (
+	github.com/iand/logfmtr v0.3.0
+	github.com/segmentio/kafka-go v0.4.47
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+)
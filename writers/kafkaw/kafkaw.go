@@ -0,0 +1,317 @@
+// Package kafkaw provides a writer that publishes log entries to a Kafka topic.
+//
+// Entries are queued and published in batches from a background goroutine so that logging does not
+// wait for Kafka. Each entry is published as a message whose value is the line written by the logger
+// and whose key is the logger name, so that entries from the same logger are kept in order on a single
+// partition:
+//
+//	w := kafkaw.New([]string{"kafka:9092"}, "audit", kafkaw.DefaultConfig())
+//	defer w.Close()
+//	opts := logfmtr.DefaultOptions()
+//	opts.Writer = w
+//	logger := logfmtr.NewWithOptions(opts)
+package kafkaw
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtparse"
+	"github.com/segmentio/kafka-go"
+)
+
+// Producer publishes messages to Kafka. It is implemented by *kafka.Writer.
+type Producer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Config holds the settings of a Writer.
+type Config struct {
+	// BatchSize is the largest number of entries published together.
+	BatchSize int
+
+	// BatchTimeout is the longest time an entry is held before its batch is published.
+	BatchTimeout time.Duration
+
+	// QueueSize is the number of entries that may be waiting to be published before DropPolicy applies.
+	// With logfmtr.DropOldest a QueueSize below one is raised to one, since there must be a queued
+	// entry to drop.
+	QueueSize int
+
+	// DropPolicy determines what happens to an entry when the queue is full. To guarantee delivery of
+	// entries such as audit logs use logfmtr.Block, which makes logging wait until there is room.
+	DropPolicy logfmtr.DropPolicy
+
+	// WriteTimeout limits the time taken to publish each batch. When zero there is no limit.
+	WriteTimeout time.Duration
+}
+
+// DefaultConfig returns a Config that publishes up to 100 entries at a time, holds entries for at most
+// a second, queues up to 10000 entries and drops new entries when the queue is full.
+func DefaultConfig() Config {
+	return Config{
+		BatchSize:    100,
+		BatchTimeout: time.Second,
+		QueueSize:    10000,
+		DropPolicy:   logfmtr.DropNewest,
+		WriteTimeout: 10 * time.Second,
+	}
+}
+
+// queueItem is a message to be published or, if flushed is not nil, a marker that is signalled when
+// all preceding messages have been published.
+type queueItem struct {
+	msg     kafka.Message
+	flushed chan struct{}
+}
+
+// Writer publishes the lines written to it to Kafka. Each call to Write should be a single entry written
+// in logfmt style; its logger key is used as the message key. Errors encountered while publishing are
+// returned by the next call to Flush or Close. A Writer is safe for concurrent use.
+type Writer struct {
+	cfg      Config
+	producer Producer
+	queue    chan queueItem
+	done     chan struct{}
+	dropped  uint64 // accessed atomically
+
+	mu     sync.RWMutex // guards closed and prevents sends on a closed queue
+	closed bool
+
+	markersMu sync.Mutex
+	markers   []chan struct{} // flush markers removed from the queue to make room, signalled by run
+
+	errMu sync.Mutex // guards err
+	err   error
+}
+
+// New returns a Writer that publishes to topic using a kafka.Writer connected to brokers. Messages are
+// assigned to partitions by hashing their key.
+func New(brokers []string, topic string, cfg Config) *Writer {
+	return NewWithProducer(&kafka.Writer{
+		Addr:     kafka.TCP(brokers...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+		// batches are assembled by the Writer so the producer should publish them without waiting
+		BatchSize:    cfg.BatchSize,
+		BatchTimeout: time.Millisecond,
+	}, cfg)
+}
+
+// NewWithProducer returns a Writer that publishes using p, which may be a kafka.Writer with custom
+// settings such as TLS or SASL authentication. Close must be called to publish any queued entries,
+// stop the background goroutine and close p.
+func NewWithProducer(p Producer, cfg Config) *Writer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = time.Millisecond
+	}
+	if cfg.QueueSize < 0 {
+		cfg.QueueSize = 0
+	}
+	if cfg.DropPolicy == logfmtr.DropOldest && cfg.QueueSize < 1 {
+		cfg.QueueSize = 1
+	}
+	w := &Writer{
+		cfg:      cfg,
+		producer: p,
+		queue:    make(chan queueItem, cfg.QueueSize),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Dropped returns the number of entries that have been dropped because the queue was full.
+func (w *Writer) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Write queues p to be published. Any trailing newline is removed. It reports success even if the
+// entry is dropped.
+func (w *Writer) Write(p []byte) (int, error) {
+	value := append([]byte(nil), bytes.TrimRight(p, "\n")...)
+	item := queueItem{msg: kafka.Message{
+		Key:   loggerName(value),
+		Value: value,
+		Time:  time.Now(),
+	}}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+
+	switch w.cfg.DropPolicy {
+	case logfmtr.Block:
+		w.queue <- item
+	case logfmtr.DropOldest:
+		for {
+			select {
+			case w.queue <- item:
+				return len(p), nil
+			default:
+			}
+			select {
+			case old := <-w.queue:
+				if old.flushed != nil {
+					// never drop a flush marker, but let the background goroutine signal it once
+					// the entries queued before it have been published
+					w.markersMu.Lock()
+					w.markers = append(w.markers, old.flushed)
+					w.markersMu.Unlock()
+				} else {
+					atomic.AddUint64(&w.dropped, 1)
+				}
+			default:
+			}
+		}
+	default:
+		select {
+		case w.queue <- item:
+		default:
+			atomic.AddUint64(&w.dropped, 1)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush waits until all entries queued before the call have been published. It returns the first error
+// encountered since the previous call to Flush.
+func (w *Writer) Flush() error {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	w.queue <- queueItem{flushed: flushed}
+	w.mu.RUnlock()
+
+	<-flushed
+	return w.takeErr()
+}
+
+// Close stops accepting writes, waits for all queued entries to be published and closes the producer.
+// Subsequent writes return logfmtr.ErrWriterClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	err := w.takeErr()
+	if cerr := w.producer.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	var batch []kafka.Message
+	var flushed []chan struct{}
+	publish := func() {
+		if len(batch) > 0 {
+			w.publish(batch)
+			batch = nil
+		}
+		for _, f := range flushed {
+			close(f)
+		}
+		flushed = nil
+	}
+
+	timer := time.NewTimer(w.cfg.BatchTimeout)
+	timer.Stop()
+	for {
+		select {
+		case item, ok := <-w.queue:
+			if markers := w.takeMarkers(); len(markers) > 0 {
+				flushed = append(flushed, markers...)
+				publish()
+			}
+			if !ok {
+				publish()
+				return
+			}
+			if item.flushed != nil {
+				flushed = append(flushed, item.flushed)
+				publish()
+				continue
+			}
+			if len(batch) == 0 {
+				timer.Reset(w.cfg.BatchTimeout)
+			}
+			batch = append(batch, item.msg)
+			if len(batch) >= w.cfg.BatchSize {
+				publish()
+			}
+		case <-timer.C:
+			publish()
+		}
+	}
+}
+
+// takeMarkers returns the flush markers removed from the queue by Write. Every entry queued before them
+// has already been received by run or dropped.
+func (w *Writer) takeMarkers() []chan struct{} {
+	w.markersMu.Lock()
+	defer w.markersMu.Unlock()
+	markers := w.markers
+	w.markers = nil
+	return markers
+}
+
+// publish sends a batch of messages to the producer, recording any error.
+func (w *Writer) publish(batch []kafka.Message) {
+	ctx := context.Background()
+	if w.cfg.WriteTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.cfg.WriteTimeout)
+		defer cancel()
+	}
+	if err := w.producer.WriteMessages(ctx, batch...); err != nil {
+		w.errMu.Lock()
+		if w.err == nil {
+			w.err = err
+		}
+		w.errMu.Unlock()
+	}
+}
+
+// takeErr returns and clears the first recorded error.
+func (w *Writer) takeErr() error {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// loggerName returns the value of the logger key of a line, or nil if it has none.
+func loggerName(line []byte) []byte {
+	pairs, err := logfmtparse.DecodePairs(string(line))
+	if err != nil {
+		return nil
+	}
+	for _, p := range pairs {
+		if p.Key == "logger" {
+			return []byte(p.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package kafkaw_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers/kafkaw"
+	"github.com/segmentio/kafka-go"
+)
+
+type fakeProducer struct {
+	mu      sync.Mutex
+	batches [][]kafka.Message
+	err     error
+	closed  bool
+}
+
+func (p *fakeProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches = append(p.batches, msgs)
+	return p.err
+}
+
+func (p *fakeProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	p := &fakeProducer{}
+	cfg := kafkaw.DefaultConfig()
+	cfg.BatchSize = 2
+	cfg.BatchTimeout = time.Hour
+	w := kafkaw.NewWithProducer(p, cfg)
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.WithName("europa").Info("hello")
+	logger.Info("unnamed")
+	logger.WithName("io").Info("volcano")
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+
+	p.mu.Lock()
+	var sizes []int
+	var keys, values []string
+	for _, b := range p.batches {
+		sizes = append(sizes, len(b))
+		for _, m := range b {
+			keys = append(keys, string(m.Key))
+			values = append(values, string(m.Value))
+		}
+	}
+	p.mu.Unlock()
+
+	if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 1 {
+		t.Errorf("got batch sizes %v, wanted [2 1]", sizes)
+	}
+	wantKeys := []string{"europa", "", "io"}
+	wantValues := []string{"level=0 logger=europa msg=hello", "level=0 msg=unnamed", "level=0 logger=io msg=volcano"}
+	for i := range wantKeys {
+		if i >= len(keys) {
+			t.Fatalf("got %d messages, wanted %d", len(keys), len(wantKeys))
+		}
+		if keys[i] != wantKeys[i] || values[i] != wantValues[i] {
+			t.Errorf("message %d: got key %q value %q, wanted key %q value %q", i, keys[i], values[i], wantKeys[i], wantValues[i])
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("unexpected error closing: %v", err)
+	}
+	if !p.closed {
+		t.Errorf("producer was not closed")
+	}
+	if _, err := w.Write([]byte("level=0 msg=closed\n")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	errUnavailable := errors.New("broker unavailable")
+	p := &fakeProducer{err: errUnavailable}
+	w := kafkaw.NewWithProducer(p, kafkaw.DefaultConfig())
+	defer w.Close()
+
+	w.Write([]byte("level=0 msg=hello\n"))
+	if err := w.Flush(); err != errUnavailable {
+		t.Errorf("got error %v, wanted %v", err, errUnavailable)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("got error %v from second flush, wanted nil", err)
+	}
+}
+
+// blockingProducer blocks publishing until released.
+type blockingProducer struct {
+	fakeProducer
+	release chan struct{}
+}
+
+func (p *blockingProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	<-p.release
+	return p.fakeProducer.WriteMessages(ctx, msgs...)
+}
+
+func TestWriterDropNewest(t *testing.T) {
+	p := &blockingProducer{release: make(chan struct{})}
+	cfg := kafkaw.DefaultConfig()
+	cfg.BatchSize = 1
+	cfg.QueueSize = 1
+	w := kafkaw.NewWithProducer(p, cfg)
+
+	// the first entry is taken by the publishing goroutine, the second fills the queue and the rest are dropped
+	w.Write([]byte("level=0 msg=one\n"))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w.Write([]byte("level=0 msg=more\n"))
+		if w.Dropped() > 0 || time.Now().After(deadline) {
+			break
+		}
+	}
+	if w.Dropped() == 0 {
+		t.Errorf("got no dropped entries")
+	}
+	close(p.release)
+	w.Close()
+}
+
+func TestWriterDropOldestZeroQueue(t *testing.T) {
+	p := &blockingProducer{release: make(chan struct{})}
+	cfg := kafkaw.DefaultConfig()
+	cfg.BatchSize = 1
+	cfg.QueueSize = 0
+	cfg.DropPolicy = logfmtr.DropOldest
+	w := kafkaw.NewWithProducer(p, cfg)
+
+	// the publishing goroutine is blocked, so writes must drop queued entries rather than wait for it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			w.Write([]byte("level=0 msg=more\n"))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out writing with a zero queue size")
+	}
+	if w.Dropped() == 0 {
+		t.Errorf("got no dropped entries")
+	}
+	close(p.release)
+	w.Close()
+}
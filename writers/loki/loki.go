@@ -0,0 +1,402 @@
+// Package loki provides a writer that batches log entries and pushes them to Grafana Loki.
+//
+// Each entry is stored in Loki as the line written by the logger, in a stream whose labels are derived
+// from the entry's logger name and level and from a set of static labels. Entries may be written in
+// logfmt or as JSON objects, such as those written by logfmtr.JSONEncoder:
+//
+//	w, err := loki.New(loki.Config{
+//		URL:    "http://loki:3100/loki/api/v1/push",
+//		Labels: map[string]string{"app": "myapp"},
+//	})
+//	if err != nil {
+//		// handle error
+//	}
+//	defer w.Close()
+//	opts := logfmtr.DefaultOptions()
+//	opts.Writer = w
+//	logger := logfmtr.NewWithOptions(opts)
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/logfmtparse"
+)
+
+// Defaults used for zero fields of Config.
+const (
+	DefaultBatchSize = 1000
+	DefaultBatchWait = time.Second
+	DefaultTimeout   = 10 * time.Second
+)
+
+// Config holds the settings of a Writer.
+type Config struct {
+	// URL is the address of Loki's push endpoint, for example http://loki:3100/loki/api/v1/push.
+	URL string
+
+	// Labels are added to the labels of every stream.
+	Labels map[string]string
+
+	// LevelLabel and LoggerLabel are the names of the labels set from the level and logger name of
+	// each entry. They default to "level" and "logger".
+	LevelLabel  string
+	LoggerLabel string
+
+	// FieldNames holds the keys of the level and logger name in each entry. It should match the
+	// FieldNames option of the loggers using the Writer. Empty keys default to "level" and "logger".
+	// The other fields are not used.
+	FieldNames logfmtr.FieldNames
+
+	// TenantID, when not empty, is sent in the X-Scope-OrgID header for multi-tenant Loki installations.
+	TenantID string
+
+	// BatchSize is the number of entries that causes a batch to be pushed. When zero DefaultBatchSize is used.
+	BatchSize int
+
+	// BatchWait is the longest time an entry is held before its batch is pushed. When zero
+	// DefaultBatchWait is used.
+	BatchWait time.Duration
+
+	// MaxPending is the largest number of entries held waiting to be pushed, including entries whose
+	// push failed and will be retried. Entries written while MaxPending entries are held are dropped.
+	// When zero ten times BatchSize is used.
+	MaxPending int
+
+	// Client is the HTTP client used to push batches. When nil a client that times out after
+	// DefaultTimeout is used.
+	Client *http.Client
+}
+
+// entry is a line waiting to be pushed.
+type entry struct {
+	key    string // identifies the stream, derived from the labels
+	labels map[string]string
+	ts     time.Time
+	line   string
+}
+
+// Writer batches the lines written to it and pushes them to Loki. Each call to Write should be a single
+// entry written in logfmt style or as a JSON object. The level and logger name of the entry, found using
+// the keys in FieldNames, are used to derive its stream labels; lines that cannot be decoded are pushed
+// with only the static labels. Batches are pushed from a background goroutine when they are full or when
+// BatchWait has elapsed, and by Flush and Close. Batches that fail because of a network error, a server
+// error or rate limiting are kept and pushed again with the next batch; other failed batches are dropped.
+// Errors encountered while pushing in the background are returned by the next call to Flush or Close. A
+// Writer is safe for concurrent use.
+type Writer struct {
+	cfg    Config
+	client *http.Client
+	full   chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	pushMu sync.Mutex // serializes pushes so entries are sent in order
+
+	mu      sync.Mutex // guards batch, dropped, err and closed
+	batch   []entry
+	dropped uint64
+	err     error
+	closed  bool
+}
+
+// New returns a Writer that pushes to the Loki server described by cfg. Close must be called to push
+// any remaining entries and stop the background goroutine.
+func New(cfg Config) (*Writer, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("loki: invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("loki: invalid URL: unsupported scheme %q", u.Scheme)
+	}
+	for name := range cfg.Labels {
+		if !validLabelName(name) {
+			return nil, fmt.Errorf("loki: invalid label name %q", name)
+		}
+	}
+	if cfg.LevelLabel == "" {
+		cfg.LevelLabel = "level"
+	}
+	if cfg.LoggerLabel == "" {
+		cfg.LoggerLabel = "logger"
+	}
+	if !validLabelName(cfg.LevelLabel) || !validLabelName(cfg.LoggerLabel) {
+		return nil, errors.New("loki: invalid level or logger label name")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = DefaultBatchWait
+	}
+	if cfg.MaxPending <= 0 {
+		cfg.MaxPending = 10 * cfg.BatchSize
+	}
+	if cfg.FieldNames.Level == "" {
+		cfg.FieldNames.Level = "level"
+	}
+	if cfg.FieldNames.Logger == "" {
+		cfg.FieldNames.Logger = "logger"
+	}
+	w := &Writer{
+		cfg:    cfg,
+		client: cfg.Client,
+		full:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if w.client == nil {
+		w.client = &http.Client{Timeout: DefaultTimeout}
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Write adds p to the current batch. Any trailing newline is removed. If MaxPending entries are waiting
+// to be pushed p is dropped.
+func (w *Writer) Write(p []byte) (int, error) {
+	line := string(bytes.TrimRight(p, "\n"))
+	labels := w.labels(line)
+	e := entry{
+		key:    labelsKey(labels),
+		labels: labels,
+		ts:     time.Now(),
+		line:   line,
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+	if len(w.batch) >= w.cfg.MaxPending {
+		w.dropped++
+		return len(p), nil
+	}
+	w.batch = append(w.batch, e)
+	if len(w.batch) >= w.cfg.BatchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush pushes any entries waiting to be sent. It returns the first error encountered since the previous
+// call to Flush, including errors from pushes made in the background.
+func (w *Writer) Flush() error {
+	w.push()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// Dropped returns the number of entries dropped because MaxPending entries were waiting to be pushed or
+// because their push failed and could not be retried.
+func (w *Writer) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Close pushes any entries waiting to be sent and stops the background goroutine. Entries that cannot be
+// pushed are dropped. Subsequent writes return logfmtr.ErrWriterClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.done)
+	w.wg.Wait()
+	err := w.Flush()
+	w.mu.Lock()
+	w.dropped += uint64(len(w.batch))
+	w.batch = nil
+	w.mu.Unlock()
+	return err
+}
+
+func (w *Writer) run() {
+	defer w.wg.Done()
+	t := time.NewTicker(w.cfg.BatchWait)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-t.C:
+		case <-w.full:
+		}
+		w.push()
+	}
+}
+
+// push sends the current batch, recording any error. A batch that can be retried is put back in front of
+// the entries written meanwhile, dropping the newest entries if more than MaxPending would be held.
+func (w *Writer) push() {
+	w.pushMu.Lock()
+	defer w.pushMu.Unlock()
+
+	w.mu.Lock()
+	batch := w.batch
+	w.batch = nil
+	w.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	retry, err := w.send(batch)
+	if err == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+	if !retry {
+		w.dropped += uint64(len(batch))
+		return
+	}
+	w.batch = append(batch, w.batch...)
+	if n := len(w.batch) - w.cfg.MaxPending; n > 0 {
+		w.batch = w.batch[:w.cfg.MaxPending]
+		w.dropped += uint64(n)
+	}
+}
+
+// pushRequest is the body of a request to Loki's push endpoint.
+type pushRequest struct {
+	Streams []stream `json:"streams"`
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// send pushes a batch of entries to Loki. If the push fails it reports whether the batch may succeed if
+// it is pushed again.
+func (w *Writer) send(batch []entry) (bool, error) {
+	var req pushRequest
+	streams := map[string]int{}
+	for _, e := range batch {
+		i, ok := streams[e.key]
+		if !ok {
+			i = len(req.Streams)
+			streams[e.key] = i
+			req.Streams = append(req.Streams, stream{Stream: e.labels})
+		}
+		req.Streams[i].Values = append(req.Streams[i].Values, [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return false, err
+	}
+
+	hreq, err := http.NewRequest(http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	if w.cfg.TenantID != "" {
+		hreq.Header.Set("X-Scope-OrgID", w.cfg.TenantID)
+	}
+	resp, err := w.client.Do(hreq)
+	if err != nil {
+		return true, fmt.Errorf("loki: push failed: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 != 2 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5
+		return retry, fmt.Errorf("loki: push failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return false, nil
+}
+
+// labels returns the stream labels of a line.
+func (w *Writer) labels(line string) map[string]string {
+	labels := make(map[string]string, len(w.cfg.Labels)+2)
+	for k, v := range w.cfg.Labels {
+		labels[k] = v
+	}
+	if strings.HasPrefix(line, "{") {
+		d := json.NewDecoder(strings.NewReader(line))
+		d.UseNumber()
+		var fields map[string]interface{}
+		if err := d.Decode(&fields); err == nil {
+			if v, ok := fields[w.cfg.FieldNames.Level]; ok {
+				labels[w.cfg.LevelLabel] = fmt.Sprint(v)
+			}
+			if v, ok := fields[w.cfg.FieldNames.Logger]; ok {
+				labels[w.cfg.LoggerLabel] = fmt.Sprint(v)
+			}
+		}
+		return labels
+	}
+	if pairs, err := logfmtparse.DecodePairs(line); err == nil {
+		for _, p := range pairs {
+			switch p.Key {
+			case w.cfg.FieldNames.Level:
+				labels[w.cfg.LevelLabel] = p.Value
+			case w.cfg.FieldNames.Logger:
+				labels[w.cfg.LoggerLabel] = p.Value
+			}
+		}
+	}
+	return labels
+}
+
+// labelsKey returns a string of sorted name=value pairs that identifies the stream with the given labels.
+func labelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for i, k := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	return b.String()
+}
+
+// validLabelName reports whether name is a valid Loki label name.
+func validLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
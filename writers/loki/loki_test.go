@@ -0,0 +1,227 @@
+package loki_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers/loki"
+)
+
+type pushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestWriter(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []pushRequest
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req pushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		pushes = append(pushes, req)
+		tenant = r.Header.Get("X-Scope-OrgID")
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w, err := loki.New(loki.Config{
+		URL:       srv.URL,
+		Labels:    map[string]string{"app": "moons"},
+		TenantID:  "tenant1",
+		BatchWait: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.WithName("europa").Info("hello", "val", 1)
+	logger.WithName("io").Info("volcano")
+	logger.WithName("europa").Info("goodbye")
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 1 {
+		t.Fatalf("got %d pushes, wanted 1", len(pushes))
+	}
+	if tenant != "tenant1" {
+		t.Errorf("got tenant %q, wanted tenant1", tenant)
+	}
+	streams := pushes[0].Streams
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, wanted 2", len(streams))
+	}
+
+	wantLabels := []map[string]string{
+		{"app": "moons", "level": "0", "logger": "europa"},
+		{"app": "moons", "level": "0", "logger": "io"},
+	}
+	wantLines := [][]string{
+		{"level=0 logger=europa msg=hello val=1", "level=0 logger=europa msg=goodbye"},
+		{"level=0 logger=io msg=volcano"},
+	}
+	for i, s := range streams {
+		if !reflect.DeepEqual(s.Stream, wantLabels[i]) {
+			t.Errorf("stream %d: got labels %v, wanted %v", i, s.Stream, wantLabels[i])
+		}
+		var lines []string
+		for _, v := range s.Values {
+			lines = append(lines, v[1])
+		}
+		if !reflect.DeepEqual(lines, wantLines[i]) {
+			t.Errorf("stream %d: got lines %q, wanted %q", i, lines, wantLines[i])
+		}
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	var mu sync.Mutex
+	status := http.StatusTooManyRequests
+	var pushed int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if status != http.StatusNoContent {
+			http.Error(w, "push refused", status)
+			return
+		}
+		var req pushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, s := range req.Streams {
+			pushed += len(s.Values)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	setStatus := func(code int) {
+		mu.Lock()
+		status = code
+		mu.Unlock()
+	}
+
+	w, err := loki.New(loki.Config{URL: srv.URL, BatchWait: time.Hour, MaxPending: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a rate limited batch is kept and pushed again
+	w.Write([]byte("level=0 msg=hello\n"))
+	if err := w.Flush(); err == nil {
+		t.Errorf("got no error, wanted push failure")
+	}
+	w.Write([]byte("level=0 msg=again\n"))
+	w.Write([]byte("level=0 msg=dropped\n"))
+	if got := w.Dropped(); got != 1 {
+		t.Errorf("got %d dropped with MaxPending entries waiting, wanted 1", got)
+	}
+	setStatus(http.StatusNoContent)
+	if err := w.Flush(); err != nil {
+		t.Errorf("unexpected error flushing: %v", err)
+	}
+	mu.Lock()
+	if pushed != 2 {
+		t.Errorf("got %d entries pushed, wanted 2", pushed)
+	}
+	mu.Unlock()
+
+	// a rejected batch is dropped
+	setStatus(http.StatusBadRequest)
+	w.Write([]byte("level=0 msg=rejected\n"))
+	if err := w.Flush(); err == nil {
+		t.Errorf("got no error, wanted push failure")
+	}
+	if got := w.Dropped(); got != 2 {
+		t.Errorf("got %d dropped after a rejected push, wanted 2", got)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("unexpected error closing: %v", err)
+	}
+	if _, err := w.Write([]byte("level=0 msg=closed\n")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
+
+func TestWriterFieldNames(t *testing.T) {
+	var mu sync.Mutex
+	var labels []map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req pushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		for _, s := range req.Streams {
+			labels = append(labels, s.Stream)
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	fields := logfmtr.FieldNames{Level: "severity", Logger: "component"}
+	w, err := loki.New(loki.Config{URL: srv.URL, BatchWait: time.Hour, FieldNames: fields})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer w.Close()
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.FieldNames = fields
+	logfmtr.NewWithOptions(opts).WithName("europa").Info("hello")
+	w.Write([]byte(`{"severity":2,"component":"io","msg":"hello"}` + "\n"))
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []map[string]string{
+		{"level": "0", "logger": "europa"},
+		{"level": "2", "logger": "io"},
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("got labels %v, wanted %v", labels, want)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, cfg := range []loki.Config{
+		{URL: "ftp://loki"},
+		{URL: "http://loki", Labels: map[string]string{"bad-name": "x"}},
+	} {
+		if _, err := loki.New(cfg); err == nil {
+			t.Errorf("New(%+v): got no error", cfg)
+		}
+	}
+}
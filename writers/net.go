@@ -0,0 +1,383 @@
+// Package writers provides general purpose writers for sending log entries to other systems.
+//
+// A NetWriter sends entries over a TCP, UDP or Unix socket connection, optionally secured by TLS, and
+// reconnects when the connection fails:
+//
+//	w := writers.NewNetWriter("tcp", "logs.example.com:5170", writers.DefaultNetConfig())
+//	defer w.Close()
+//	opts := logfmtr.DefaultOptions()
+//	opts.Writer = w
+//	logger := logfmtr.NewWithOptions(opts)
+package writers
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+// NetConfig holds the settings of a NetWriter.
+type NetConfig struct {
+	// TLS is the configuration used to secure connections. When nil connections do not use TLS.
+	TLS *tls.Config
+
+	// DialTimeout limits the time taken to establish each connection. When zero there is no limit.
+	DialTimeout time.Duration
+
+	// WriteTimeout limits the time taken by each write to the connection. When zero there is no limit.
+	WriteTimeout time.Duration
+
+	// MinBackoff is the delay before the first attempt to reconnect after a connection fails. When zero
+	// or negative the default of 100 milliseconds is used.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the longest delay between attempts to reconnect. The delay doubles after each
+	// failed attempt until it reaches MaxBackoff. When zero or negative the default of 30 seconds is
+	// used. It is raised to MinBackoff if it is smaller.
+	MaxBackoff time.Duration
+
+	// BufferSize is the maximum number of bytes of entries held in memory while disconnected. When the
+	// buffer is full the oldest entries are dropped to make room. When zero or negative the default of
+	// 1MiB is used.
+	BufferSize int
+
+	// SpoolDir, when not empty, is a directory where entries are queued on disk while disconnected,
+	// instead of in memory, so that more entries can be held and entries survive a restart. Entries
+	// left in the directory by a previous NetWriter are sent once connected. Entries are written with a
+	// checksum and any that are damaged, for example by a crash, are dropped. Entries are sent at least
+	// once: entries sent before the writer was closed may be sent again by the next NetWriter that
+	// uses the directory. If the directory cannot be used entries are held in memory.
+	SpoolDir string
+
+	// SpoolSize is the maximum number of bytes of entries queued in SpoolDir. When the queue is full
+	// the oldest entries are dropped to make room. When zero or negative the default of 256MiB is used.
+	SpoolSize int64
+}
+
+// DefaultNetConfig returns a NetConfig that does not use TLS, reconnects with delays between 100
+// milliseconds and 30 seconds and buffers up to 1MiB of entries while disconnected, or up to 256MiB if
+// SpoolDir is set.
+func DefaultNetConfig() NetConfig {
+	return NetConfig{
+		DialTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		MinBackoff:   defaultMinBackoff,
+		MaxBackoff:   defaultMaxBackoff,
+		BufferSize:   defaultBufferSize,
+		SpoolSize:    defaultSpoolSize,
+	}
+}
+
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+	defaultBufferSize = 1 << 20
+	defaultSpoolSize  = 256 << 20
+)
+
+// resumeBatchSize is the maximum number of bytes of queued entries sent after reconnecting before the
+// writer's lock is taken again, so that writes are not blocked while a large queue is sent.
+const resumeBatchSize = 64 << 10
+
+// withDefaults returns the config with the defaults applied to settings that must be positive.
+func (cfg NetConfig) withDefaults() NetConfig {
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = cfg.MinBackoff
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultBufferSize
+	}
+	if cfg.SpoolSize <= 0 {
+		cfg.SpoolSize = defaultSpoolSize
+	}
+	return cfg
+}
+
+// NetWriter writes entries to a network connection. When the connection fails the NetWriter holds
+// entries in a buffer and reconnects in the background, waiting longer after each failed attempt.
+// Buffered entries are sent once a new connection is established. Each call to Write is expected to be
+// a complete entry and is sent with a single write, so it is also suitable for datagram networks. A
+// NetWriter is safe for concurrent use.
+type NetWriter struct {
+	cfg     NetConfig
+	network string
+	addr    string
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	mu           sync.Mutex // guards the fields below
+	conn         net.Conn
+	inflight     [][]byte // entries being sent after reconnecting, sent before those in spool and buf
+	buf          [][]byte // entries waiting to be sent, oldest first
+	buffered     int      // number of bytes in buf
+	spool        *spool   // entries waiting to be sent before those in buf, nil unless SpoolDir is set
+	dropped      uint64
+	reconnecting bool
+	closed       bool
+}
+
+// NewNetWriter returns a NetWriter that sends entries to addr using the named network, which may be
+// any network accepted by net.Dial. It attempts to connect immediately. If the attempt fails, entries
+// are buffered until a connection can be established.
+func NewNetWriter(network, addr string, cfg NetConfig) *NetWriter {
+	w := &NetWriter{
+		cfg:     cfg.withDefaults(),
+		network: network,
+		addr:    addr,
+		done:    make(chan struct{}),
+	}
+	if w.cfg.SpoolDir != "" {
+		if sp, err := openSpool(w.cfg.SpoolDir, w.cfg.SpoolSize); err == nil {
+			w.spool = sp
+		}
+	}
+	conn, err := w.dial()
+	switch {
+	case err != nil:
+		w.startReconnect(nil)
+	case w.spool != nil && w.spool.len() > 0:
+		// send the entries left by a previous writer in the background, taking the first batch now so
+		// that it is sent before any entries written meanwhile
+		w.inflight = w.nextBatch()
+		w.startReconnect(conn)
+	default:
+		w.conn = conn
+	}
+	return w
+}
+
+// dial establishes a new connection.
+func (w *NetWriter) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: w.cfg.DialTimeout}
+	if w.cfg.TLS != nil {
+		return tls.DialWithDialer(d, w.network, w.addr, w.cfg.TLS)
+	}
+	return d.Dial(w.network, w.addr)
+}
+
+// Write sends p to the connection. If the writer is disconnected, or the write fails, p is buffered
+// and sent when the writer reconnects. Write only returns an error if the writer has been closed.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+	if w.conn != nil {
+		if err := w.send(p); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		w.startReconnect(nil)
+	}
+	w.enqueue(append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// send writes an entry to the connection. w.mu must be held.
+func (w *NetWriter) send(p []byte) error {
+	return w.sendTo(w.conn, p)
+}
+
+// sendTo writes an entry to conn.
+func (w *NetWriter) sendTo(conn net.Conn, p []byte) error {
+	if w.cfg.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(w.cfg.WriteTimeout))
+	}
+	_, err := conn.Write(p)
+	return err
+}
+
+// enqueue adds an entry to the spool, or to the buffer if there is no spool or the entry could not be
+// spooled, dropping the oldest entries if the buffer is full. w.mu must be held.
+func (w *NetWriter) enqueue(p []byte) {
+	if w.spool != nil && len(w.buf) == 0 {
+		dropped, err := w.spool.push(p)
+		w.dropped += uint64(dropped)
+		if err == nil {
+			return
+		}
+	}
+	if len(p) > w.cfg.BufferSize {
+		w.dropped++
+		return
+	}
+	for w.buffered+len(p) > w.cfg.BufferSize {
+		w.buffered -= len(w.buf[0])
+		w.buf[0] = nil
+		w.buf = w.buf[1:]
+		w.dropped++
+	}
+	w.buf = append(w.buf, p)
+	w.buffered += len(p)
+}
+
+// startReconnect starts a goroutine that reconnects to the server unless one is already running. When
+// conn is not nil it is used as the first connection. w.mu must be held or w must not yet be shared.
+func (w *NetWriter) startReconnect(conn net.Conn) {
+	if w.reconnecting {
+		return
+	}
+	w.reconnecting = true
+	w.wg.Add(1)
+	go w.reconnect(conn)
+}
+
+// reconnect attempts to connect to the server, waiting longer after each failure, until it succeeds in
+// connecting and sending any queued entries or the writer is closed.
+func (w *NetWriter) reconnect(conn net.Conn) {
+	defer w.wg.Done()
+	if conn != nil && w.resume(conn) {
+		return
+	}
+	backoff := w.cfg.MinBackoff
+	t := time.NewTimer(backoff)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-t.C:
+		}
+
+		if conn, err := w.dial(); err == nil && w.resume(conn) {
+			return
+		}
+
+		backoff *= 2
+		if backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+		t.Reset(backoff)
+	}
+}
+
+// resume sends queued entries over a new connection and then makes it the writer's connection. Entries
+// are sent in batches without holding the writer's lock, so entries written meanwhile are queued behind
+// them. It reports false, closing the connection, if an entry could not be sent.
+func (w *NetWriter) resume(conn net.Conn) bool {
+	for {
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			conn.Close()
+			return true
+		}
+		if len(w.inflight) == 0 {
+			w.inflight = w.nextBatch()
+		}
+		batch := w.inflight
+		if len(batch) == 0 {
+			w.conn = conn
+			w.reconnecting = false
+			w.mu.Unlock()
+			return true
+		}
+		w.mu.Unlock()
+
+		for i, p := range batch {
+			if err := w.sendTo(conn, p); err != nil {
+				conn.Close()
+				w.mu.Lock()
+				if !w.closed {
+					w.inflight = batch[i:]
+				}
+				w.mu.Unlock()
+				return false
+			}
+		}
+		w.mu.Lock()
+		if !w.closed {
+			w.inflight = nil
+		}
+		w.mu.Unlock()
+	}
+}
+
+// nextBatch removes up to resumeBatchSize bytes of the oldest queued entries from the spool and buffer.
+// w.mu must be held.
+func (w *NetWriter) nextBatch() [][]byte {
+	var batch [][]byte
+	size := 0
+	for w.spool != nil && size < resumeBatchSize {
+		p, dropped, err := w.spool.peek()
+		w.dropped += uint64(dropped)
+		if err != nil || p == nil {
+			break
+		}
+		w.spool.pop(p)
+		batch = append(batch, p)
+		size += len(p)
+	}
+	for len(w.buf) > 0 && size < resumeBatchSize {
+		p := w.buf[0]
+		w.buffered -= len(p)
+		w.buf[0] = nil
+		w.buf = w.buf[1:]
+		batch = append(batch, p)
+		size += len(p)
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return batch
+}
+
+// Connected reports whether the writer currently has a connection to the server.
+func (w *NetWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn != nil
+}
+
+// Dropped returns the number of entries that have been dropped because the buffer or spool was full or
+// spooled entries were damaged.
+func (w *NetWriter) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Close closes the connection and stops any attempt to reconnect. Entries that are still buffered in
+// memory are discarded while those queued in SpoolDir are kept, including any that were taken from
+// the spool but not yet sent. Subsequent writes return logfmtr.ErrWriterClosed.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	var err error
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+	w.buf = nil
+	w.buffered = 0
+	inflight := w.inflight
+	w.inflight = nil
+	if w.spool != nil {
+		// entries taken from the spool may not have been sent, so queue them again
+		for _, p := range inflight {
+			_, _ = w.spool.push(p)
+		}
+		if serr := w.spool.close(); serr != nil && err == nil {
+			err = serr
+		}
+	}
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
@@ -0,0 +1,305 @@
+package writers_test
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers"
+)
+
+// testConfig returns a NetConfig that reconnects quickly.
+func testConfig() writers.NetConfig {
+	cfg := writers.DefaultNetConfig()
+	cfg.MinBackoff = 5 * time.Millisecond
+	cfg.MaxBackoff = 20 * time.Millisecond
+	return cfg
+}
+
+// serve accepts a single connection on l and sends each line read from it to the returned channel.
+func serve(t *testing.T, l net.Listener) (<-chan string, <-chan net.Conn) {
+	t.Helper()
+	lines := make(chan string, 100)
+	conns := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conns <- conn
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+	return lines, conns
+}
+
+func readLine(t *testing.T, lines <-chan string) string {
+	t.Helper()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for line")
+		return ""
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	addr := l.Addr().String()
+	lines, conns := serve(t, l)
+
+	w := writers.NewNetWriter("tcp", addr, testConfig())
+	defer w.Close()
+
+	if _, err := w.Write([]byte("one\n")); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if got := readLine(t, lines); got != "one" {
+		t.Errorf("got %q, wanted %q", got, "one")
+	}
+
+	// Stop the server and write until the writer notices the connection has failed.
+	l.Close()
+	(<-conns).Close()
+	waitFor(t, func() bool {
+		w.Write([]byte("lost\n"))
+		return !w.Connected()
+	})
+
+	if _, err := w.Write([]byte("two\n")); err != nil {
+		t.Fatalf("unexpected error writing while disconnected: %v", err)
+	}
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("unable to listen again on %s: %v", addr, err)
+	}
+	defer l.Close()
+	lines, _ = serve(t, l)
+
+	if _, err := w.Write([]byte("three\n")); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	var got []string
+	for len(got) == 0 || got[len(got)-1] != "three" {
+		if line := readLine(t, lines); line != "lost" {
+			got = append(got, line)
+		}
+	}
+	if len(got) != 2 || got[0] != "two" {
+		t.Errorf("got lines %q, wanted [two three]", got)
+	}
+}
+
+func TestNetWriterInitialFailure(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cfg := testConfig()
+	cfg.BufferSize = 8
+	w := writers.NewNetWriter("tcp", addr, cfg)
+	defer w.Close()
+
+	for _, s := range []string{"one\n", "two\n", "three\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if got := w.Dropped(); got != 2 {
+		t.Errorf("got %d dropped, wanted 2", got)
+	}
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("unable to listen again on %s: %v", addr, err)
+	}
+	defer l.Close()
+	lines, _ := serve(t, l)
+
+	if got := readLine(t, lines); got != "three" {
+		t.Errorf("got %q, wanted %q", got, "three")
+	}
+}
+
+func TestNetWriterZeroConfig(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	w := writers.NewNetWriter("tcp", addr, writers.NetConfig{})
+	defer w.Close()
+
+	if _, err := w.Write([]byte("one\n")); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	if got := w.Dropped(); got != 0 {
+		t.Errorf("got %d dropped, wanted 0", got)
+	}
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("unable to listen again on %s: %v", addr, err)
+	}
+	defer l.Close()
+	lines, _ := serve(t, l)
+
+	if got := readLine(t, lines); got != "one" {
+		t.Errorf("got %q, wanted %q", got, "one")
+	}
+}
+
+func TestNetWriterClose(t *testing.T) {
+	w := writers.NewNetWriter("tcp", "127.0.0.1:0", testConfig())
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if _, err := w.Write([]byte("one\n")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+}
+
+func TestNetWriterSpool(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cfg := testConfig()
+	cfg.SpoolDir = t.TempDir()
+	cfg.SpoolSize = 30
+	w := writers.NewNetWriter("tcp", addr, cfg)
+	for _, s := range []string{"one\n", "two\n", "three\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if got := w.Dropped(); got != 1 {
+		t.Errorf("got %d dropped, wanted 1", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	// Simulate a crash while writing a record, which should be skipped.
+	names, err := filepath.Glob(filepath.Join(cfg.SpoolDir, "*.spool"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("got spool files %q, %v, wanted at least one", names, err)
+	}
+	f, err := os.OpenFile(names[len(names)-1], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("unexpected error opening spool file: %v", err)
+	}
+	f.Write([]byte("\x00\x00\x00\x05abc"))
+	f.Close()
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("unable to listen again on %s: %v", addr, err)
+	}
+	defer l.Close()
+	lines, _ := serve(t, l)
+
+	w = writers.NewNetWriter("tcp", addr, cfg)
+	defer w.Close()
+	if _, err := w.Write([]byte("four\n")); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+	for _, want := range []string{"two", "three", "four"} {
+		if got := readLine(t, lines); got != want {
+			t.Errorf("got %q, wanted %q", got, want)
+		}
+	}
+}
+
+func TestNetWriterSpoolDrainDoesNotBlockWrites(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cfg := testConfig()
+	cfg.SpoolDir = t.TempDir()
+	cfg.SpoolSize = 0 // use the default
+	w := writers.NewNetWriter("tcp", addr, cfg)
+	entry := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 16<<10; i++ {
+		if _, err := w.Write(entry); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if got := w.Dropped(); got != 0 {
+		t.Errorf("got %d dropped, wanted 0", got)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	// A server that never reads stalls sending the spooled entries.
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("unable to listen again on %s: %v", addr, err)
+	}
+	defer l.Close()
+	conns := serveIdle(t, l)
+
+	w = writers.NewNetWriter("tcp", addr, cfg)
+	defer w.Close()
+	defer func() { (<-conns).Close() }()
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("new\n")); err != nil {
+			t.Fatalf("unexpected error writing: %v", err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("writes took %s while the spool was being sent, wanted them not to block", d)
+	}
+}
+
+// serveIdle accepts a single connection on l without reading from it.
+func serveIdle(t *testing.T, l net.Listener) <-chan net.Conn {
+	t.Helper()
+	conns := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		conns <- conn
+	}()
+	return conns
+}
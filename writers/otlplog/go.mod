@@ -0,0 +1,22 @@
+module github.com/iand/logfmtr/writers/otlplog
+
+go 1.21
+
+replace github.com/iand/logfmtr => ../../
+
+require (
+	github.com/iand/logfmtr v0.3.0
+	go.opentelemetry.io/proto/otlp v1.3.1
+	google.golang.org/grpc v1.64.1
+	google.golang.org/protobuf v1.34.1
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.20.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20240513163218-0867130af1f8 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240513163218-0867130af1f8 // indirect
+)
@@ -0,0 +1,463 @@
+// Package otlplog exports log entries to an OpenTelemetry Collector or other receiver using the
+// OpenTelemetry Protocol, over gRPC or HTTP.
+//
+// An Exporter is used as both the Writer and the Encoder of a logger's options. Each entry is converted
+// to a LogRecord and exported in batches from a background goroutine:
+//
+//	exp, err := otlplog.New(otlplog.DefaultConfig())
+//	if err != nil {
+//		// handle error
+//	}
+//	defer exp.Close()
+//	opts := logfmtr.DefaultOptions()
+//	opts.Writer = exp
+//	opts.Encoder = exp
+//	logger := logfmtr.NewWithOptions(opts)
+package otlplog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iand/logfmtr"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protocol selects the transport used to export log records.
+type Protocol int
+
+const (
+	// GRPC exports log records using gRPC.
+	GRPC Protocol = iota
+
+	// HTTP exports log records as binary protobuf messages sent by HTTP POST.
+	HTTP
+)
+
+// Config holds the settings of an Exporter.
+type Config struct {
+	// Protocol is the transport used to export log records.
+	Protocol Protocol
+
+	// Endpoint is the address of the receiver. For GRPC it is a host and port such as localhost:4317.
+	// For HTTP it is a URL such as http://localhost:4318/v1/logs.
+	Endpoint string
+
+	// Insecure disables transport security for GRPC. HTTP uses transport security when the endpoint
+	// has the https scheme.
+	Insecure bool
+
+	// Headers are sent with each export request, for example to supply an API key.
+	Headers map[string]string
+
+	// Resource holds the attributes of the resource that produces the logs, such as service.name.
+	Resource map[string]string
+
+	// BatchSize is the number of records that causes a batch to be exported.
+	BatchSize int
+
+	// BatchTimeout is the longest time a record is held before its batch is exported.
+	BatchTimeout time.Duration
+
+	// Timeout limits the time taken by each export request. When zero there is no limit.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns a Config that exports to a local collector using gRPC without transport security,
+// with the service.name resource attribute set to the base name of the running program.
+func DefaultConfig() Config {
+	return Config{
+		Protocol:     GRPC,
+		Endpoint:     "localhost:4317",
+		Insecure:     true,
+		Resource:     map[string]string{"service.name": filepath.Base(os.Args[0])},
+		BatchSize:    512,
+		BatchTimeout: time.Second,
+		Timeout:      10 * time.Second,
+	}
+}
+
+// record is a log record waiting to be exported along with the name of the logger that wrote it.
+type record struct {
+	scope string
+	lr    *logspb.LogRecord
+}
+
+var (
+	_ logfmtr.Encoder = (*Exporter)(nil)
+	_ io.Writer       = (*Exporter)(nil)
+)
+
+// Exporter converts entries to OpenTelemetry log records and exports them in batches. Entries written
+// by Error are given the ERROR severity, entries at V level 0 INFO, at V level 1 DEBUG and at higher
+// levels TRACE. The logger name is used as the instrumentation scope name. Key/value pairs become
+// attributes, except for trace_id and span_id, such as those added by logfmtr.TraceExtractor, which set
+// the trace context of the record. The caller, error and stacktrace are recorded in the code.filepath,
+// code.lineno, exception.message and exception.stacktrace attributes.
+//
+// Set both the Writer and the Encoder of a logger's options to the Exporter and register it with
+// logfmtr.RegisterWriter so that logfmtr.Flush and logfmtr.Close reach it. Errors encountered while
+// exporting in the background are returned by the next call to Flush or Close. An Exporter is safe for
+// concurrent use.
+type Exporter struct {
+	cfg      Config
+	resource *resourcepb.Resource
+	export   func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error
+	conn     *grpc.ClientConn
+	full     chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	exportMu sync.Mutex // serializes exports so records are sent in order
+
+	mu      sync.Mutex // guards pending, err and closed
+	pending []record
+	err     error
+	closed  bool
+}
+
+// New returns an Exporter that exports to the receiver described by cfg. Close must be called to export
+// any remaining records and stop the background goroutine.
+func New(cfg Config) (*Exporter, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = time.Second
+	}
+	e := &Exporter{
+		cfg:      cfg,
+		resource: &resourcepb.Resource{Attributes: stringAttributes(cfg.Resource)},
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	switch cfg.Protocol {
+	case GRPC:
+		creds := credentials.NewTLS(&tls.Config{})
+		if cfg.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("otlplog: %w", err)
+		}
+		e.conn = conn
+		client := collogspb.NewLogsServiceClient(conn)
+		e.export = func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+			if len(cfg.Headers) > 0 {
+				ctx = metadata.NewOutgoingContext(ctx, metadata.New(cfg.Headers))
+			}
+			_, err := client.Export(ctx, req)
+			return err
+		}
+	case HTTP:
+		if !strings.HasPrefix(cfg.Endpoint, "http://") && !strings.HasPrefix(cfg.Endpoint, "https://") {
+			return nil, fmt.Errorf("otlplog: invalid HTTP endpoint %q", cfg.Endpoint)
+		}
+		e.export = e.exportHTTP
+	default:
+		return nil, fmt.Errorf("otlplog: unknown protocol %d", cfg.Protocol)
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return e, nil
+}
+
+// EncodeEntry converts the entry to a log record and adds it to the current batch. Nothing is written to w.
+func (e *Exporter) EncodeEntry(w io.Writer, entry logfmtr.Entry) error {
+	r := record{scope: entry.Name, lr: logRecord(entry)}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return logfmtr.ErrWriterClosed
+	}
+	e.pending = append(e.pending, r)
+	if len(e.pending) >= e.cfg.BatchSize {
+		select {
+		case e.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Write discards p. It allows the Exporter to be used as the Writer of a logger's options.
+func (e *Exporter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Flush exports any pending records. It returns the first error encountered since the previous call to
+// Flush, including errors from exports made in the background.
+func (e *Exporter) Flush() error {
+	e.exportPending()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.err
+	e.err = nil
+	return err
+}
+
+// Close exports any pending records, stops the background goroutine and closes the connection to the
+// receiver. Subsequent entries are rejected with logfmtr.ErrWriterClosed.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.mu.Unlock()
+	close(e.done)
+	e.wg.Wait()
+
+	err := e.Flush()
+	if e.conn != nil {
+		if cerr := e.conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (e *Exporter) run() {
+	defer e.wg.Done()
+	t := time.NewTicker(e.cfg.BatchTimeout)
+	defer t.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-t.C:
+		case <-e.full:
+		}
+		e.exportPending()
+	}
+}
+
+// exportPending exports the current batch, recording any error.
+func (e *Exporter) exportPending() {
+	e.exportMu.Lock()
+	defer e.exportMu.Unlock()
+
+	e.mu.Lock()
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	if e.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.Timeout)
+		defer cancel()
+	}
+	if err := e.export(ctx, e.request(batch)); err != nil {
+		e.mu.Lock()
+		if e.err == nil {
+			e.err = fmt.Errorf("otlplog: export failed: %w", err)
+		}
+		e.mu.Unlock()
+	}
+}
+
+// request groups a batch of records by scope into an export request.
+func (e *Exporter) request(batch []record) *collogspb.ExportLogsServiceRequest {
+	rl := &logspb.ResourceLogs{Resource: e.resource}
+	scopes := map[string]*logspb.ScopeLogs{}
+	for _, r := range batch {
+		sl, ok := scopes[r.scope]
+		if !ok {
+			sl = &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: r.scope}}
+			scopes[r.scope] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		sl.LogRecords = append(sl.LogRecords, r.lr)
+	}
+	return &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{rl}}
+}
+
+// exportHTTP sends an export request as a binary protobuf message.
+func (e *Exporter) exportHTTP(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hreq, err := http.NewRequest(http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hreq = hreq.WithContext(ctx)
+	hreq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.cfg.Headers {
+		hreq.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 != 2 {
+		return errors.New(resp.Status + ": " + strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// logRecord converts an entry to a log record.
+func logRecord(e logfmtr.Entry) *logspb.LogRecord {
+	lr := &logspb.LogRecord{
+		TimeUnixNano:         uint64(e.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		Body:                 stringValue(e.Message),
+	}
+	switch {
+	case e.IsError:
+		lr.SeverityNumber, lr.SeverityText = logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "ERROR"
+	case e.Level == 0:
+		lr.SeverityNumber, lr.SeverityText = logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "INFO"
+	case e.Level == 1:
+		lr.SeverityNumber, lr.SeverityText = logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "DEBUG"
+	default:
+		lr.SeverityNumber, lr.SeverityText = logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, "TRACE"
+	}
+	if e.Caller != "" {
+		file, line := e.Caller, ""
+		if i := strings.LastIndexByte(file, ' '); i >= 0 {
+			file = file[i+1:]
+		}
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file, line = file[:i], file[i+1:]
+		}
+		lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: "code.filepath", Value: stringValue(file)})
+		if n, err := strconv.ParseInt(line, 10, 64); err == nil {
+			lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: "code.lineno", Value: intValue(n)})
+		}
+	}
+	if e.IsError {
+		lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: "exception.message", Value: stringValue(fmt.Sprint(e.Error))})
+	}
+	if e.Stacktrace != "" {
+		lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: "exception.stacktrace", Value: stringValue(e.Stacktrace)})
+	}
+	for _, kvs := range [][]interface{}{e.Context, e.Values} {
+		for i := 0; i < len(kvs); i += 2 {
+			key := fmt.Sprint(kvs[i])
+			var v interface{} = ""
+			if i+1 < len(kvs) {
+				v = kvs[i+1]
+			}
+			switch key {
+			case "trace_id":
+				if id, ok := decodeID(v, 16); ok {
+					lr.TraceId = id
+					continue
+				}
+			case "span_id":
+				if id, ok := decodeID(v, 8); ok {
+					lr.SpanId = id
+					continue
+				}
+			}
+			lr.Attributes = append(lr.Attributes, &commonpb.KeyValue{Key: key, Value: anyValue(v)})
+		}
+	}
+	return lr
+}
+
+// decodeID decodes a hex encoded trace or span id of the given size in bytes.
+func decodeID(v interface{}, size int) ([]byte, bool) {
+	s, ok := v.(string)
+	if !ok || len(s) != 2*size {
+		return nil, false
+	}
+	id, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	return id, true
+}
+
+// anyValue converts a value to the closest attribute value type. Values that have no corresponding
+// type are converted to strings.
+func anyValue(v interface{}) *commonpb.AnyValue {
+	switch vv := v.(type) {
+	case string:
+		return stringValue(vv)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: vv}}
+	case int:
+		return intValue(int64(vv))
+	case int8:
+		return intValue(int64(vv))
+	case int16:
+		return intValue(int64(vv))
+	case int32:
+		return intValue(int64(vv))
+	case int64:
+		return intValue(vv)
+	case uint:
+		return intValue(int64(vv))
+	case uint8:
+		return intValue(int64(vv))
+	case uint16:
+		return intValue(int64(vv))
+	case uint32:
+		return intValue(int64(vv))
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(vv)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: vv}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: vv}}
+	}
+	return stringValue(fmt.Sprint(v))
+}
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func intValue(n int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: n}}
+}
+
+// stringAttributes converts a map to attributes sorted by key.
+func stringAttributes(m map[string]string) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, &commonpb.KeyValue{Key: k, Value: stringValue(m[k])})
+	}
+	return attrs
+}
@@ -0,0 +1,184 @@
+package otlplog_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers/otlplog"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+func checkRequest(t *testing.T, req *collogspb.ExportLogsServiceRequest) {
+	t.Helper()
+	if len(req.ResourceLogs) != 1 {
+		t.Fatalf("got %d resource logs, wanted 1", len(req.ResourceLogs))
+	}
+	rl := req.ResourceLogs[0]
+	if attrs := rl.Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || attrs[0].Value.GetStringValue() != "moons" {
+		t.Errorf("got resource attributes %v, wanted service.name=moons", attrs)
+	}
+	if len(rl.ScopeLogs) != 2 {
+		t.Fatalf("got %d scope logs, wanted 2", len(rl.ScopeLogs))
+	}
+	if rl.ScopeLogs[0].Scope.Name != "europa" || rl.ScopeLogs[1].Scope.Name != "io" {
+		t.Errorf("got scopes %q and %q, wanted europa and io", rl.ScopeLogs[0].Scope.Name, rl.ScopeLogs[1].Scope.Name)
+	}
+
+	records := rl.ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("got %d records for europa, wanted 2", len(records))
+	}
+	lr := records[0]
+	if lr.Body.GetStringValue() != "hello" || lr.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_INFO || lr.SeverityText != "INFO" {
+		t.Errorf("got record %v, wanted INFO record with body hello", lr)
+	}
+	if lr.TimeUnixNano != uint64(time.Unix(1704164645, 0).UnixNano()) {
+		t.Errorf("got time %d, wanted entry time", lr.TimeUnixNano)
+	}
+	if hex.EncodeToString(lr.TraceId) != "4bf92f3577b34da6a3ce929d0e0e4736" || hex.EncodeToString(lr.SpanId) != "00f067aa0ba902b7" {
+		t.Errorf("got trace id %x and span id %x", lr.TraceId, lr.SpanId)
+	}
+	if len(lr.Attributes) != 2 || lr.Attributes[0].Key != "val" || lr.Attributes[0].Value.GetIntValue() != 1 ||
+		lr.Attributes[1].Key != "ratio" || lr.Attributes[1].Value.GetDoubleValue() != 0.5 {
+		t.Errorf("got attributes %v, wanted val=1 ratio=0.5", lr.Attributes)
+	}
+
+	lr = records[1]
+	if lr.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR || len(lr.Attributes) != 1 ||
+		lr.Attributes[0].Key != "exception.message" || lr.Attributes[0].Value.GetStringValue() != "uh oh" {
+		t.Errorf("got record %v, wanted ERROR record with exception.message", lr)
+	}
+
+	if lr := rl.ScopeLogs[1].LogRecords[0]; lr.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG {
+		t.Errorf("got severity %v for V(1) entry, wanted DEBUG", lr.SeverityNumber)
+	}
+}
+
+func logEntries(exp *otlplog.Exporter) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = exp
+	opts.Encoder = exp
+	opts.Clock = func() time.Time { return time.Unix(1704164645, 0) }
+	logger := logfmtr.NewWithOptions(opts)
+
+	europa := logger.WithName("europa")
+	europa.WithValues("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7").Info("hello", "val", 1, "ratio", 0.5)
+	europa.Error(errors.New("uh oh"), "goodbye")
+	logger.WithName("io").V(1).Info("volcano")
+}
+
+func testConfig() otlplog.Config {
+	cfg := otlplog.DefaultConfig()
+	cfg.Resource = map[string]string{"service.name": "moons"}
+	cfg.Headers = map[string]string{"api-key": "secret"}
+	cfg.BatchTimeout = time.Hour
+	return cfg
+}
+
+func TestExporterHTTP(t *testing.T) {
+	var mu sync.Mutex
+	var reqs []*collogspb.ExportLogsServiceRequest
+	var apiKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := &collogspb.ExportLogsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		apiKey = r.Header.Get("api-key")
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.Protocol = otlplog.HTTP
+	cfg.Endpoint = srv.URL + "/v1/logs"
+	exp, err := otlplog.New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logEntries(exp)
+	if err := exp.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, wanted 1", len(reqs))
+	}
+	if apiKey != "secret" {
+		t.Errorf("got api-key header %q, wanted secret", apiKey)
+	}
+	checkRequest(t, reqs[0])
+}
+
+type logsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+	mu     sync.Mutex
+	reqs   []*collogspb.ExportLogsServiceRequest
+	apiKey []string
+}
+
+func (s *logsServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reqs = append(s.reqs, req)
+	s.apiKey = md.Get("api-key")
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func TestExporterGRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	ls := &logsServer{}
+	collogspb.RegisterLogsServiceServer(srv, ls)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	cfg := testConfig()
+	cfg.Endpoint = ln.Addr().String()
+	exp, err := otlplog.New(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logEntries(exp)
+	if err := exp.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing: %v", err)
+	}
+	if err := exp.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if len(ls.reqs) != 1 {
+		t.Fatalf("got %d requests, wanted 1", len(ls.reqs))
+	}
+	if len(ls.apiKey) != 1 || ls.apiKey[0] != "secret" {
+		t.Errorf("got api-key metadata %q, wanted secret", ls.apiKey)
+	}
+	checkRequest(t, ls.reqs[0])
+}
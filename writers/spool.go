@@ -0,0 +1,291 @@
+package writers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// spoolExt is the extension of the segment files of a spool.
+	spoolExt = ".spool"
+
+	// spoolHeaderLen is the length of the header of each record, holding the length of the entry and
+	// its CRC-32 checksum.
+	spoolHeaderLen = 8
+
+	// maxSpoolSegment is the largest size of a segment file.
+	maxSpoolSegment = 4 << 20
+)
+
+// errSpoolCorrupt is returned when a record of a spool segment is truncated or fails its checksum.
+var errSpoolCorrupt = errors.New("corrupt spool record")
+
+// spool is a queue of entries held in a directory of segment files. Each entry is written as a record
+// with a header holding its length and checksum, so that records damaged by a crash or a full disk are
+// detected and skipped. Segments are removed once all of their entries have been read, and the oldest
+// segments are removed to keep the total size within a limit. A spool is not safe for concurrent use.
+type spool struct {
+	dir     string
+	maxSize int64
+	segSize int64
+
+	segs []spoolSegment // oldest first
+	size int64          // total size of segs
+	w    *os.File       // newest segment, open for appending, nil until an entry is pushed
+	r    *os.File       // oldest segment, open for reading, nil until an entry is read
+	off  int64          // offset of the next record to read in the oldest segment
+	read int            // number of records read from the oldest segment
+}
+
+type spoolSegment struct {
+	seq   uint64
+	size  int64
+	count int
+}
+
+// openSpool opens the spool in dir, creating the directory if necessary. Entries left in the directory
+// by a previous spool are kept and are read before new entries.
+func openSpool(dir string, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &spool{
+		dir:     dir,
+		maxSize: maxSize,
+		segSize: maxSize / 4,
+	}
+	if s.segSize > maxSpoolSegment {
+		s.segSize = maxSpoolSegment
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, spoolExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		count, err := countRecords(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		s.segs = append(s.segs, spoolSegment{seq: seq, size: info.Size(), count: count})
+		s.size += info.Size()
+	}
+	sort.Slice(s.segs, func(i, j int) bool { return s.segs[i].seq < s.segs[j].seq })
+	return s, nil
+}
+
+// countRecords returns the number of valid records at the start of a segment file.
+func countRecords(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var off int64
+	n := 0
+	for {
+		p, err := readRecord(f, off)
+		if err != nil {
+			if err == io.EOF || err == errSpoolCorrupt {
+				return n, nil
+			}
+			return n, err
+		}
+		off += spoolHeaderLen + int64(len(p))
+		n++
+	}
+}
+
+// readRecord reads the record at off. It returns io.EOF if there are no more records.
+func readRecord(f *os.File, off int64) ([]byte, error) {
+	var hdr [spoolHeaderLen]byte
+	n, err := f.ReadAt(hdr[:], off)
+	if n == 0 && err == io.EOF {
+		return nil, io.EOF
+	}
+	if n < len(hdr) {
+		if err == io.EOF {
+			return nil, errSpoolCorrupt
+		}
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(hdr[0:4])
+	if size > maxSpoolSegment {
+		return nil, errSpoolCorrupt
+	}
+	p := make([]byte, size)
+	if n, err := f.ReadAt(p, off+spoolHeaderLen); n < len(p) {
+		if err == io.EOF {
+			return nil, errSpoolCorrupt
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(p) != binary.BigEndian.Uint32(hdr[4:8]) {
+		return nil, errSpoolCorrupt
+	}
+	return p, nil
+}
+
+// len returns the number of entries in the spool.
+func (s *spool) len() int {
+	n := -s.read
+	for _, seg := range s.segs {
+		n += seg.count
+	}
+	return n
+}
+
+// push appends an entry to the spool, removing the oldest segments if necessary to keep within the
+// maximum size. It returns the number of entries that were removed, including p itself if it is
+// larger than the spool.
+func (s *spool) push(p []byte) (dropped int, err error) {
+	n := int64(spoolHeaderLen + len(p))
+	if n > s.maxSize || len(p) > maxSpoolSegment {
+		return 1, nil
+	}
+	for len(s.segs) > 0 && s.size+n > s.maxSize {
+		dropped += s.segs[0].count - s.read
+		if err := s.removeOldest(); err != nil {
+			return dropped, err
+		}
+	}
+
+	if s.w == nil || s.segs[len(s.segs)-1].size+n > s.segSize {
+		if err := s.newSegment(); err != nil {
+			return dropped, err
+		}
+	}
+	rec := make([]byte, spoolHeaderLen, n)
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(p)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(p))
+	rec = append(rec, p...)
+	if _, err := s.w.Write(rec); err != nil {
+		// a partial record is detected by its checksum, so start a new segment for later entries
+		s.w.Close()
+		s.w = nil
+		return dropped + 1, err
+	}
+	seg := &s.segs[len(s.segs)-1]
+	seg.size += n
+	seg.count++
+	s.size += n
+	return dropped, nil
+}
+
+// newSegment closes the segment being appended to and starts a new one.
+func (s *spool) newSegment() error {
+	if s.w != nil {
+		if err := s.w.Close(); err != nil {
+			return err
+		}
+		s.w = nil
+	}
+	var seq uint64
+	if len(s.segs) > 0 {
+		seq = s.segs[len(s.segs)-1].seq + 1
+	}
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.w = f
+	s.segs = append(s.segs, spoolSegment{seq: seq})
+	return nil
+}
+
+// peek returns the oldest entry without removing it, or nil if the spool is empty. Entries that cannot
+// be read because their records are damaged are removed along with the rest of their segment, and are
+// counted in dropped.
+func (s *spool) peek() (p []byte, dropped int, err error) {
+	for len(s.segs) > 0 {
+		if s.r == nil {
+			f, err := os.Open(s.segmentPath(s.segs[0].seq))
+			if err != nil {
+				return nil, dropped, err
+			}
+			s.r = f
+		}
+		if s.read < s.segs[0].count {
+			p, err := readRecord(s.r, s.off)
+			if err == nil {
+				return p, dropped, nil
+			}
+			if err != io.EOF && err != errSpoolCorrupt {
+				return nil, dropped, err
+			}
+			dropped += s.segs[0].count - s.read
+		}
+		if len(s.segs) == 1 && s.w != nil {
+			// the segment is still being appended to and has no unread entries
+			return nil, dropped, nil
+		}
+		if err := s.removeOldest(); err != nil {
+			return nil, dropped, err
+		}
+	}
+	return nil, dropped, nil
+}
+
+// pop removes the entry returned by the last call to peek.
+func (s *spool) pop(p []byte) {
+	s.off += spoolHeaderLen + int64(len(p))
+	s.read++
+}
+
+// removeOldest removes the oldest segment.
+func (s *spool) removeOldest() error {
+	if s.r != nil {
+		s.r.Close()
+		s.r = nil
+	}
+	if len(s.segs) == 1 && s.w != nil {
+		s.w.Close()
+		s.w = nil
+	}
+	seg := s.segs[0]
+	s.segs = s.segs[1:]
+	s.size -= seg.size
+	s.off = 0
+	s.read = 0
+	if err := os.Remove(s.segmentPath(seg.seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolExt))
+}
+
+// close closes the spool's files, leaving unread entries in the directory.
+func (s *spool) close() error {
+	var err error
+	if s.r != nil {
+		err = s.r.Close()
+		s.r = nil
+	}
+	if s.w != nil {
+		if cerr := s.w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		s.w = nil
+	}
+	return err
+}
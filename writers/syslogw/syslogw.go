@@ -0,0 +1,338 @@
+// Package syslogw provides a writer that sends log entries to a syslog server and an encoder that
+// frames entries as syslog messages with a severity derived from the entry.
+//
+// A Writer can be used directly as the Writer of logfmtr Options, in which case each entry is sent with
+// the severity given in the Config. To map entries written by Error to the err severity and entries
+// written at V levels above zero to the debug severity, also set the Encoder of the Options to the
+// writer's Encoder:
+//
+//	w, err := syslogw.Dial("udp", "logs.example.com:514", syslogw.DefaultConfig())
+//	if err != nil {
+//		// handle error
+//	}
+//	opts := logfmtr.DefaultOptions()
+//	opts.Writer = w
+//	opts.Encoder = w.Encoder()
+//	logger := logfmtr.NewWithOptions(opts)
+package syslogw
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+// Format selects the syslog message format.
+type Format int
+
+const (
+	// RFC5424 formats messages as described by RFC 5424.
+	RFC5424 Format = iota
+
+	// RFC3164 formats messages in the older BSD syslog format described by RFC 3164.
+	RFC3164
+)
+
+// Facility is a syslog facility code.
+type Facility int
+
+// Facilities defined by RFC 5424.
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	LPR
+	News
+	UUCP
+	Cron
+	AuthPriv
+	FTP
+	Local0 Facility = iota + 4
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// Severity is a syslog severity level.
+type Severity int
+
+// Severities defined by RFC 5424, from most to least severe.
+const (
+	Emerg Severity = iota
+	Alert
+	Crit
+	Err
+	Warning
+	Notice
+	Info
+	Debug
+)
+
+// localPaths are the paths of the local syslog socket searched when Dial is called with an empty network.
+var localPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// Config holds the fields used in the header of each syslog message.
+type Config struct {
+	// Format is the message format.
+	Format Format
+
+	// Facility is the facility of each message.
+	Facility Facility
+
+	// Severity is the severity of messages written to a Writer that do not already have a syslog header.
+	Severity Severity
+
+	// Hostname is the name of the host sending the messages. When empty "-" is sent in RFC 5424
+	// messages and the hostname is omitted from RFC 3164 messages.
+	Hostname string
+
+	// AppName identifies the application sending the messages.
+	AppName string
+}
+
+// DefaultConfig returns a Config that sends RFC 5424 messages with the user facility and the info severity,
+// identified by the host name and the base name of the running program.
+func DefaultConfig() Config {
+	hostname, _ := os.Hostname()
+	return Config{
+		Format:   RFC5424,
+		Facility: User,
+		Severity: Info,
+		Hostname: hostname,
+		AppName:  filepath.Base(os.Args[0]),
+	}
+}
+
+// appendHeader appends a syslog header for a message with the given severity and time.
+func (c *Config) appendHeader(b []byte, sev Severity, t time.Time) []byte {
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(c.Facility)<<3|int64(sev), 10)
+	b = append(b, '>')
+	if c.Format == RFC3164 {
+		b = t.AppendFormat(b, time.Stamp)
+		if c.Hostname != "" {
+			b = append(b, ' ')
+			b = append(b, c.Hostname...)
+		}
+		b = append(b, ' ')
+		b = append(b, c.AppName...)
+		b = append(b, '[')
+		b = strconv.AppendInt(b, int64(os.Getpid()), 10)
+		return append(b, "]: "...)
+	}
+	b = append(b, "1 "...)
+	b = t.UTC().AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, ' ')
+	b = appendHeaderField(b, c.Hostname)
+	b = append(b, ' ')
+	b = appendHeaderField(b, c.AppName)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(os.Getpid()), 10)
+	// no message id or structured data
+	return append(b, " - - "...)
+}
+
+// appendHeaderField appends an RFC 5424 header field, using "-" for an empty value and replacing
+// characters that are not permitted with underscores.
+func appendHeaderField(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	return b
+}
+
+var _ logfmtr.Encoder = (*Encoder)(nil)
+
+// Encoder writes entries as syslog messages terminated by a newline. Entries written by Error are given
+// the err severity, entries at V level zero the info severity and entries at higher V levels the debug
+// severity.
+type Encoder struct {
+	// Config supplies the fields of the message header. Its Severity is not used.
+	Config Config
+
+	// Body encodes the entry as the message body. When nil a logfmtr.LogfmtEncoder without timestamps
+	// is used since the header includes the time of the entry.
+	Body logfmtr.Encoder
+}
+
+// EncodeEntry writes the entry as a single syslog message.
+func (enc *Encoder) EncodeEntry(w io.Writer, e logfmtr.Entry) error {
+	body := enc.Body
+	if body == nil {
+		body = &logfmtr.LogfmtEncoder{}
+	}
+	var buf bytes.Buffer
+	if err := body.EncodeEntry(&buf, e); err != nil {
+		return err
+	}
+	b := enc.Config.appendHeader(make([]byte, 0, 64+buf.Len()), severity(e), e.Time)
+	b = append(b, bytes.TrimRight(buf.Bytes(), "\n")...)
+	b = append(b, '\n')
+	_, err := w.Write(b)
+	return err
+}
+
+// severity returns the syslog severity of an entry.
+func severity(e logfmtr.Entry) Severity {
+	switch {
+	case e.IsError:
+		return Err
+	case e.Level > 0:
+		return Debug
+	default:
+		return Info
+	}
+}
+
+// Writer sends messages to a syslog server. Each call to Write sends one message. Messages that do not
+// begin with a syslog priority, such as those written by logfmtr's default encoders, are given a header
+// using the writer's Config. Messages that already have one, such as those written by an Encoder, are
+// sent unchanged. A Writer reconnects to the server if a write fails. It is safe for concurrent use.
+type Writer struct {
+	cfg     Config
+	network string
+	addr    string
+
+	mu     sync.Mutex // guards conn, stream and closed
+	conn   net.Conn
+	stream bool // whether conn is a stream connection requiring messages to be delimited
+	closed bool
+}
+
+// Dial connects to a syslog server at addr using the named network, which may be "udp", "tcp", "unix" or
+// "unixgram" or any other network accepted by net.Dial. If network is empty the local syslog socket is used.
+func Dial(network, addr string, cfg Config) (*Writer, error) {
+	w := &Writer{
+		cfg:     cfg,
+		network: network,
+		addr:    addr,
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect establishes a connection to the server. w.mu must be held or w must not yet be shared.
+func (w *Writer) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	if w.network != "" {
+		conn, err := net.Dial(w.network, w.addr)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		w.stream = w.network != "unixgram" && !strings.HasPrefix(w.network, "udp")
+		return nil
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range localPaths {
+			conn, err := net.Dial(network, path)
+			if err == nil {
+				w.conn = conn
+				w.stream = network == "unix"
+				return nil
+			}
+		}
+	}
+	return errors.New("syslogw: local syslog socket not found")
+}
+
+// Encoder returns an Encoder that uses the writer's Config.
+func (w *Writer) Encoder() *Encoder {
+	return &Encoder{Config: w.cfg}
+}
+
+// Write sends p as a single message.
+func (w *Writer) Write(p []byte) (int, error) {
+	msg := bytes.TrimRight(p, "\n")
+	if !hasPriority(msg) {
+		b := w.cfg.appendHeader(make([]byte, 0, 64+len(msg)), w.cfg.Severity, time.Now())
+		msg = append(b, msg...)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, logfmtr.ErrWriterClosed
+	}
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.send(msg); err != nil {
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+		if err := w.send(msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// send writes a message to the connection. Messages sent over stream connections are terminated by a
+// newline. w.mu must be held.
+func (w *Writer) send(msg []byte) error {
+	if w.stream {
+		msg = append(msg[:len(msg):len(msg)], '\n')
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// Close closes the connection to the server. Subsequent writes return logfmtr.ErrWriterClosed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// hasPriority reports whether msg begins with a syslog priority such as <14>.
+func hasPriority(msg []byte) bool {
+	if len(msg) < 3 || msg[0] != '<' {
+		return false
+	}
+	for i := 1; i < len(msg) && i <= 4; i++ {
+		if msg[i] == '>' {
+			return i > 1
+		}
+		if msg[i] < '0' || msg[i] > '9' {
+			return false
+		}
+	}
+	return false
+}
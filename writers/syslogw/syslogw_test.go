@@ -0,0 +1,116 @@
+package syslogw_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers/syslogw"
+)
+
+var testConfig = syslogw.Config{
+	Format:   syslogw.RFC5424,
+	Facility: syslogw.Local0,
+	Severity: syslogw.Notice,
+	Hostname: "europa",
+	AppName:  "app",
+}
+
+func TestEncoderUDP(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	defer pc.Close()
+
+	w, err := syslogw.Dial("udp", pc.LocalAddr().String(), testConfig)
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+	defer w.Close()
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.Encoder = w.Encoder()
+	opts.Clock = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC) }
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello", "val", 1)
+	logger.V(1).Info("details")
+	logger.Error(errors.New("uh oh"), "goodbye")
+
+	header := "1 2024-01-02T03:04:05.000006Z europa app " + fmt.Sprint(os.Getpid()) + " - - "
+	want := []string{
+		"<134>" + header + "level=0 msg=hello val=1",
+		"<135>" + header + "level=1 msg=details",
+		"<131>" + header + "level=0 msg=goodbye error=\"uh oh\"",
+	}
+	buf := make([]byte, 1024)
+	for _, wanted := range want {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("unexpected error reading: %v", err)
+		}
+		if got := string(buf[:n]); got != wanted {
+			t.Errorf("got %q, wanted %q", got, wanted)
+		}
+	}
+}
+
+func TestWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("unable to listen: %v", err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(lines)
+			return
+		}
+		defer conn.Close()
+		s := bufio.NewScanner(conn)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+		close(lines)
+	}()
+
+	cfg := testConfig
+	cfg.Format = syslogw.RFC3164
+	w, err := syslogw.Dial("tcp", ln.Addr().String(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error dialing: %v", err)
+	}
+
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = w
+	opts.TimestampFormat = ""
+	logfmtr.NewWithOptions(opts).Info("hello")
+	w.Close()
+
+	if _, err := w.Write([]byte("after close")); err != logfmtr.ErrWriterClosed {
+		t.Errorf("got error %v after close, wanted %v", err, logfmtr.ErrWriterClosed)
+	}
+
+	got, ok := <-lines
+	if !ok {
+		t.Fatalf("no message received")
+	}
+	// <133> is the local0 facility with notice severity, followed by a timestamp in the RFC 3164 format
+	wantPrefix, wantSuffix := "<133>", fmt.Sprintf(" europa app[%d]: level=0 msg=hello", os.Getpid())
+	if len(got) != len(wantPrefix)+len(time.Stamp)+len(wantSuffix) || got[:len(wantPrefix)] != wantPrefix || got[len(got)-len(wantSuffix):] != wantSuffix {
+		t.Errorf("got %q, wanted %q followed by a timestamp and %q", got, wantPrefix, wantSuffix)
+	}
+}
@@ -0,0 +1,239 @@
+package writers
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iand/logfmtr"
+)
+
+var (
+	_ logfmtr.LevelWriter = (*TeeWriter)(nil)
+	_ logfmtr.LevelWriter = (*levelFilter)(nil)
+	_ logfmtr.LevelWriter = (*FailoverWriter)(nil)
+)
+
+// TeeWriter writes each entry to several writers.
+type TeeWriter struct {
+	ws []io.Writer
+}
+
+// Tee returns a writer that writes each entry to all of the supplied writers. A write is made to every
+// writer even if an earlier one fails, and the first error is returned. Writers wrapped by MaxLevel or
+// ErrorsOnly only receive entries that meet their level threshold. Flushing or closing the TeeWriter
+// flushes or closes each of the writers, except for standard output and standard error which are not
+// closed.
+func Tee(w ...io.Writer) *TeeWriter {
+	return &TeeWriter{ws: append([]io.Writer(nil), w...)}
+}
+
+// Write writes p to each writer.
+func (t *TeeWriter) Write(p []byte) (int, error) {
+	var firstErr error
+	for _, w := range t.ws {
+		if _, err := w.Write(p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(p), firstErr
+}
+
+// WriteLevel writes p to each writer that accepts entries of the given level.
+func (t *TeeWriter) WriteLevel(level int, isError bool, p []byte) (int, error) {
+	var firstErr error
+	for _, w := range t.ws {
+		if _, err := writeLevel(w, level, isError, p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return len(p), firstErr
+}
+
+// Flush flushes each writer that has a Flush or Sync method.
+func (t *TeeWriter) Flush() error {
+	var firstErr error
+	for _, w := range t.ws {
+		if err := flushWriter(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close closes each writer that has a Close method.
+func (t *TeeWriter) Close() error {
+	var firstErr error
+	for _, w := range t.ws {
+		if err := closeWriter(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// levelFilter passes entries that meet a level threshold to an underlying writer.
+type levelFilter struct {
+	w          io.Writer
+	max        int
+	errorsOnly bool
+}
+
+// MaxLevel returns a writer that writes entries with a V level no greater than max to w. Entries written
+// by Error are always written. The level of an entry is only known when the writer is used directly as a
+// logger's writer or through Tee or Failover; otherwise every entry is written.
+func MaxLevel(w io.Writer, max int) io.Writer {
+	return &levelFilter{w: w, max: max}
+}
+
+// ErrorsOnly returns a writer that only writes entries written by Error to w. The level of an entry is
+// only known when the writer is used directly as a logger's writer or through Tee or Failover; otherwise
+// every entry is written.
+func ErrorsOnly(w io.Writer) io.Writer {
+	return &levelFilter{w: w, errorsOnly: true}
+}
+
+func (f *levelFilter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+func (f *levelFilter) WriteLevel(level int, isError bool, p []byte) (int, error) {
+	if !isError && (f.errorsOnly || level > f.max) {
+		return len(p), nil
+	}
+	return writeLevel(f.w, level, isError, p)
+}
+
+func (f *levelFilter) Flush() error {
+	return flushWriter(f.w)
+}
+
+func (f *levelFilter) Close() error {
+	return closeWriter(f.w)
+}
+
+// DefaultRetryInterval is the time a FailoverWriter waits before retrying its primary writer.
+const DefaultRetryInterval = 10 * time.Second
+
+// FailoverWriter writes to a primary writer, switching to a fallback writer when the primary fails.
+type FailoverWriter struct {
+	primary  io.Writer
+	fallback io.Writer
+
+	mu       sync.Mutex // guards the fields below
+	retry    time.Duration
+	failedAt time.Time // zero while the primary is in use
+	failures uint64
+}
+
+// Failover returns a writer that writes to primary until a write fails, after which that write and
+// subsequent writes are made to fallback, such as os.Stderr. The primary is tried again once the retry
+// interval has passed, which is DefaultRetryInterval unless changed by SetRetryInterval.
+func Failover(primary, fallback io.Writer) *FailoverWriter {
+	return &FailoverWriter{
+		primary:  primary,
+		fallback: fallback,
+		retry:    DefaultRetryInterval,
+	}
+}
+
+// SetRetryInterval sets the time to wait after the primary fails before it is tried again.
+func (f *FailoverWriter) SetRetryInterval(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.retry = d
+}
+
+// Failures returns the number of writes to the primary that have failed.
+func (f *FailoverWriter) Failures() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failures
+}
+
+// Write writes p to the primary writer, or to the fallback writer if the primary has failed.
+func (f *FailoverWriter) Write(p []byte) (int, error) {
+	return f.write(func(w io.Writer) (int, error) { return w.Write(p) })
+}
+
+// WriteLevel writes p to the primary writer, or to the fallback writer if the primary has failed,
+// passing on the level of the entry.
+func (f *FailoverWriter) WriteLevel(level int, isError bool, p []byte) (int, error) {
+	return f.write(func(w io.Writer) (int, error) { return writeLevel(w, level, isError, p) })
+}
+
+func (f *FailoverWriter) write(fn func(w io.Writer) (int, error)) (int, error) {
+	if f.usePrimary() {
+		n, err := fn(f.primary)
+		if err == nil {
+			return n, nil
+		}
+		f.mu.Lock()
+		f.failedAt = time.Now()
+		f.failures++
+		f.mu.Unlock()
+	}
+	return fn(f.fallback)
+}
+
+// usePrimary reports whether the primary writer should be used, which it is until it fails and then
+// again once the retry interval has passed.
+func (f *FailoverWriter) usePrimary() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failedAt.IsZero() || time.Since(f.failedAt) >= f.retry
+}
+
+// Flush flushes the primary and fallback writers if they have a Flush or Sync method.
+func (f *FailoverWriter) Flush() error {
+	err := flushWriter(f.primary)
+	if ferr := flushWriter(f.fallback); ferr != nil && err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// Close closes the primary and fallback writers if they have a Close method.
+func (f *FailoverWriter) Close() error {
+	err := closeWriter(f.primary)
+	if cerr := closeWriter(f.fallback); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeLevel writes p to w, passing the level of the entry if w is a LevelWriter.
+func writeLevel(w io.Writer, level int, isError bool, p []byte) (int, error) {
+	if lw, ok := w.(logfmtr.LevelWriter); ok {
+		return lw.WriteLevel(level, isError, p)
+	}
+	return w.Write(p)
+}
+
+// flushWriter flushes w if it has a Flush or Sync method.
+func flushWriter(w io.Writer) error {
+	switch fw := w.(type) {
+	case interface{ Flush() error }:
+		return fw.Flush()
+	case interface{ Sync() error }:
+		if isStdStream(w) {
+			// syncing a terminal or pipe is not supported on all platforms
+			return nil
+		}
+		return fw.Sync()
+	}
+	return nil
+}
+
+// closeWriter closes w if it has a Close method and is not standard output or standard error.
+func closeWriter(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok && !isStdStream(w) {
+		return c.Close()
+	}
+	return nil
+}
+
+func isStdStream(w io.Writer) bool {
+	return w == io.Writer(os.Stdout) || w == io.Writer(os.Stderr)
+}
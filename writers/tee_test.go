@@ -0,0 +1,92 @@
+package writers_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iand/logfmtr"
+	"github.com/iand/logfmtr/writers"
+)
+
+func TestTee(t *testing.T) {
+	defer logfmtr.SetVerbosity(logfmtr.SetVerbosity(1))
+	var all, info, errs bytes.Buffer
+	opts := logfmtr.DefaultOptions()
+	opts.Writer = writers.Tee(&all, writers.MaxLevel(&info, 0), writers.ErrorsOnly(&errs))
+	opts.TimestampFormat = ""
+	logger := logfmtr.NewWithOptions(opts)
+
+	logger.Info("hello")
+	logger.V(1).Info("details")
+	logger.Error(nil, "goodbye")
+
+	testCases := []struct {
+		name string
+		buf  *bytes.Buffer
+		want string
+	}{
+		{
+			name: "all",
+			buf:  &all,
+			want: "level=0 msg=hello\nlevel=1 msg=details\nlevel=0 msg=goodbye error=<nil>\n",
+		},
+		{
+			name: "info",
+			buf:  &info,
+			want: "level=0 msg=hello\nlevel=0 msg=goodbye error=<nil>\n",
+		},
+		{
+			name: "errors",
+			buf:  &errs,
+			want: "level=0 msg=goodbye error=<nil>\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.buf.String(); got != tc.want {
+				t.Errorf("got %q, wanted %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// failWriter fails every write while fail is set.
+type failWriter struct {
+	bytes.Buffer
+	fail bool
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, errors.New("write failed")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFailover(t *testing.T) {
+	primary := &failWriter{}
+	var fallback bytes.Buffer
+	w := writers.Failover(primary, &fallback)
+	w.SetRetryInterval(20 * time.Millisecond)
+
+	w.Write([]byte("one\n"))
+	primary.fail = true
+	w.Write([]byte("two\n"))
+	primary.fail = false
+	w.Write([]byte("three\n"))
+	time.Sleep(30 * time.Millisecond)
+	w.Write([]byte("four\n"))
+
+	if got, want := primary.String(), "one\nfour\n"; got != want {
+		t.Errorf("got primary %q, wanted %q", got, want)
+	}
+	if got, want := fallback.String(), "two\nthree\n"; got != want {
+		t.Errorf("got fallback %q, wanted %q", got, want)
+	}
+	if got := w.Failures(); got != 1 {
+		t.Errorf("got %d failures, wanted 1", got)
+	}
+}